@@ -0,0 +1,2 @@
+alter table public.url
+    drop column if exists expires_at;
\ No newline at end of file
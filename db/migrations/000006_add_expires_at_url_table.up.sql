@@ -0,0 +1,4 @@
+begin;
+alter table public.url
+    add if not exists expires_at timestamptz default null;
+commit;
\ No newline at end of file
@@ -0,0 +1 @@
+drop table if exists public.click;
\ No newline at end of file
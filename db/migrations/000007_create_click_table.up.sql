@@ -0,0 +1,16 @@
+begin;
+create table if not exists public.click
+(
+    id         bigserial
+        constraint click_pk
+            primary key,
+    short_key  varchar     not null,
+    created_at timestamptz not null default now(),
+    referrer   varchar,
+    user_agent varchar,
+    ip         varchar,
+    ip_prefix  varchar
+);
+create index if not exists click_short_key_created_at_index
+    on public.click (short_key, created_at);
+commit;
\ No newline at end of file
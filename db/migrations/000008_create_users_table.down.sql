@@ -0,0 +1 @@
+drop table if exists public.users;
\ No newline at end of file
@@ -0,0 +1,13 @@
+begin;
+create table if not exists public.users
+(
+    id            varchar     not null
+        constraint users_pk
+            primary key,
+    email         varchar     not null,
+    password_hash varchar     not null,
+    created_at    timestamptz not null default now()
+);
+create unique index if not exists users_email_uindex
+    on public.users (email);
+commit;
@@ -0,0 +1,14 @@
+create table if not exists url
+(
+    short_key         varchar(64)   not null,
+    original_url      varchar(2048) not null,
+    original_url_hash char(64) as (sha2(original_url, 256)) stored not null,
+    correlation_id    varchar(255),
+    user_id           varchar(255),
+    is_deleted        bool          not null default false,
+    expires_at        datetime(6)   default null,
+    constraint url_pk
+        primary key (short_key),
+    constraint url_original_url_hash_uindex
+        unique (original_url_hash)
+);
\ No newline at end of file
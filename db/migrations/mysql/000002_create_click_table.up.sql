@@ -0,0 +1,13 @@
+create table if not exists click
+(
+    id         bigint unsigned not null auto_increment,
+    short_key  varchar(64)     not null,
+    created_at datetime(6)     not null default current_timestamp(6),
+    referrer   varchar(2048),
+    user_agent varchar(1024),
+    ip         varchar(64),
+    ip_prefix  varchar(64),
+    constraint click_pk
+        primary key (id),
+    index click_short_key_created_at_index (short_key, created_at)
+);
\ No newline at end of file
@@ -0,0 +1,11 @@
+create table if not exists users
+(
+    id            varchar(64)  not null,
+    email         varchar(320) not null,
+    password_hash varchar(255) not null,
+    created_at    datetime(6)  not null default current_timestamp(6),
+    constraint users_pk
+        primary key (id),
+    constraint users_email_uindex
+        unique (email)
+);
\ No newline at end of file
@@ -0,0 +1,15 @@
+create table if not exists url
+(
+    short_key      text    not null
+        constraint url_pk
+            primary key,
+    original_url   text    not null,
+    correlation_id text,
+    user_id        text,
+    is_deleted     boolean not null default false,
+    expires_at     datetime default null
+);
+create unique index if not exists url_original_url_uindex
+    on url (original_url);
+create index if not exists url_user_id_index
+    on url (user_id);
\ No newline at end of file
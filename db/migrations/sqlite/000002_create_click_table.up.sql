@@ -0,0 +1,14 @@
+create table if not exists click
+(
+    id         integer  not null
+        constraint click_pk
+            primary key autoincrement,
+    short_key  text     not null,
+    created_at datetime not null default current_timestamp,
+    referrer   text,
+    user_agent text,
+    ip         text,
+    ip_prefix  text
+);
+create index if not exists click_short_key_created_at_index
+    on click (short_key, created_at);
\ No newline at end of file
@@ -0,0 +1 @@
+drop table if exists users;
\ No newline at end of file
@@ -0,0 +1,11 @@
+create table if not exists users
+(
+    id            text     not null
+        constraint users_pk
+            primary key,
+    email         text     not null,
+    password_hash text     not null,
+    created_at    datetime not null default current_timestamp
+);
+create unique index if not exists users_email_uindex
+    on users (email);
\ No newline at end of file
@@ -0,0 +1,9 @@
+// Package sqlite embedded SQLite migrations
+package sqlite
+
+import "embed"
+
+// Migrations SQL files applied by SQLiteURLRepository
+//
+//go:embed *.sql
+var Migrations embed.FS
@@ -0,0 +1,151 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/ShukinDmitriy/shortener/internal/auth"
+	"github.com/ShukinDmitriy/shortener/internal/models"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// errEmailInvalid адрес почты не удалось разобрать
+var errEmailInvalid = errors.New("email is invalid")
+
+// HandleRegister handler for creating an account.
+// Links of the anonymous user from the request's token are claimed into the account:
+// the account takes over the anonymous user id, unless the id already belongs to another account.
+func (us *URLShortener) HandleRegister(ctx echo.Context) error {
+	req, err := decodeAuthRequest(ctx)
+	if err != nil {
+		return err
+	}
+
+	email, err := normalizeEmail(req.Email)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	passwordHash, err := auth.HashPassword(req.Password)
+	if errors.Is(err, auth.ErrPasswordInvalid) {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal Server Error")
+	}
+
+	userID, err := us.claimableUserID(ctx)
+	if err != nil {
+		ctx.Logger().Error(err)
+		return repositoryError(err)
+	}
+
+	user := &models.User{
+		ID:           userID,
+		Email:        email,
+		PasswordHash: passwordHash,
+		CreatedAt:    time.Now().UTC(),
+	}
+	err = us.UserRepository.Create(ctx.Request().Context(), user)
+	if errors.Is(err, models.ErrUserExist) {
+		return echo.NewHTTPError(http.StatusConflict, "user already exists")
+	}
+	if err != nil {
+		ctx.Logger().Error(err)
+		return repositoryError(err)
+	}
+
+	if err = auth.GenerateTokensAndSetCookies(ctx, &auth.User{ID: user.ID, Registered: true}); err != nil {
+		ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal Server Error")
+	}
+
+	return ctx.JSON(http.StatusCreated, user)
+}
+
+// HandleLogin handler for signing in, the tokens of the account replace the current ones.
+// Links created anonymously before the login stay with the anonymous user.
+func (us *URLShortener) HandleLogin(ctx echo.Context) error {
+	req, err := decodeAuthRequest(ctx)
+	if err != nil {
+		return err
+	}
+
+	user, err := us.UserRepository.GetByEmail(ctx.Request().Context(), strings.ToLower(strings.TrimSpace(req.Email)))
+	if err != nil && !errors.Is(err, models.ErrNotFound) {
+		ctx.Logger().Error(err)
+		return repositoryError(err)
+	}
+	// Не сообщаем, что именно не совпало: почта или пароль, в том числе временем ответа
+	if err != nil {
+		auth.CheckDummyPassword(req.Password)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid email or password")
+	}
+	if !auth.CheckPassword(user.PasswordHash, req.Password) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid email or password")
+	}
+
+	if err = auth.GenerateTokensAndSetCookies(ctx, &auth.User{ID: user.ID, Registered: true}); err != nil {
+		ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal Server Error")
+	}
+
+	return ctx.JSON(http.StatusOK, user)
+}
+
+// HandleLogout handler for signing out, the token cookies are removed
+func (us *URLShortener) HandleLogout(ctx echo.Context) error {
+	auth.ClearTokenCookies(ctx)
+
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// claimableUserID id нового аккаунта: анонимный пользователь из токена или новый id,
+// если токена нет или пользователь из него уже зарегистрирован
+func (us *URLShortener) claimableUserID(ctx echo.Context) (string, error) {
+	userID := us.authService.GetUserID(ctx)
+	if userID == "" {
+		return uuid.New().String(), nil
+	}
+
+	_, err := us.UserRepository.GetByID(ctx.Request().Context(), userID)
+	if errors.Is(err, models.ErrNotFound) {
+		return userID, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return uuid.New().String(), nil
+}
+
+func decodeAuthRequest(ctx echo.Context) (models.AuthRequest, error) {
+	var req models.AuthRequest
+	defer ctx.Request().Body.Close()
+
+	if err := json.NewDecoder(ctx.Request().Body).Decode(&req); err != nil {
+		zap.L().Debug("cannot decode request JSON body", zap.Error(err))
+		return req, echo.NewHTTPError(http.StatusBadRequest, "invalid JSON")
+	}
+
+	return req, nil
+}
+
+// normalizeEmail принимает только адрес без имени, домен и имя приводятся к нижнему регистру
+func normalizeEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email {
+		return "", errEmailInvalid
+	}
+
+	return strings.ToLower(address.Address), nil
+}
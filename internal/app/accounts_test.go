@@ -0,0 +1,196 @@
+package app_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ShukinDmitriy/shortener/internal/app"
+	auth2 "github.com/ShukinDmitriy/shortener/internal/auth"
+	"github.com/ShukinDmitriy/shortener/internal/models"
+	"github.com/ShukinDmitriy/shortener/mocks/internal_/auth"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestURLShortener_HandleRegister(t *testing.T) {
+	type want struct {
+		code int
+		// id аккаунта совпадает с анонимным пользователем
+		claimed bool
+		message string
+	}
+	tests := []struct {
+		name   string
+		body   string
+		userID string
+		want   want
+	}{
+		{
+			name:   "positive test #1",
+			body:   `{"email":"Alice@Example.com","password":"secret-password"}`,
+			userID: "anonymous-1",
+			want:   want{code: http.StatusCreated, claimed: true},
+		},
+		{
+			// Анонимный пользователь уже стал аккаунтом, второй аккаунт получает новый id
+			name:   "positive test #2",
+			body:   `{"email":"bob@example.com","password":"secret-password"}`,
+			userID: "anonymous-1",
+			want:   want{code: http.StatusCreated},
+		},
+		{
+			name: "positive test #3",
+			body: `{"email":"carol@example.com","password":"secret-password"}`,
+			want: want{code: http.StatusCreated},
+		},
+		{
+			name: "negative test #1",
+			body: `{"email":"alice@example.com","password":"other-password"}`,
+			want: want{code: http.StatusConflict, message: "user already exists"},
+		},
+		{
+			name: "negative test #2",
+			body: `{"email":"Alice <dave@example.com>","password":"secret-password"}`,
+			want: want{code: http.StatusBadRequest, message: "email is invalid"},
+		},
+		{
+			name: "negative test #3",
+			body: `{"email":"dave@example.com","password":"short"}`,
+			want: want{code: http.StatusBadRequest, message: "password must be"},
+		},
+		{
+			name: "negative test #4",
+			body: `{"email":`,
+			want: want{code: http.StatusBadRequest, message: "invalid JSON"},
+		},
+	}
+
+	authService := new(auth.AuthServiceInterface)
+	shortener := app.NewURLShortener(&models.MemoryURLRepository{}, nil, authService, nil, nil)
+	e := echo.New()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/auth/register", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			authService.ExpectedCalls = nil
+			authService.EXPECT().GetUserID(mock.Anything).Return(tt.userID)
+
+			err := shortener.HandleRegister(c)
+			if tt.want.message != "" {
+				var httpErr *echo.HTTPError
+				require.ErrorAs(t, err, &httpErr)
+				assert.Equal(t, tt.want.code, httpErr.Code)
+				assert.Contains(t, httpErr.Message, tt.want.message)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want.code, rec.Code)
+
+			var user models.User
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &user))
+			assert.NotEmpty(t, user.ID)
+			assert.Equal(t, tt.want.claimed, user.ID == tt.userID)
+			assert.Equal(t, strings.ToLower(user.Email), user.Email)
+			assert.False(t, user.CreatedAt.IsZero())
+			assert.NotContains(t, rec.Body.String(), "password")
+
+			// Аккаунт сразу авторизован
+			assert.Contains(t, rec.Header().Values("Set-Cookie")[0], "access-token=")
+		})
+	}
+}
+
+func TestURLShortener_HandleLogin(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		code int
+	}{
+		{
+			name: "positive test #1",
+			body: `{"email":"alice@example.com","password":"secret-password"}`,
+			code: http.StatusOK,
+		},
+		{
+			name: "positive test #2",
+			body: `{"email":" ALICE@example.com ","password":"secret-password"}`,
+			code: http.StatusOK,
+		},
+		{
+			name: "negative test #1",
+			body: `{"email":"alice@example.com","password":"wrong-password"}`,
+			code: http.StatusUnauthorized,
+		},
+		{
+			name: "negative test #2",
+			body: `{"email":"bob@example.com","password":"secret-password"}`,
+			code: http.StatusUnauthorized,
+		},
+		{
+			name: "negative test #3",
+			body: `not json`,
+			code: http.StatusBadRequest,
+		},
+	}
+
+	authService := new(auth.AuthServiceInterface)
+	authService.EXPECT().GetUserID(mock.Anything).Return("anonymous-1")
+	shortener := app.NewURLShortener(&models.MemoryURLRepository{}, nil, authService, nil, nil)
+	e := echo.New()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/register",
+		strings.NewReader(`{"email":"alice@example.com","password":"secret-password"}`))
+	require.NoError(t, shortener.HandleRegister(e.NewContext(req, httptest.NewRecorder())))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/auth/login", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			err := shortener.HandleLogin(c)
+			if tt.code != http.StatusOK {
+				var httpErr *echo.HTTPError
+				require.ErrorAs(t, err, &httpErr)
+				assert.Equal(t, tt.code, httpErr.Code)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.code, rec.Code)
+
+			var user models.User
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &user))
+			assert.Equal(t, "anonymous-1", user.ID)
+			assert.Contains(t, rec.Header().Values("Set-Cookie")[0], "access-token=")
+			// Токен аккаунта отличается от анонимного, по нему ограничиваем запросы пользователя
+			assert.Equal(t, "anonymous-1", auth2.RegisteredUserID(c))
+		})
+	}
+}
+
+func TestURLShortener_HandleLogout(t *testing.T) {
+	shortener := app.NewURLShortener(&models.MemoryURLRepository{}, nil, new(auth.AuthServiceInterface), nil, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/logout", nil)
+	rec := httptest.NewRecorder()
+
+	require.NoError(t, shortener.HandleLogout(echo.New().NewContext(req, rec)))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	cookies := rec.Result().Cookies()
+	defer rec.Result().Body.Close()
+	require.Len(t, cookies, 3)
+	for _, cookie := range cookies {
+		assert.Empty(t, cookie.Value)
+		assert.Negative(t, cookie.MaxAge)
+	}
+}
@@ -0,0 +1,315 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ShukinDmitriy/shortener/internal/metrics"
+	"github.com/ShukinDmitriy/shortener/internal/models"
+	"go.uber.org/zap"
+)
+
+const (
+	// deleteFlushInterval как часто накопленные запросы отправляются в хранилище
+	deleteFlushInterval = 2 * time.Second
+	// deleteTimeout сколько ждем хранилище при удалении
+	deleteTimeout = 10 * time.Second
+	// deleteMinBackoff и deleteMaxBackoff пауза перед повтором после ошибки удаления
+	deleteMinBackoff = 2 * time.Second
+	deleteMaxBackoff = time.Minute
+	// deleteMaxAttempts после стольких неудач запрос откладывается до перезапуска,
+	// чтобы один сбойный запрос не задерживал остальные
+	deleteMaxAttempts = 10
+)
+
+// ErrDeleteQueueClosed the queue was shut down and doesn't accept requests
+var ErrDeleteQueueClosed = errors.New("delete queue closed")
+
+// DeleteQueue deletes user's links in the background.
+// With a journal every request is written to it before it is accepted,
+// pending requests are replayed on start and failed deletions are retried with backoff,
+// so an accepted request isn't lost on restart. Requests are applied one by one:
+// a request failing deleteMaxAttempts times is parked in the journal until the next start
+// and doesn't hold back the others.
+type DeleteQueue struct {
+	repository models.URLRepository
+	journal    models.DeleteJournal
+
+	// канал принятых запросов
+	eDeletedEvent chan models.DeleteJournalEntry
+
+	// канал для уведомления об окончании работы
+	shutdownChan chan chan struct{}
+
+	// закрывается, когда очередь остановлена
+	done chan struct{}
+
+	// записанные в журнал запросы, которые не успели передать через канал до отмены контекста,
+	// обработчик забирает их по таймеру
+	handoffMutex sync.Mutex
+	handoff      []models.DeleteJournalEntry
+
+	// запросов, еще не выполненных хранилищем
+	depth atomic.Int64
+}
+
+// pendingDelete принятый запрос и количество неудачных попыток его выполнить
+type pendingDelete struct {
+	entry    models.DeleteJournalEntry
+	attempts int
+}
+
+// NewDeleteQueue queue's constructor, without a journal requests are kept only in memory
+func NewDeleteQueue(repository models.URLRepository, journal models.DeleteJournal) *DeleteQueue {
+	queue := &DeleteQueue{
+		repository:    repository,
+		journal:       journal,
+		eDeletedEvent: make(chan models.DeleteJournalEntry, 100),
+		shutdownChan:  make(chan chan struct{}),
+		done:          make(chan struct{}),
+	}
+
+	// Запросы, не выполненные до остановки, выполняем заново
+	var pending []pendingDelete
+	if journal != nil {
+		for _, entry := range journal.Pending() {
+			pending = append(pending, pendingDelete{entry: entry})
+		}
+	}
+	if len(pending) > 0 {
+		zap.L().Info("replay delete queue", zap.Int("count", len(pending)))
+	}
+	queue.depth.Store(int64(len(pending)))
+
+	go queue.deleteEvents(pending)
+
+	return queue
+}
+
+// Enqueue accept the request, it is written to the journal before the call returns
+func (q *DeleteQueue) Enqueue(ctx context.Context, req models.DeleteRequestBatch) error {
+	select {
+	case <-q.done:
+		return ErrDeleteQueueClosed
+	default:
+	}
+
+	entry := models.DeleteJournalEntry{Request: req}
+	if q.journal != nil {
+		id, err := q.journal.Append(req)
+		if err != nil {
+			return err
+		}
+		entry.ID = id
+	}
+
+	select {
+	case q.eDeletedEvent <- entry:
+		q.depth.Add(1)
+		return nil
+	case <-q.done:
+	case <-ctx.Done():
+		// Запрос уже принят, отмена контекста не должна откладывать его до перезапуска
+		if entry.ID != 0 && q.handOff(entry) {
+			return nil
+		}
+	}
+
+	// Записанный в журнал запрос выполнится после перезапуска
+	if entry.ID != 0 {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	return ErrDeleteQueueClosed
+}
+
+// handOff передает запрос обработчику в обход канала, false если очередь уже остановлена
+func (q *DeleteQueue) handOff(entry models.DeleteJournalEntry) bool {
+	q.handoffMutex.Lock()
+	defer q.handoffMutex.Unlock()
+
+	select {
+	case <-q.done:
+		return false
+	default:
+	}
+
+	q.handoff = append(q.handoff, entry)
+	q.depth.Add(1)
+
+	return true
+}
+
+// takeHandoff забирает переданные в обход канала запросы
+func (q *DeleteQueue) takeHandoff() []pendingDelete {
+	q.handoffMutex.Lock()
+	defer q.handoffMutex.Unlock()
+
+	entries := make([]pendingDelete, 0, len(q.handoff))
+	for _, entry := range q.handoff {
+		entries = append(entries, pendingDelete{entry: entry})
+	}
+	q.handoff = nil
+
+	return entries
+}
+
+// Depth count of accepted requests not yet applied by the repository
+func (q *DeleteQueue) Depth() int {
+	return int(q.depth.Load())
+}
+
+// Shutdown try to apply pending requests and stop the queue,
+// what is left stays in the journal until the next start
+func (q *DeleteQueue) Shutdown() chan struct{} {
+	res := make(chan struct{})
+
+	go func() {
+		defer close(res)
+
+		successShutdown := make(chan struct{})
+		q.shutdownChan <- successShutdown
+
+		<-successShutdown
+		close(successShutdown)
+		res <- struct{}{}
+	}()
+
+	return res
+}
+
+func (q *DeleteQueue) deleteEvents(entries []pendingDelete) {
+	ticker := time.NewTicker(deleteFlushInterval)
+	defer ticker.Stop()
+
+	var backoff time.Duration
+	var retryAt time.Time
+
+	for {
+		select {
+		case entry := <-q.eDeletedEvent:
+			entries = append(entries, pendingDelete{entry: entry})
+		case success := <-q.shutdownChan:
+			// После закрытия запросы в обход канала не передаются
+			q.handoffMutex.Lock()
+			close(q.done)
+			q.handoffMutex.Unlock()
+
+			// Забираем то, что осталось в очереди
+			for len(q.eDeletedEvent) > 0 {
+				entries = append(entries, pendingDelete{entry: <-q.eDeletedEvent})
+			}
+			entries = append(entries, q.takeHandoff()...)
+
+			if entries = q.applyEach(entries); len(entries) > 0 {
+				zap.L().Error("cannot delete events", zap.Int("pending", len(entries)))
+			}
+
+			if q.journal != nil {
+				if err := q.journal.Close(); err != nil {
+					zap.L().Error("cannot close delete journal", zap.Error(err))
+				}
+			}
+
+			success <- struct{}{}
+
+			return
+		case now := <-ticker.C:
+			entries = append(entries, q.takeHandoff()...)
+
+			if len(entries) == 0 || now.Before(retryAt) {
+				continue
+			}
+
+			// Все запросы выполнены или отложены
+			if entries = q.applyEach(entries); len(entries) == 0 {
+				backoff = 0
+				continue
+			}
+
+			backoff = min(max(2*backoff, deleteMinBackoff), deleteMaxBackoff)
+			retryAt = now.Add(backoff)
+
+			zap.L().Warn("delete retry scheduled",
+				zap.Int("pending", len(entries)),
+				zap.Duration("retry_in", backoff),
+			)
+		}
+	}
+}
+
+// applyEach выполняет запросы по одному и возвращает невыполненные, они переносятся в конец.
+// Если не удался первый же запрос, хранилище, скорее всего, недоступно,
+// и остальные запросы до следующей попытки не трогаем.
+func (q *DeleteQueue) applyEach(entries []pendingDelete) []pendingDelete {
+	var failed []pendingDelete
+	applied := 0
+
+	for i, pending := range entries {
+		err := q.apply(pending.entry)
+		if err == nil {
+			applied++
+			continue
+		}
+
+		pending.attempts++
+		zap.L().Error("cannot delete events",
+			zap.Uint64("id", pending.entry.ID),
+			zap.Int("attempts", pending.attempts),
+			zap.Error(err),
+		)
+
+		if pending.attempts < deleteMaxAttempts {
+			failed = append(failed, pending)
+		} else {
+			q.park(pending, err)
+		}
+
+		if applied == 0 {
+			return append(slices.Clone(entries[i+1:]), failed...)
+		}
+	}
+
+	return failed
+}
+
+// apply удаляет ссылки запроса и подтверждает его в журнале
+func (q *DeleteQueue) apply(entry models.DeleteJournalEntry) error {
+	ctx, cancel := context.WithTimeout(context.Background(), deleteTimeout)
+	defer cancel()
+
+	if err := q.repository.Delete(ctx, []models.DeleteRequestBatch{entry.Request}); err != nil {
+		return err
+	}
+	q.depth.Add(-1)
+
+	// Без подтверждения запрос повторится после перезапуска, удаление можно повторять
+	if q.journal != nil {
+		if err := q.journal.Ack([]uint64{entry.ID}); err != nil {
+			zap.L().Error("cannot acknowledge deleted events", zap.Error(err))
+		}
+	}
+
+	return nil
+}
+
+// park больше не повторяет запрос, в журнале он остается до перезапуска
+func (q *DeleteQueue) park(pending pendingDelete, err error) {
+	q.depth.Add(-1)
+	metrics.DeleteRequestsParkedTotal.Inc()
+
+	zap.L().Error("delete request parked",
+		zap.Uint64("id", pending.entry.ID),
+		zap.String("user_id", pending.entry.Request.UserID),
+		zap.Int("attempts", pending.attempts),
+		zap.Bool("journaled", pending.entry.ID != 0),
+		zap.Error(err),
+	)
+}
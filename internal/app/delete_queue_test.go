@@ -0,0 +1,163 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ShukinDmitriy/shortener/internal/app"
+	"github.com/ShukinDmitriy/shortener/internal/models"
+	models2 "github.com/ShukinDmitriy/shortener/mocks/internal_/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// shutdownDeleteQueue останавливает очередь и ждет окончания
+func shutdownDeleteQueue(t *testing.T, queue *app.DeleteQueue) {
+	select {
+	case <-queue.Shutdown():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Can't wait for the end shutdown")
+	}
+}
+
+func TestDeleteQueue(t *testing.T) {
+	testError := errors.New("test error")
+	pending := models.DeleteRequestBatch{UserID: "1", ShortKeys: []string{"SYqDJ3"}}
+	accepted := models.DeleteRequestBatch{UserID: "2", ShortKeys: []string{"4SwGPJ", "z3e7av"}}
+	later := models.DeleteRequestBatch{UserID: "3", ShortKeys: []string{"k8Lm2q"}}
+
+	tests := []struct {
+		name string
+		// ошибки хранилища по запросам, запросы без ошибки выполняются
+		deleteErrs map[string]error
+		// запросы, которые хранилище не должно получить
+		notCalled []models.DeleteRequestBatch
+		// запросы, оставшиеся в журнале после остановки
+		want []models.DeleteRequestBatch
+	}{
+		{
+			name: "positive test #1",
+		},
+		{
+			// Сбойный запрос не задерживает следующие
+			name:       "positive test #2",
+			deleteErrs: map[string]error{accepted.UserID: testError},
+			want:       []models.DeleteRequestBatch{accepted},
+		},
+		{
+			// Первый же запрос не выполнен: хранилище недоступно, остальные не отправляются
+			name:       "negative test #1",
+			deleteErrs: map[string]error{pending.UserID: testError},
+			notCalled:  []models.DeleteRequestBatch{accepted, later},
+			want:       []models.DeleteRequestBatch{pending, accepted, later},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "delete-queue.json")
+
+			// Запрос, не выполненный до перезапуска
+			journal, err := models.NewFileDeleteJournal(filename)
+			require.NoError(t, err)
+			_, err = journal.Append(pending)
+			require.NoError(t, err)
+			require.NoError(t, journal.Close())
+
+			journal, err = models.NewFileDeleteJournal(filename)
+			require.NoError(t, err)
+
+			repository := new(models2.URLRepository)
+			for _, req := range []models.DeleteRequestBatch{pending, accepted, later} {
+				// Неожиданный вызов мока проваливает тест
+				if slices.ContainsFunc(test.notCalled, func(notCalled models.DeleteRequestBatch) bool {
+					return notCalled.UserID == req.UserID
+				}) {
+					continue
+				}
+
+				repository.EXPECT().Delete(
+					mock.Anything,
+					[]models.DeleteRequestBatch{req},
+				).Return(test.deleteErrs[req.UserID]).Once()
+			}
+
+			queue := app.NewDeleteQueue(repository, journal)
+			assert.Equal(t, 1, queue.Depth())
+
+			require.NoError(t, queue.Enqueue(context.Background(), accepted))
+			require.NoError(t, queue.Enqueue(context.Background(), later))
+
+			// При остановке очередь выполняет накопленные запросы
+			shutdownDeleteQueue(t, queue)
+			repository.AssertExpectations(t)
+
+			assert.ErrorIs(t, queue.Enqueue(context.Background(), accepted), app.ErrDeleteQueueClosed)
+
+			journal, err = models.NewFileDeleteJournal(filename)
+			require.NoError(t, err)
+			defer journal.Close()
+
+			var requests []models.DeleteRequestBatch
+			for _, entry := range journal.Pending() {
+				requests = append(requests, entry.Request)
+			}
+			assert.Equal(t, test.want, requests)
+		})
+	}
+}
+
+func TestDeleteQueueCancelledEnqueue(t *testing.T) {
+	journal, err := models.NewFileDeleteJournal(filepath.Join(t.TempDir(), "delete-queue.json"))
+	require.NoError(t, err)
+
+	// Обработчик занят первым запросом, пока его не отпустят
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var startOnce sync.Once
+	var deleted atomic.Int32
+	repository := new(models2.URLRepository)
+	repository.EXPECT().Delete(mock.Anything, mock.Anything).RunAndReturn(
+		func(_ context.Context, _ []models.DeleteRequestBatch) error {
+			startOnce.Do(func() { close(started) })
+			<-release
+			deleted.Add(1)
+			return nil
+		},
+	)
+
+	queue := app.NewDeleteQueue(repository, journal)
+	require.NoError(t, queue.Enqueue(context.Background(), models.DeleteRequestBatch{UserID: "1", ShortKeys: []string{"SYqDJ3"}}))
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Can't wait for the first delete")
+	}
+
+	// Заполняем канал, следующий запрос через него не пройдет
+	for i := 0; i < 100; i++ {
+		require.NoError(t, queue.Enqueue(context.Background(), models.DeleteRequestBatch{UserID: "2", ShortKeys: []string{"4SwGPJ"}}))
+	}
+
+	// Запрос записан в журнал до отмены контекста и выполняется без перезапуска
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.NoError(t, queue.Enqueue(ctx, models.DeleteRequestBatch{UserID: "3", ShortKeys: []string{"k8Lm2q"}}))
+	assert.Equal(t, 102, queue.Depth())
+
+	close(release)
+	assert.Eventually(t, func() bool {
+		return deleted.Load() == 102
+	}, 10*time.Second, 50*time.Millisecond)
+	assert.Equal(t, 0, queue.Depth())
+	assert.Empty(t, journal.Pending())
+
+	shutdownDeleteQueue(t, queue)
+}
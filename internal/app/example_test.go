@@ -1,6 +1,7 @@
 package app_test
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -37,12 +38,12 @@ func Example() {
 
 	repository := &models.MemoryURLRepository{}
 	configuration := environments.Configuration{}
-	err := repository.Initialize(configuration)
+	err := repository.Initialize(context.TODO(), configuration)
 	if err != nil {
 		panic(err)
 	}
 	authService := auth.NewAuthService()
-	shortener := app.NewURLShortener(repository, nil, authService, nil)
+	shortener := app.NewURLShortener(repository, nil, authService, nil, nil)
 
 	e := echo.New()
 	stringBody, _ := json.Marshal(body)
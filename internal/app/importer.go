@@ -0,0 +1,282 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+
+	"github.com/ShukinDmitriy/shortener/internal/destination"
+	"github.com/ShukinDmitriy/shortener/internal/models"
+)
+
+const (
+	// importChunkSize сколько строк импорта сохраняем за одно обращение к хранилищу
+	importChunkSize = 100
+	// maxImportRows сколько строк можно импортировать за один запрос, остальные отклоняются
+	maxImportRows = 10000
+	// maxImportLineSize предел длины строки NDJSON, более длинная строка отклоняется
+	maxImportLineSize = 64 << 10
+)
+
+var (
+	// errUnsupportedImportFormat формат тела запроса импорта не поддерживается
+	errUnsupportedImportFormat = errors.New("unsupported import format, use NDJSON or CSV")
+	// errImportTooLarge строка после maxImportRows
+	errImportTooLarge = fmt.Errorf("import is limited to %d rows", maxImportRows)
+	// errImportLineTooLong строка NDJSON длиннее maxImportLineSize
+	errImportLineTooLong = fmt.Errorf("line is longer than %d bytes", maxImportLineSize)
+)
+
+// importRow строка импорта, ожидающая сохранения
+type importRow struct {
+	line  int
+	event *models.Event
+}
+
+// urlImporter сохраняет строки импорта порциями и сообщает результат по каждой строке.
+// Ошибка в строке не прерывает импорт, его прерывает только недоступность хранилища.
+type urlImporter struct {
+	repository   models.URLRepository
+	keyGenerator models.KeyGenerator
+	normalizer   *models.URLNormalizer
+	policy       *destination.Policy
+	transport    string
+	userID       string
+	host         string
+	report       func(result models.ImportResponse) error
+
+	rows []importRow
+	// сколько строк принято на проверку
+	added int
+}
+
+// Add проверяет строку и ставит ее в очередь на сохранение
+func (im *urlImporter) Add(ctx context.Context, line int, req models.CreateRequestBatch) error {
+	if im.added >= maxImportRows {
+		return im.Fail(line, req.CorrelationID, errImportTooLarge.Error())
+	}
+	im.added++
+
+	event, err := prepareBatchEvent(ctx, im.normalizer, im.policy, im.userID, req)
+	if err != nil {
+		return im.Fail(line, req.CorrelationID, err.Error())
+	}
+
+	im.rows = append(im.rows, importRow{line: line, event: event})
+
+	if len(im.rows) < importChunkSize {
+		return nil
+	}
+
+	return im.Flush(ctx)
+}
+
+// Fail сообщает об отклоненной строке
+func (im *urlImporter) Fail(line int, correlationID string, reason string) error {
+	return im.report(models.ImportResponse{
+		Line:          line,
+		CorrelationID: correlationID,
+		Status:        models.LinkStatusInvalid,
+		Error:         reason,
+	})
+}
+
+// Flush сохраняет накопленные строки
+func (im *urlImporter) Flush(ctx context.Context) error {
+	rows := im.rows
+	im.rows = nil
+
+	if len(rows) == 0 {
+		return nil
+	}
+
+	events := make([]*models.Event, len(rows))
+	for i, row := range rows {
+		events[i] = row.event
+	}
+
+	// Конфликт в строке не прерывает сохранение порции, результат известен для каждой строки
+	err := models.SaveBatchWithShortKeys(ctx, im.keyGenerator, im.repository, events)
+	if batchSaveFailed(err) {
+		return err
+	}
+	recordCreated(ctx, im.transport, events, err)
+
+	for i, row := range rows {
+		status, reason := linkStatus(models.SaveErrorAt(err, i))
+
+		result := models.ImportResponse{
+			Line:          row.line,
+			CorrelationID: row.event.CorrelationID,
+			Status:        status,
+			Error:         reason,
+		}
+		if status != models.LinkStatusInvalid {
+			result.ShortURL = models.PrepareFullURL(row.event.ShortKey, im.host)
+		}
+
+		if err := im.report(result); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// importRowError строку не удалось разобрать, импорт продолжается со следующей
+type importRowError struct {
+	line int
+	err  error
+}
+
+func (e *importRowError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.line, e.err)
+}
+
+func (e *importRowError) Unwrap() error {
+	return e.err
+}
+
+// importReader читает строки импорта по одной, в конце возвращает io.EOF
+type importReader interface {
+	Read() (line int, req models.CreateRequestBatch, err error)
+}
+
+// newImportReader выбирает формат по Content-Type, без него ожидается NDJSON
+func newImportReader(contentType string, body io.Reader) (importReader, error) {
+	if contentType == "" {
+		return &ndjsonImportReader{reader: bufio.NewReaderSize(body, maxImportLineSize)}, nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, errUnsupportedImportFormat
+	}
+
+	switch mediaType {
+	case "application/x-ndjson", "application/jsonl", "application/json":
+		return &ndjsonImportReader{reader: bufio.NewReaderSize(body, maxImportLineSize)}, nil
+	case "text/csv":
+		return newCSVImportReader(body)
+	default:
+		return nil, errUnsupportedImportFormat
+	}
+}
+
+// ndjsonImportReader по одному JSON-объекту на строку, пустые строки пропускаются.
+// Строка читается в буфер размером maxImportLineSize, более длинная отклоняется.
+type ndjsonImportReader struct {
+	reader *bufio.Reader
+	line   int
+}
+
+// Read читает следующую непустую строку
+func (r *ndjsonImportReader) Read() (int, models.CreateRequestBatch, error) {
+	for {
+		data, isPrefix, err := r.reader.ReadLine()
+		if err != nil {
+			return 0, models.CreateRequestBatch{}, err
+		}
+		r.line++
+
+		// Строка не поместилась в буфер, остаток пропускаем, не накапливая его в памяти
+		if isPrefix {
+			if err = r.skipLine(); err != nil && !errors.Is(err, io.EOF) {
+				return r.line, models.CreateRequestBatch{}, err
+			}
+
+			return r.line, models.CreateRequestBatch{}, &importRowError{line: r.line, err: errImportLineTooLong}
+		}
+
+		data = bytes.TrimSpace(data)
+		if len(data) == 0 {
+			continue
+		}
+
+		var req models.CreateRequestBatch
+		if err := json.Unmarshal(data, &req); err != nil {
+			return r.line, req, &importRowError{line: r.line, err: errors.New("invalid JSON")}
+		}
+
+		return r.line, req, nil
+	}
+}
+
+// skipLine дочитывает текущую строку до конца
+func (r *ndjsonImportReader) skipLine() error {
+	for {
+		_, isPrefix, err := r.reader.ReadLine()
+		if err != nil || !isPrefix {
+			return err
+		}
+	}
+}
+
+// csvImportReader CSV с заголовком, колонки сопоставляются по имени
+type csvImportReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+}
+
+// newCSVImportReader читает заголовок и проверяет наличие обязательных колонок
+func newCSVImportReader(body io.Reader) (*csvImportReader, error) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("can't read CSV header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	for _, name := range []string{"correlation_id", "original_url"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("CSV header has no %s column", name)
+		}
+	}
+
+	return &csvImportReader{reader: reader, columns: columns}, nil
+}
+
+// Read читает следующую запись
+func (r *csvImportReader) Read() (int, models.CreateRequestBatch, error) {
+	record, err := r.reader.Read()
+
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return parseErr.Line, models.CreateRequestBatch{}, &importRowError{line: parseErr.Line, err: parseErr.Err}
+	}
+	if err != nil {
+		return 0, models.CreateRequestBatch{}, err
+	}
+
+	line, _ := r.reader.FieldPos(0)
+
+	return line, models.CreateRequestBatch{
+		CorrelationID: r.field(record, "correlation_id"),
+		OriginalURL:   r.field(record, "original_url"),
+		Alias:         r.field(record, "alias"),
+	}, nil
+}
+
+// field значение колонки, пустое, если колонки нет в записи
+func (r *csvImportReader) field(record []string, name string) string {
+	i, ok := r.columns[name]
+	if !ok || i >= len(record) {
+		return ""
+	}
+
+	return strings.TrimSpace(record[i])
+}
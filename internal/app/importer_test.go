@@ -0,0 +1,343 @@
+package app_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ShukinDmitriy/shortener/internal/app"
+	"github.com/ShukinDmitriy/shortener/internal/environments"
+	"github.com/ShukinDmitriy/shortener/internal/models"
+	"github.com/ShukinDmitriy/shortener/mocks/internal_/auth"
+	models2 "github.com/ShukinDmitriy/shortener/mocks/internal_/models"
+	pb "github.com/ShukinDmitriy/shortener/proto"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// decodeImportResults читает NDJSON с результатами импорта
+func decodeImportResults(t *testing.T, body string) []models.ImportResponse {
+	var results []models.ImportResponse
+
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		var result models.ImportResponse
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &result))
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// importStatuses статусы строк импорта по номеру строки
+func importStatuses(results []models.ImportResponse) map[int]string {
+	statuses := make(map[int]string, len(results))
+	for _, result := range results {
+		statuses[result.Line] = result.Status
+	}
+
+	return statuses
+}
+
+func TestURLShortener_HandleImport(t *testing.T) {
+	type want struct {
+		code     int
+		statuses map[int]string
+	}
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        want
+	}{
+		{
+			name:        "positive test #1",
+			contentType: "application/x-ndjson",
+			body: `{"correlation_id":"1","original_url":"https://import.example.com/1"}
+{"correlation_id":"2","original_url":""}
+
+{"correlation_id":"3","original_url":"https://import.example.com/3","alias":"taken"}
+not a json
+{"correlation_id":"5","original_url":"https://import.example.com/1"}
+{"correlation_id":"6","original_url":"https://import.example.com/6","alias":"imported"}`,
+			want: want{
+				code: http.StatusOK,
+				statuses: map[int]string{
+					1: models.LinkStatusCreated,
+					2: models.LinkStatusInvalid,
+					4: models.LinkStatusInvalid,
+					5: models.LinkStatusInvalid,
+					6: models.LinkStatusExisting,
+					7: models.LinkStatusCreated,
+				},
+			},
+		},
+		{
+			name:        "positive test #2",
+			contentType: "text/csv; charset=utf-8",
+			body: `original_url,correlation_id,alias
+https://import.example.com/csv1,c1,
+https://import.example.com/csv2,c2,taken
+"https://import.example.com/csv3,c3
+`,
+			want: want{
+				code: http.StatusOK,
+				statuses: map[int]string{
+					2: models.LinkStatusCreated,
+					3: models.LinkStatusInvalid,
+					4: models.LinkStatusInvalid,
+				},
+			},
+		},
+		{
+			name:        "positive test #3",
+			contentType: "application/x-ndjson",
+			body:        "",
+			want: want{
+				code:     http.StatusOK,
+				statuses: map[int]string{},
+			},
+		},
+		{
+			name:        "positive test #4",
+			contentType: "application/x-ndjson",
+			body: `{"correlation_id":"1","original_url":"https://import.example.com/` + strings.Repeat("a", 70000) + `"}
+{"correlation_id":"2","original_url":"https://import.example.com/after-long"}
+{"correlation_id":"3","original_url":"https://import.example.com/` + strings.Repeat("b", 70000) + `"}`,
+			want: want{
+				code: http.StatusOK,
+				statuses: map[int]string{
+					1: models.LinkStatusInvalid,
+					2: models.LinkStatusCreated,
+					3: models.LinkStatusInvalid,
+				},
+			},
+		},
+		{
+			name:        "negative test #1",
+			contentType: "application/xml",
+			body:        "<urls/>",
+			want: want{
+				code: http.StatusUnsupportedMediaType,
+			},
+		},
+		{
+			name:        "negative test #2",
+			contentType: "text/csv",
+			body:        "url,id\nhttps://example.com,1\n",
+			want: want{
+				code: http.StatusBadRequest,
+			},
+		},
+	}
+
+	environments.BaseAddr = "http://example.com"
+	repository := &models.MemoryURLRepository{}
+	require.NoError(t, repository.Initialize(context.TODO(), environments.Configuration{}))
+	require.NoError(t, repository.Save(context.TODO(), []*models.Event{{
+		ShortKey:    "taken",
+		OriginalURL: "https://taken.example.com",
+	}}))
+	authService := new(auth.AuthServiceInterface)
+	authService.EXPECT().GetUserID(mock.Anything).Return("testUserID")
+
+	shortener := app.NewURLShortener(repository, nil, authService, nil, nil)
+	e := echo.New()
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/shorten/import", strings.NewReader(test.body))
+			req.Header.Set(echo.HeaderContentType, test.contentType)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			err := shortener.HandleImport(c)
+			if test.want.code != http.StatusOK {
+				res, ok := err.(*echo.HTTPError)
+				require.True(t, ok)
+				assert.Equal(t, test.want.code, res.Code)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusOK, rec.Code)
+
+			results := decodeImportResults(t, rec.Body.String())
+			assert.Equal(t, test.want.statuses, importStatuses(results))
+			for _, result := range results {
+				assert.Equal(t, result.Status == models.LinkStatusInvalid, result.ShortURL == "")
+			}
+		})
+	}
+}
+
+func TestURLShortener_HandleImportChunks(t *testing.T) {
+	environments.BaseAddr = "http://example.com"
+	repository := &models.MemoryURLRepository{}
+	require.NoError(t, repository.Initialize(context.TODO(), environments.Configuration{}))
+	authService := new(auth.AuthServiceInterface)
+	authService.EXPECT().GetUserID(mock.Anything).Return("testUserID")
+
+	shortener := app.NewURLShortener(repository, nil, authService, nil, nil)
+
+	// Строк больше, чем сохраняется за одно обращение к хранилищу
+	var body strings.Builder
+	for i := 1; i <= 250; i++ {
+		fmt.Fprintf(&body, "{\"correlation_id\":\"%d\",\"original_url\":\"https://chunk.example.com/%d\"}\n", i, i)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/shorten/import", strings.NewReader(body.String()))
+	rec := httptest.NewRecorder()
+
+	require.NoError(t, shortener.HandleImport(echo.New().NewContext(req, rec)))
+	assert.Equal(t, "application/x-ndjson", rec.Header().Get(echo.HeaderContentType))
+
+	results := decodeImportResults(t, rec.Body.String())
+	require.Len(t, results, 250)
+	for i, result := range results {
+		assert.Equal(t, i+1, result.Line)
+		assert.Equal(t, models.LinkStatusCreated, result.Status)
+	}
+
+	events, err := repository.GetEventsByUserID(context.TODO(), "testUserID")
+	require.NoError(t, err)
+	assert.Len(t, events, 250)
+}
+
+func TestURLShortener_HandleImportTooLarge(t *testing.T) {
+	environments.BaseAddr = "http://example.com"
+	repository := &models.MemoryURLRepository{}
+	require.NoError(t, repository.Initialize(context.TODO(), environments.Configuration{}))
+	authService := new(auth.AuthServiceInterface)
+	authService.EXPECT().GetUserID(mock.Anything).Return("testUserID")
+
+	shortener := app.NewURLShortener(repository, nil, authService, nil, nil)
+
+	// Строки сверх предела отклоняются, остальные импортируются
+	var body strings.Builder
+	for i := 1; i <= 10002; i++ {
+		fmt.Fprintf(&body, "{\"correlation_id\":\"%d\",\"original_url\":\"https://limit.example.com/%d\"}\n", i, i)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/shorten/import", strings.NewReader(body.String()))
+	rec := httptest.NewRecorder()
+
+	require.NoError(t, shortener.HandleImport(echo.New().NewContext(req, rec)))
+
+	results := decodeImportResults(t, rec.Body.String())
+	require.Len(t, results, 10002)
+	statuses := importStatuses(results)
+	assert.Equal(t, models.LinkStatusCreated, statuses[10000])
+	assert.Equal(t, models.LinkStatusInvalid, statuses[10001])
+	assert.Equal(t, models.LinkStatusInvalid, statuses[10002])
+
+	events, err := repository.GetEventsByUserID(context.TODO(), "testUserID")
+	require.NoError(t, err)
+	assert.Len(t, events, 10000)
+}
+
+func TestURLShortener_HandleImportUnavailable(t *testing.T) {
+	repository := new(models2.URLRepository)
+	repository.EXPECT().Get(mock.Anything, mock.Anything).Return(models.Event{}, models.ErrUnavailable)
+	authService := new(auth.AuthServiceInterface)
+	authService.EXPECT().GetUserID(mock.Anything).Return("")
+
+	shortener := app.NewURLShortener(repository, nil, authService, nil, nil)
+
+	req := httptest.NewRequest(
+		http.MethodPost,
+		"/api/shorten/import",
+		strings.NewReader(`{"correlation_id":"1","original_url":"https://example.com"}`),
+	)
+	rec := httptest.NewRecorder()
+
+	err := shortener.HandleImport(echo.New().NewContext(req, rec))
+	res, ok := err.(*echo.HTTPError)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusServiceUnavailable, res.Code)
+}
+
+func TestURLShortenerGRPC_ImportURLs(t *testing.T) {
+	environments.BaseAddr = "http://example.com"
+	repository := &models.MemoryURLRepository{}
+	require.NoError(t, repository.Initialize(context.TODO(), environments.Configuration{}))
+	require.NoError(t, repository.Save(context.TODO(), []*models.Event{{
+		ShortKey:    "taken",
+		OriginalURL: "https://taken.example.com",
+	}}))
+
+	conn, err := grpc.NewClient(
+		"passthrough://bufnet",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(dialer(app.NewURLShortenerGRPC(repository, nil, nil))),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := pb.NewURLClient(conn)
+
+	t.Run("positive test #1", func(t *testing.T) {
+		stream, err := client.ImportURLs(withToken(context.Background(), userToken(t, "testUserID")))
+		require.NoError(t, err)
+
+		for _, req := range []*pb.ImportURLsRequest{
+			{CorrelationId: "1", OriginalUrl: "https://grpc.example.com/1"},
+			{CorrelationId: "2"},
+			{CorrelationId: "3", OriginalUrl: "https://grpc.example.com/3", Alias: "taken"},
+			{CorrelationId: "4", OriginalUrl: "https://grpc.example.com/1"},
+		} {
+			require.NoError(t, stream.Send(req))
+		}
+		require.NoError(t, stream.CloseSend())
+
+		// Результаты приходят по мере сохранения, отклоненные строки раньше сохраненных
+		results := make(map[int32]*pb.ImportURLsResponse)
+		for {
+			result, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			require.NoError(t, err)
+			results[result.Line] = result
+		}
+		require.Len(t, results, 4)
+
+		var statuses []string
+		for line := int32(1); line <= 4; line++ {
+			statuses = append(statuses, results[line].Status)
+		}
+		assert.Equal(t, []string{
+			models.LinkStatusCreated,
+			models.LinkStatusInvalid,
+			models.LinkStatusInvalid,
+			models.LinkStatusExisting,
+		}, statuses)
+		assert.Equal(t, results[1].ShortUrl, results[4].ShortUrl)
+
+		events, err := repository.GetEventsByUserID(context.TODO(), "testUserID")
+		require.NoError(t, err)
+		assert.Len(t, events, 1)
+	})
+
+	t.Run("negative test #1", func(t *testing.T) {
+		stream, err := client.ImportURLs(withToken(context.Background(), "invalid"))
+		require.NoError(t, err)
+
+		require.NoError(t, stream.CloseSend())
+		_, err = stream.Recv()
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net"
 	"net/http"
@@ -12,11 +13,30 @@ import (
 	"time"
 
 	"github.com/ShukinDmitriy/shortener/internal/auth"
+	"github.com/ShukinDmitriy/shortener/internal/destination"
+	"github.com/ShukinDmitriy/shortener/internal/metrics"
 	"github.com/ShukinDmitriy/shortener/internal/models"
+	"github.com/ShukinDmitriy/shortener/internal/ratelimit"
 	"github.com/labstack/echo/v4"
 	"go.uber.org/zap"
 )
 
+// expiredEventsInterval период очистки просроченных ссылок
+const expiredEventsInterval = time.Minute
+
+const (
+	// clickFlushInterval период сохранения накопленных переходов
+	clickFlushInterval = 2 * time.Second
+	// clickSaveTimeout сколько ждем хранилище при сохранении переходов
+	clickSaveTimeout = 10 * time.Second
+)
+
+// maxBatchSize сколько ссылок можно создать одним пакетом
+const maxBatchSize = 1000
+
+// errBatchTooLarge пакет больше maxBatchSize
+var errBatchTooLarge = fmt.Errorf("batch is limited to %d links", maxBatchSize)
+
 // PgxConnPinger interface for checking connection to the database
 type PgxConnPinger interface {
 	Ping(context.Context) error
@@ -26,37 +46,62 @@ type PgxConnPinger interface {
 // URLShortener the application
 type URLShortener struct {
 	URLRepository models.URLRepository
-	conn          PgxConnPinger
+	// UserRepository accounts of registered users
+	UserRepository models.UserRepository
+	KeyGenerator   models.KeyGenerator
+	URLNormalizer  *models.URLNormalizer
+	// DestinationPolicy refuses blocked destinations and destinations in private networks
+	DestinationPolicy *destination.Policy
+	conn              PgxConnPinger
 
 	authService auth.AuthServiceInterface
 
-	// канал для отложенного удаления
-	eDeletedEvent chan models.DeleteRequestBatch
+	// очередь отложенного удаления
+	deleteQueue *DeleteQueue
+
+	// канал для остановки очистки просроченных ссылок
+	reaperShutdownChan chan chan struct{}
 
-	// канал для уведомления об окончании работы
-	shutdownChan chan chan struct{}
+	// канал для отложенного сохранения переходов
+	eClickEvent chan *models.Click
+
+	// канал для остановки сохранения переходов
+	clickShutdownChan chan chan struct{}
 
 	// разрешенная подсеть
 	subnet *net.IPNet
 }
 
-// NewURLShortener application's constructor
+// NewURLShortener application's constructor.
+// The deletion queue is stopped by Shutdown, without it an in-memory queue is used.
 func NewURLShortener(
 	urlRepository models.URLRepository,
 	conn PgxConnPinger,
 	authService auth.AuthServiceInterface,
 	subnet *net.IPNet,
+	deleteQueue *DeleteQueue,
 ) *URLShortener {
+	if deleteQueue == nil {
+		deleteQueue = NewDeleteQueue(urlRepository, nil)
+	}
+
 	instance := &URLShortener{
-		URLRepository: urlRepository,
-		conn:          conn,
-		authService:   authService,
-		eDeletedEvent: make(chan models.DeleteRequestBatch, 100),
-		shutdownChan:  make(chan chan struct{}),
-		subnet:        subnet,
+		URLRepository:      urlRepository,
+		UserRepository:     models.NewMemoryUserRepository(),
+		KeyGenerator:       models.NewRandomKeyGenerator(models.DefaultKeyAlphabet, models.DefaultKeyLength),
+		URLNormalizer:      models.NewURLNormalizer(models.DefaultURLMaxLength, false, false),
+		DestinationPolicy:  destination.NewPolicy(destination.Options{}),
+		conn:               conn,
+		authService:        authService,
+		deleteQueue:        deleteQueue,
+		reaperShutdownChan: make(chan chan struct{}),
+		eClickEvent:        make(chan *models.Click, 1000),
+		clickShutdownChan:  make(chan chan struct{}),
+		subnet:             subnet,
 	}
 
-	go instance.deleteEvents()
+	go instance.reapExpiredEvents()
+	go instance.saveClickEvents()
 
 	return instance
 }
@@ -77,23 +122,37 @@ func (us *URLShortener) HandleShorten(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, err)
 	}
 
-	// Generate a unique shortened key for the original URL
-	shortKey := models.GenerateShortKey()
+	normalizedURL, err := prepareOriginalURL(ctx.Request().Context(), us.URLNormalizer, us.DestinationPolicy, string(originalURL))
+	if err != nil {
+		ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
 
+	// Save under a unique shortened key for the original URL
 	status := http.StatusCreated
 	events := []*models.Event{{
-		ShortKey:    shortKey,
-		OriginalURL: string(originalURL),
+		OriginalURL: normalizedURL,
 		UserID:      us.authService.GetUserID(ctx),
 	}}
-	err = us.URLRepository.Save(ctx.Request().Context(), events)
+	err = models.SaveWithShortKey(ctx.Request().Context(), us.KeyGenerator, us.URLRepository, "", events[0])
+	recordCreated(ctx.Request().Context(), metrics.TransportHTTP, events, err)
+	shortKey := events[0].ShortKey
 
 	if errors.Is(err, models.ErrURLExist) {
 		ctx.Logger().Error(err)
-		shortKey = events[0].ShortKey
 		return ctx.String(http.StatusConflict, models.PrepareFullURL(shortKey, ctx.Request().Host))
 	}
 
+	if errors.Is(err, models.ErrUnavailable) {
+		ctx.Logger().Error(err)
+		return repositoryError(err)
+	}
+
+	if errors.Is(err, models.ErrShortKeyGeneration) {
+		ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "can't generate short key")
+	}
+
 	if err != nil {
 		ctx.Logger().Error(err)
 		return echo.NewHTTPError(http.StatusBadRequest, "can't save url. internal error1"+err.Error())
@@ -127,23 +186,51 @@ func (us *URLShortener) HandleCreateShorten(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, err)
 	}
 
-	// Generate a unique shortened key for the original URL
-	shortKey := models.GenerateShortKey()
+	normalizedURL, err := prepareOriginalURL(ctx.Request().Context(), us.URLNormalizer, us.DestinationPolicy, req.URL)
+	if err != nil {
+		ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	req.URL = normalizedURL
+
+	expiresAt, err := models.PrepareExpiresAt(req.ExpiresAt, req.TTL, time.Now())
+	if err != nil {
+		ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
 
+	// Use the caller's alias or a unique shortened key for the original URL
 	status := http.StatusCreated
 	events := []*models.Event{{
-		ShortKey:    shortKey,
 		OriginalURL: req.URL,
 		UserID:      us.authService.GetUserID(ctx),
+		ExpiresAt:   expiresAt,
 	}}
 
-	err := us.URLRepository.Save(ctx.Request().Context(), events)
+	err = models.SaveWithShortKey(ctx.Request().Context(), us.KeyGenerator, us.URLRepository, req.Alias, events[0])
+	recordCreated(ctx.Request().Context(), metrics.TransportHTTP, events, err)
+	shortKey := events[0].ShortKey
 	if err != nil {
 		ctx.Logger().Error(err)
 
+		if errors.Is(err, models.ErrShortKeyExist) && req.Alias != "" {
+			return echo.NewHTTPError(http.StatusConflict, "alias already exists")
+		}
+
+		if errors.Is(err, models.ErrShortKeyGeneration) {
+			return echo.NewHTTPError(http.StatusInternalServerError, "can't generate short key")
+		}
+
+		if errors.Is(err, models.ErrUnavailable) {
+			return repositoryError(err)
+		}
+
+		if errors.Is(err, models.ErrAliasInvalid) || errors.Is(err, models.ErrAliasReserved) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+
 		if errors.Is(err, models.ErrURLExist) {
 			status = http.StatusConflict
-			shortKey = events[0].ShortKey
 		} else {
 			return echo.NewHTTPError(http.StatusBadRequest, "can't save url. internal error")
 		}
@@ -157,7 +244,9 @@ func (us *URLShortener) HandleCreateShorten(ctx echo.Context) error {
 	return ctx.JSON(status, resp)
 }
 
-// HandleCreateShortenBatch handler for batch create links
+// HandleCreateShortenBatch handler for batch create links.
+// Every item gets its own status: created, existing or invalid, so a bad item doesn't fail the whole batch.
+// Responds with 201 if at least one link was created and with 200 otherwise.
 func (us *URLShortener) HandleCreateShortenBatch(ctx echo.Context) error {
 	// десериализуем запрос в структуру модели
 	zap.L().Debug("decoding request")
@@ -167,57 +256,148 @@ func (us *URLShortener) HandleCreateShortenBatch(ctx echo.Context) error {
 		zap.L().Debug("cannot decode request JSON body", zap.Error(err))
 		return echo.NewHTTPError(http.StatusInternalServerError, "invalid JSON")
 	}
+	if len(req) > maxBatchSize {
+		return echo.NewHTTPError(http.StatusBadRequest, errBatchTooLarge.Error())
+	}
 
-	// События для сохранения
-	events := make([]*models.Event, len(req))
+	resp := make([]models.CreateResponseBatch, len(req))
+	// События для сохранения и их позиции в ответе
+	events := make([]*models.Event, 0, len(req))
+	positions := make([]int, 0, len(req))
 	userID := us.authService.GetUserID(ctx)
 
 	for i, cr := range req {
-		// проверяем, что пришёл запрос понятного типа
-		if string(cr.OriginalURL) == "" || string(cr.CorrelationID) == "" {
-			err := "empty original_url or correlation_id"
+		resp[i].CorrelationID = cr.CorrelationID
+
+		event, err := prepareBatchEvent(ctx.Request().Context(), us.URLNormalizer, us.DestinationPolicy, userID, cr)
+		if err != nil {
+			ctx.Logger().Error(err)
+
+			resp[i].Status = models.LinkStatusInvalid
+			resp[i].Error = err.Error()
+			continue
+		}
+
+		events = append(events, event)
+		positions = append(positions, i)
+	}
+
+	status := http.StatusOK
+	if len(events) > 0 {
+		// Конфликт в одном элементе не прерывает сохранение остальных
+		err := models.SaveBatchWithShortKeys(ctx.Request().Context(), us.KeyGenerator, us.URLRepository, events)
+		if batchSaveFailed(err) {
 			ctx.Logger().Error(err)
-			zap.L().Debug(
-				"unsupported request url",
-				zap.String("original_url", cr.OriginalURL),
-				zap.String("correlation_id", cr.CorrelationID),
-			)
-			return echo.NewHTTPError(http.StatusBadRequest, err)
+			return repositoryError(err)
 		}
+		recordCreated(ctx.Request().Context(), metrics.TransportHTTP, events, err)
 
-		// Generate a unique shortened key for the original URL
-		shortKey := models.GenerateShortKey()
+		// заполняем модель ответа
+		for j, event := range events {
+			i := positions[j]
+			resp[i].Status, resp[i].Error = linkStatus(models.SaveErrorAt(err, j))
 
-		events[i] = &models.Event{
-			ShortKey:      shortKey,
-			OriginalURL:   cr.OriginalURL,
-			CorrelationID: cr.CorrelationID,
-			UserID:        userID,
+			if resp[i].Status != models.LinkStatusInvalid {
+				resp[i].ShortURL = models.PrepareFullURL(event.ShortKey, ctx.Request().Host)
+			}
+			if resp[i].Status == models.LinkStatusCreated {
+				status = http.StatusCreated
+			}
 		}
 	}
 
-	status := http.StatusCreated
-	err := us.URLRepository.Save(ctx.Request().Context(), events)
+	return ctx.JSON(status, resp)
+}
+
+// HandleImport handler for bulk import of links from NDJSON or CSV.
+// Rows are saved in chunks, the result of every row is streamed back as NDJSON,
+// a rejected row doesn't fail the whole import.
+func (us *URLShortener) HandleImport(ctx echo.Context) error {
+	reader, err := newImportReader(ctx.Request().Header.Get(echo.HeaderContentType), ctx.Request().Body)
+	if errors.Is(err, errUnsupportedImportFormat) {
+		return echo.NewHTTPError(http.StatusUnsupportedMediaType, err.Error())
+	}
 	if err != nil {
-		ctx.Logger().Error(err)
+		zap.L().Debug("cannot read import header", zap.Error(err))
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
 
-		if errors.Is(err, models.ErrURLExist) {
-			status = http.StatusConflict
-		} else {
-			return echo.NewHTTPError(http.StatusBadRequest, "can't save url. internal error")
-		}
+	res := ctx.Response()
+	enc := json.NewEncoder(res)
+	importer := &urlImporter{
+		repository:   us.URLRepository,
+		keyGenerator: us.KeyGenerator,
+		normalizer:   us.URLNormalizer,
+		policy:       us.DestinationPolicy,
+		transport:    metrics.TransportHTTP,
+		userID:       us.authService.GetUserID(ctx),
+		host:         ctx.Request().Host,
+		report: func(result models.ImportResponse) error {
+			if !res.Committed {
+				res.Header().Set(echo.HeaderContentType, "application/x-ndjson")
+				res.WriteHeader(http.StatusOK)
+			}
+
+			if err := enc.Encode(result); err != nil {
+				return err
+			}
+			res.Flush()
+
+			return nil
+		},
 	}
 
-	// заполняем модель ответа
-	resp := make([]models.CreateResponseBatch, len(events))
-	for i, event := range events {
-		resp[i] = models.CreateResponseBatch{
-			CorrelationID: event.CorrelationID,
-			ShortURL:      models.PrepareFullURL(event.ShortKey, ctx.Request().Host),
+	line := 0
+	for {
+		var req models.CreateRequestBatch
+		line, req, err = reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		var rowErr *importRowError
+		if errors.As(err, &rowErr) {
+			err = importer.Fail(line, "", rowErr.err.Error())
+		} else if err == nil {
+			err = importer.Add(ctx.Request().Context(), line, req)
+		}
+
+		if err != nil {
+			return importAborted(ctx, line, err)
 		}
 	}
 
-	return ctx.JSON(status, resp)
+	if err = importer.Flush(ctx.Request().Context()); err != nil {
+		return importAborted(ctx, line, err)
+	}
+
+	// Пустой импорт
+	if !res.Committed {
+		return ctx.NoContent(http.StatusOK)
+	}
+
+	return nil
+}
+
+// importAborted сообщает о прерванном импорте: до первой строки ответа кодом,
+// после нее последней строкой, строки без результата не импортированы
+func importAborted(ctx echo.Context, line int, err error) error {
+	ctx.Logger().Error(err)
+
+	httpErr := repositoryError(err)
+	if !errors.Is(err, models.ErrUnavailable) {
+		httpErr = echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if !ctx.Response().Committed {
+		return httpErr
+	}
+
+	return json.NewEncoder(ctx.Response()).Encode(models.ImportResponse{
+		Line:   line,
+		Status: models.ImportStatusAborted,
+		Error:  fmt.Sprint("import aborted: ", httpErr.Message),
+	})
 }
 
 // HandleRedirect handler for redirect by short link
@@ -230,17 +410,27 @@ func (us *URLShortener) HandleRedirect(ctx echo.Context) error {
 	}
 
 	// Retrieve the original URL from the `urls` map using the shortened key
-	event, found := us.URLRepository.Get(shortKey)
-	if !found {
-		err := "URL not found"
+	event, err := us.URLRepository.Get(ctx.Request().Context(), shortKey)
+	if errors.Is(err, models.ErrDeleted) || (err == nil && event.IsExpired(time.Now())) {
+		return ctx.String(http.StatusGone, "")
+	}
+
+	if err != nil {
 		ctx.Logger().Error(err)
-		return echo.NewHTTPError(http.StatusNotFound, err)
+		return repositoryError(err)
 	}
 
-	if event.DeletedFlag {
-		return ctx.String(http.StatusGone, "")
+	// Ссылка могла попасть в список заблокированных после создания
+	if us.DestinationPolicy.CheckOnRedirect() {
+		if err = us.DestinationPolicy.CheckRedirect(ctx.Request().Context(), event.OriginalURL); err != nil {
+			ctx.Logger().Error(err)
+			return echo.NewHTTPError(http.StatusForbidden, err.Error())
+		}
 	}
 
+	us.trackClick(ctx, shortKey)
+	metrics.LinksRedirectedTotal.WithLabelValues(metrics.TransportHTTP).Inc()
+
 	return ctx.Redirect(http.StatusTemporaryRedirect, event.OriginalURL)
 }
 
@@ -267,7 +457,11 @@ func (us *URLShortener) HandleUserURLGet(ctx echo.Context) error {
 		return ctx.JSON(http.StatusNoContent, nil)
 	}
 
-	events := us.URLRepository.GetEventsByUserID(ctx.Request().Context(), userID)
+	events, err := us.URLRepository.GetEventsByUserID(ctx.Request().Context(), userID)
+	if err != nil {
+		ctx.Logger().Error(err)
+		return repositoryError(err)
+	}
 
 	// заполняем модель ответа
 	resp := make([]models.GetUserURLsResponse, len(events))
@@ -282,6 +476,38 @@ func (us *URLShortener) HandleUserURLGet(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, resp)
 }
 
+// HandleUserURLStats handler for get short link's clicks statistic
+func (us *URLShortener) HandleUserURLStats(ctx echo.Context) error {
+	userID := us.authService.GetUserID(ctx)
+	shortKey := ctx.Param("id")
+
+	// Статистика доступна только владельцу ссылки
+	// Статистика удаленной ссылки остается доступной
+	event, err := us.URLRepository.Get(ctx.Request().Context(), shortKey)
+	if err != nil && !errors.Is(err, models.ErrDeleted) {
+		ctx.Logger().Error(err)
+		return repositoryError(err)
+	}
+
+	if userID == "" || event.UserID != userID {
+		err := "URL not found"
+		ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusNotFound, err)
+	}
+
+	stats, err := us.URLRepository.GetClickStats(ctx.Request().Context(), shortKey)
+	if err != nil {
+		ctx.Logger().Error(err)
+		return repositoryError(err)
+	}
+
+	return ctx.JSON(http.StatusOK, models.GetURLStatsResponse{
+		ShortURL:    models.PrepareFullURL(shortKey, ctx.Request().Host),
+		OriginalURL: event.OriginalURL,
+		ClickStats:  stats,
+	})
+}
+
 // HandleUserURLDelete handler for delete short link
 func (us *URLShortener) HandleUserURLDelete(ctx echo.Context) error {
 	req := models.DeleteRequestBatch{
@@ -298,7 +524,10 @@ func (us *URLShortener) HandleUserURLDelete(ctx echo.Context) error {
 
 	zap.L().Info("delete", zap.Any("req", req))
 
-	us.eDeletedEvent <- req
+	if err := us.deleteQueue.Enqueue(ctx.Request().Context(), req); err != nil {
+		ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "Service Unavailable")
+	}
 
 	return ctx.JSON(http.StatusAccepted, "Accepted")
 }
@@ -315,7 +544,7 @@ func (us *URLShortener) HandleGetStats(ctx echo.Context) error {
 	countUser, countURL, err := us.URLRepository.GetStats(ctx.Request().Context())
 	if err != nil {
 		ctx.Logger().Error(err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Internal Server Error")
+		return repositoryError(err)
 	}
 
 	return ctx.JSON(http.StatusOK, struct {
@@ -327,6 +556,28 @@ func (us *URLShortener) HandleGetStats(ctx echo.Context) error {
 	})
 }
 
+// HandleCompact handler for on-demand compaction of the storage file, allowed only from the trusted subnet
+func (us *URLShortener) HandleCompact(ctx echo.Context) error {
+	// Проверяем доступ
+	xRealIPHeader := ctx.Request().Header.Get("X-Real-IP")
+	ip := net.ParseIP(xRealIPHeader)
+	if us.subnet == nil || !us.subnet.Contains(ip) {
+		return ctx.JSON(http.StatusForbidden, http.NoBody)
+	}
+
+	compactor, ok := models.AsCompactor(us.URLRepository)
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotImplemented, "storage doesn't support compaction")
+	}
+
+	if err := compactor.Compact(ctx.Request().Context()); err != nil {
+		ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal Server Error")
+	}
+
+	return ctx.JSON(http.StatusOK, "Compacted")
+}
+
 // Shutdown function
 func (us *URLShortener) Shutdown() chan struct{} {
 	res := make(chan struct{})
@@ -334,65 +585,227 @@ func (us *URLShortener) Shutdown() chan struct{} {
 	go func() {
 		defer close(res)
 
-		successShutdown := make(chan struct{})
-		us.shutdownChan <- successShutdown
+		// Останавливаем очистку просроченных ссылок
+		reaperShutdown := make(chan struct{})
+		us.reaperShutdownChan <- reaperShutdown
+		<-reaperShutdown
+		close(reaperShutdown)
+
+		// Сохраняем накопленные переходы
+		clickShutdown := make(chan struct{})
+		us.clickShutdownChan <- clickShutdown
+		<-clickShutdown
+		close(clickShutdown)
+
+		// Выполняем накопленные удаления, остальное останется в журнале
+		<-us.deleteQueue.Shutdown()
 
-		<-successShutdown
-		close(successShutdown)
 		res <- struct{}{}
 	}()
 
 	return res
 }
 
-func (us *URLShortener) deleteEvents() {
-	var events []models.DeleteRequestBatch
-	ticker := time.NewTicker(2 * time.Second)
+func (us *URLShortener) reapExpiredEvents() {
+	ticker := time.NewTicker(expiredEventsInterval)
+	defer ticker.Stop()
 
 	for {
 		select {
-		case event := <-us.eDeletedEvent:
-			events = append(events, event)
-		case success := <-us.shutdownChan:
-			if len(events) == 0 {
-				success <- struct{}{}
-				return
+		case success := <-us.reaperShutdownChan:
+			success <- struct{}{}
+			return
+		case <-ticker.C:
+			count, err := us.URLRepository.DeleteExpired(context.TODO(), time.Now())
+			if err != nil {
+				zap.L().Error("cannot delete expired events", zap.String("err", err.Error()))
+				continue
 			}
 
-			// Сброс на диск очереди на удаление
-			fileProducer, err := models.NewProducer("/tmp/short-deleted-db.json")
-			if err != nil {
-				zap.L().Error("create file backup", zap.String("err", err.Error()))
-				success <- struct{}{}
-				return
+			if count > 0 {
+				zap.L().Info("expired events deleted", zap.Int("count", count))
 			}
+		}
+	}
+}
 
-			err = fileProducer.WriteEvent(events)
-			if err != nil {
-				zap.L().Error("backup deleted events", zap.String("err", err.Error()))
+// trackClick ставит переход в очередь на сохранение, не задерживая редирект
+func (us *URLShortener) trackClick(ctx echo.Context, shortKey string) {
+	us.TrackClick(models.NewClick(
+		shortKey,
+		time.Now(),
+		ctx.Request().Referer(),
+		ctx.Request().UserAgent(),
+		ctx.RealIP(),
+	))
+}
+
+// TrackClick put the click into the queue saved in the background, the click is dropped when the queue is full
+func (us *URLShortener) TrackClick(click *models.Click) {
+	select {
+	case us.eClickEvent <- click:
+	default:
+		zap.L().Warn("click queue is full", zap.String("short_key", click.ShortKey))
+	}
+}
+
+func (us *URLShortener) saveClickEvents() {
+	var clicks []*models.Click
+	ticker := time.NewTicker(clickFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case click := <-us.eClickEvent:
+			clicks = append(clicks, click)
+		case success := <-us.clickShutdownChan:
+			// Забираем то, что осталось в очереди
+			for len(us.eClickEvent) > 0 {
+				clicks = append(clicks, <-us.eClickEvent)
 			}
 
-			events = nil
+			us.flushClicks(clicks)
+
 			success <- struct{}{}
 
 			return
 		case <-ticker.C:
-			if len(events) == 0 {
-				continue
-			}
+			// Сохраняем в этой же горутине: медленное хранилище заполнит очередь,
+			// и новые переходы будут отброшены в trackClick, а не накопятся в горутинах
+			us.flushClicks(clicks)
+
+			clicks = nil
+		}
+	}
+}
+
+// flushClicks сохраняет пачку переходов, ошибка только логируется
+func (us *URLShortener) flushClicks(clicks []*models.Click) {
+	if len(clicks) == 0 {
+		return
+	}
 
-			copyEvents := make([]models.DeleteRequestBatch, len(events))
+	ctx, cancel := context.WithTimeout(context.Background(), clickSaveTimeout)
+	defer cancel()
 
-			copy(copyEvents, events)
+	if err := us.URLRepository.SaveClicks(ctx, clicks); err != nil {
+		zap.L().Error("cannot save clicks", zap.Int("count", len(clicks)), zap.Error(err))
+	}
+}
 
-			go func(events []models.DeleteRequestBatch) {
-				err := us.URLRepository.Delete(context.TODO(), events)
-				if err != nil {
-					zap.L().Error("cannot delete events", zap.String("err", err.Error()))
-				}
-			}(copyEvents)
+// DeleteQueueDepth count of delete requests waiting in the queue
+func (us *URLShortener) DeleteQueueDepth() int {
+	return us.deleteQueue.Depth()
+}
 
-			events = nil
+// recordCreated учитывает в метриках ссылки, созданные при сохранении,
+// и списывает их с ограничения запросов клиента
+func recordCreated(ctx context.Context, transport string, events []*models.Event, err error) {
+	count := 0
+	for i := range events {
+		if models.SaveErrorAt(err, i) == nil {
+			count++
 		}
 	}
+
+	metrics.LinksCreatedTotal.WithLabelValues(transport).Add(float64(count))
+	ratelimit.ChargeLinks(ctx, count)
+}
+
+// prepareBatchEvent проверяет элемент пакета и готовит событие для сохранения,
+// ошибка отклоняет только этот элемент. Событие без алиаса получает ключ при сохранении пакета.
+func prepareBatchEvent(
+	ctx context.Context,
+	normalizer *models.URLNormalizer,
+	policy *destination.Policy,
+	userID string,
+	req models.CreateRequestBatch,
+) (*models.Event, error) {
+	// проверяем, что пришёл запрос понятного типа
+	if req.OriginalURL == "" || req.CorrelationID == "" {
+		return nil, errors.New("empty original_url or correlation_id")
+	}
+
+	originalURL, err := prepareOriginalURL(ctx, normalizer, policy, req.OriginalURL)
+	if err != nil {
+		return nil, err
+	}
+	req.OriginalURL = originalURL
+
+	if req.Alias != "" {
+		if err = models.ValidateAlias(req.Alias); err != nil {
+			return nil, err
+		}
+	}
+
+	expiresAt, err := models.PrepareExpiresAt(req.ExpiresAt, req.TTL, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Event{
+		ShortKey:      req.Alias,
+		OriginalURL:   req.OriginalURL,
+		CorrelationID: req.CorrelationID,
+		UserID:        userID,
+		ExpiresAt:     expiresAt,
+	}, nil
+}
+
+// prepareOriginalURL приводит ссылку к каноническому виду и проверяет ее политикой назначений
+func prepareOriginalURL(
+	ctx context.Context,
+	normalizer *models.URLNormalizer,
+	policy *destination.Policy,
+	rawURL string,
+) (string, error) {
+	originalURL, err := normalizer.Normalize(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	if err = policy.Check(ctx, originalURL); err != nil {
+		return "", err
+	}
+
+	return originalURL, nil
+}
+
+// batchSaveFailed ошибка сохранения относится ко всему пакету, а не к отдельным ссылкам
+func batchSaveFailed(err error) bool {
+	var saveErr *models.SaveError
+	return err != nil && !errors.As(err, &saveErr)
+}
+
+// linkStatus статус ссылки в пакетном ответе по результату ее сохранения и причина отказа
+func linkStatus(err error) (string, string) {
+	switch {
+	case err == nil:
+		return models.LinkStatusCreated, ""
+	case errors.Is(err, models.ErrURLExist):
+		return models.LinkStatusExisting, ""
+	// Занятый сгенерированный ключ генерируется заново, так что занятым остается только алиас
+	case errors.Is(err, models.ErrShortKeyExist):
+		return models.LinkStatusInvalid, "alias already exists"
+	case errors.Is(err, models.ErrShortKeyGeneration):
+		return models.LinkStatusInvalid, "can't generate short key"
+	case errors.Is(err, models.ErrDeleted):
+		return models.LinkStatusInvalid, "URL deleted"
+	default:
+		return models.LinkStatusInvalid, "can't save url"
+	}
+}
+
+// repositoryError преобразует ошибку хранилища в HTTP-ответ
+func repositoryError(err error) *echo.HTTPError {
+	switch {
+	case errors.Is(err, models.ErrNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "URL not found")
+	case errors.Is(err, models.ErrDeleted):
+		return echo.NewHTTPError(http.StatusGone, "URL deleted")
+	case errors.Is(err, models.ErrUnavailable):
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "Service Unavailable")
+	default:
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal Server Error")
+	}
 }
@@ -3,34 +3,93 @@ package app
 import (
 	"context"
 	"errors"
-	"net"
+	"io"
+	"time"
 
+	"github.com/ShukinDmitriy/shortener/internal/auth"
+	"github.com/ShukinDmitriy/shortener/internal/destination"
+	"github.com/ShukinDmitriy/shortener/internal/interceptors"
+	"github.com/ShukinDmitriy/shortener/internal/metrics"
 	"github.com/ShukinDmitriy/shortener/internal/models"
+	"github.com/ShukinDmitriy/shortener/internal/ratelimit"
 	pb "github.com/ShukinDmitriy/shortener/proto"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
-// URLShortenerGRPC the application
+// GRPCAnonymousMethods methods that can be called without an access token
+var GRPCAnonymousMethods = []string{
+	pb.URL_Create_FullMethodName,
+	pb.URL_CreateBatch_FullMethodName,
+	pb.URL_ImportURLs_FullMethodName,
+	pb.URL_Redirect_FullMethodName,
+	pb.URL_GetStats_FullMethodName,
+	pb.URL_ExportAll_FullMethodName,
+}
+
+// GRPCTrustedSubnetMethods methods available only from the trusted subnet
+var GRPCTrustedSubnetMethods = []string{
+	pb.URL_GetStats_FullMethodName,
+	pb.URL_ExportAll_FullMethodName,
+}
+
+// GRPCRateLimitedMethods policies of the methods limited per client
+func GRPCRateLimitedMethods(policies ratelimit.Policies) map[string]ratelimit.Policy {
+	return map[string]ratelimit.Policy{
+		pb.URL_Create_FullMethodName:      policies.Shorten,
+		pb.URL_CreateBatch_FullMethodName: policies.Shorten,
+		pb.URL_ImportURLs_FullMethodName:  policies.Shorten,
+		pb.URL_Redirect_FullMethodName:    policies.Redirect,
+	}
+}
+
+// ClickTracker saves clicks in the background without delaying the redirect
+type ClickTracker interface {
+	TrackClick(click *models.Click)
+}
+
+// URLShortenerGRPC the application.
+// The user is put into the context by the auth interceptor from the access token
+// in the "authorization" metadata, access to GetStats is checked by the trusted
+// subnet interceptor, as well as to ExportAll. The user_id and ip_address request fields are ignored.
 type URLShortenerGRPC struct {
 	pb.UnimplementedURLServer
 
 	URLRepository models.URLRepository
-	conn          PgxConnPinger
-
-	// разрешенная подсеть
-	subnet *net.IPNet
+	KeyGenerator  models.KeyGenerator
+	URLNormalizer *models.URLNormalizer
+	// DestinationPolicy refuses blocked destinations and destinations in private networks
+	DestinationPolicy *destination.Policy
+	// Clicks queue of clicks shared with the HTTP application, without it redirects aren't counted
+	Clicks ClickTracker
+	conn   PgxConnPinger
+
+	// очередь отложенного удаления, общая с HTTP API
+	deleteQueue *DeleteQueue
 }
 
-// NewURLShortenerGRPC application's constructor
+// NewURLShortenerGRPC application's constructor.
+// The deletion queue is shared with the HTTP application, without it an in-memory queue is used.
 func NewURLShortenerGRPC(
 	urlRepository models.URLRepository,
 	conn PgxConnPinger,
-	subnet *net.IPNet,
+	deleteQueue *DeleteQueue,
 ) *URLShortenerGRPC {
+	if deleteQueue == nil {
+		deleteQueue = NewDeleteQueue(urlRepository, nil)
+	}
+
 	instance := &URLShortenerGRPC{
-		URLRepository: urlRepository,
-		conn:          conn,
-		subnet:        subnet,
+		URLRepository:     urlRepository,
+		KeyGenerator:      models.NewRandomKeyGenerator(models.DefaultKeyAlphabet, models.DefaultKeyLength),
+		URLNormalizer:     models.NewURLNormalizer(models.DefaultURLMaxLength, false, false),
+		DestinationPolicy: destination.NewPolicy(destination.Options{}),
+		conn:              conn,
+		deleteQueue:       deleteQueue,
 	}
 
 	return instance
@@ -38,147 +97,262 @@ func NewURLShortenerGRPC(
 
 // Create handler for create short link
 func (us *URLShortenerGRPC) Create(ctx context.Context, req *pb.CreateRequest) (*pb.CreateResponse, error) {
+	// Ссылку можно создать без токена, тогда у нее нет владельца
+	userID := auth.UserIDFromContext(ctx)
+
 	// проверяем, что пришёл запрос понятного типа
 	if string(req.OriginalUrl) == "" {
 		zap.L().Debug("unsupported request url", zap.String("url", req.OriginalUrl))
-		return &pb.CreateResponse{
-			Status: "bad request",
-		}, nil
+		return nil, status.Error(codes.InvalidArgument, "empty original_url")
+	}
+	originalURL, err := prepareOriginalURL(ctx, us.URLNormalizer, us.DestinationPolicy, req.OriginalUrl)
+	if err != nil {
+		zap.L().Debug("invalid request url", zap.String("url", req.OriginalUrl), zap.Error(err))
+		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
-	// Generate a unique shortened key for the original URL
-	shortKey := models.GenerateShortKey()
 
-	status := "created"
+	expiresAt, err := models.PrepareExpiresAt(timestampToTime(req.ExpiresAt), req.Ttl, time.Now())
+	if err != nil {
+		zap.L().Debug("invalid expiration", zap.Error(err))
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// Use the caller's alias or a unique shortened key for the original URL
 	events := []*models.Event{{
-		ShortKey:    shortKey,
-		OriginalURL: req.OriginalUrl,
-		UserID:      req.UserId,
+		OriginalURL: originalURL,
+		UserID:      userID,
+		ExpiresAt:   expiresAt,
 	}}
 
-	err := us.URLRepository.Save(ctx, events)
+	err = models.SaveWithShortKey(ctx, us.KeyGenerator, us.URLRepository, req.Alias, events[0])
+	recordCreated(ctx, metrics.TransportGRPC, events, err)
+	shortKey := events[0].ShortKey
 	if err != nil {
+		if errors.Is(err, models.ErrAliasInvalid) || errors.Is(err, models.ErrAliasReserved) {
+			zap.L().Debug("can't prepare short key", zap.String("alias", req.Alias), zap.Error(err))
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+
 		zap.L().Error(err.Error())
 
+		if errors.Is(err, models.ErrShortKeyExist) && req.Alias != "" {
+			return nil, status.Error(codes.AlreadyExists, "alias conflict")
+		}
+
+		if errors.Is(err, models.ErrShortKeyGeneration) {
+			return nil, status.Error(codes.Internal, "can't generate short key")
+		}
+
 		if errors.Is(err, models.ErrURLExist) {
-			status = "conflict"
-			shortKey = events[0].ShortKey
-		} else {
-			zap.L().Debug("can't save url. internal error", zap.String("url", req.OriginalUrl))
-			return &pb.CreateResponse{
-				Status: "bad request",
-			}, nil
+			return nil, conflictStatus(&pb.CreateResponse{
+				Status:      "conflict",
+				ResponseUrl: models.PrepareFullURL(shortKey, ""),
+			})
 		}
+
+		return nil, repositoryStatus(err)
 	}
 
 	// заполняем модель ответа
 	return &pb.CreateResponse{
-		Status:      status,
+		Status:      "created",
 		ResponseUrl: models.PrepareFullURL(shortKey, ""),
 	}, nil
 }
 
+// CreateBatch handler for create short links in batch.
+// Every item gets its own status: created, existing or invalid, so a bad item doesn't fail the whole batch.
 func (us *URLShortenerGRPC) CreateBatch(ctx context.Context, req *pb.CreateBatchRequest) (*pb.CreateBatchResponse, error) {
-	// События для сохранения
-	events := make([]*models.Event, len(req.Urls))
+	if len(req.Urls) > maxBatchSize {
+		return nil, status.Error(codes.InvalidArgument, errBatchTooLarge.Error())
+	}
+
+	userID := auth.UserIDFromContext(ctx)
+
+	resp := make([]*pb.CreateBatchResponse_URL, len(req.Urls))
+	// События для сохранения и их позиции в ответе
+	events := make([]*models.Event, 0, len(req.Urls))
+	positions := make([]int, 0, len(req.Urls))
 
 	for i, cr := range req.Urls {
-		// проверяем, что пришёл запрос понятного типа
-		if cr.OriginalUrl == "" || cr.CorrelationId == "" {
-			err := "empty original_url or correlation_id"
-			zap.L().Error(err)
-			zap.L().Debug(
-				"unsupported request url",
-				zap.String("original_url", cr.OriginalUrl),
-				zap.String("correlation_id", cr.CorrelationId),
-			)
-			return &pb.CreateBatchResponse{
-				Status: "bad request",
-			}, nil
+		resp[i] = &pb.CreateBatchResponse_URL{
+			CorrelationId: cr.CorrelationId,
 		}
 
-		// Generate a unique shortened key for the original URL
-		shortKey := models.GenerateShortKey()
-
-		events[i] = &models.Event{
-			ShortKey:      shortKey,
-			OriginalURL:   cr.OriginalUrl,
+		event, err := prepareBatchEvent(ctx, us.URLNormalizer, us.DestinationPolicy, userID, models.CreateRequestBatch{
 			CorrelationID: cr.CorrelationId,
-			UserID:        req.UserId,
+			OriginalURL:   cr.OriginalUrl,
+			Alias:         cr.Alias,
+			ExpiresAt:     timestampToTime(cr.ExpiresAt),
+			TTL:           cr.Ttl,
+		})
+		if err != nil {
+			zap.L().Debug("invalid batch item", zap.String("correlation_id", cr.CorrelationId), zap.Error(err))
+			resp[i].Status = models.LinkStatusInvalid
+			resp[i].Error = err.Error()
+			continue
 		}
-	}
-
-	status := "created"
-	err := us.URLRepository.Save(ctx, events)
-	if err != nil {
-		zap.L().Error(err.Error())
 
-		if errors.Is(err, models.ErrURLExist) {
-			status = "conflict"
-		} else {
-			return &pb.CreateBatchResponse{
-				Status: "bad request",
-			}, nil
-		}
+		events = append(events, event)
+		positions = append(positions, i)
 	}
 
-	// заполняем модель ответа
-	resp := make([]*pb.CreateBatchResponse_URL, len(events))
-	for i, event := range events {
-		resp[i] = &pb.CreateBatchResponse_URL{
-			CorrelationId: event.CorrelationID,
-			ShortUrl:      models.PrepareFullURL(event.ShortKey, ""),
+	batchStatus := "ok"
+	if len(events) > 0 {
+		// Конфликт в одном элементе не прерывает сохранение остальных
+		err := models.SaveBatchWithShortKeys(ctx, us.KeyGenerator, us.URLRepository, events)
+		if batchSaveFailed(err) {
+			zap.L().Error(err.Error())
+			return nil, repositoryStatus(err)
+		}
+		recordCreated(ctx, metrics.TransportGRPC, events, err)
+
+		// заполняем модель ответа
+		for j, event := range events {
+			item := resp[positions[j]]
+			item.Status, item.Error = linkStatus(models.SaveErrorAt(err, j))
+
+			if item.Status != models.LinkStatusInvalid {
+				item.ShortUrl = models.PrepareFullURL(event.ShortKey, "")
+			}
+			if item.Status == models.LinkStatusCreated {
+				batchStatus = "created"
+			}
 		}
 	}
 
-	// заполняем модель ответа
 	return &pb.CreateBatchResponse{
-		Status: status,
+		Status: batchStatus,
 		Urls:   resp,
 	}, nil
 }
 
-func (us *URLShortenerGRPC) Redirect(_ context.Context, req *pb.RedirectRequest) (*pb.RedirectResponse, error) {
+// ImportURLs handler for bulk import of links.
+// A rejected message doesn't fail the whole import. The result of every message is sent back
+// as soon as it is known, so the size of the import isn't limited by the size of one response.
+func (us *URLShortenerGRPC) ImportURLs(stream pb.URL_ImportURLsServer) error {
+	ctx := stream.Context()
+
+	importer := &urlImporter{
+		repository:   us.URLRepository,
+		keyGenerator: us.KeyGenerator,
+		normalizer:   us.URLNormalizer,
+		policy:       us.DestinationPolicy,
+		transport:    metrics.TransportGRPC,
+		// Ссылки можно импортировать без токена, тогда у них нет владельца
+		userID: auth.UserIDFromContext(ctx),
+		report: func(result models.ImportResponse) error {
+			return stream.Send(&pb.ImportURLsResponse{
+				Line:          int32(result.Line),
+				CorrelationId: result.CorrelationID,
+				ShortUrl:      result.ShortURL,
+				Status:        result.Status,
+				Error:         result.Error,
+			})
+		},
+	}
+
+	for line := 1; ; line++ {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		err = importer.Add(ctx, line, models.CreateRequestBatch{
+			CorrelationID: req.CorrelationId,
+			OriginalURL:   req.OriginalUrl,
+			Alias:         req.Alias,
+		})
+		if err != nil {
+			zap.L().Error(err.Error())
+			return repositoryStatus(err)
+		}
+	}
+
+	if err := importer.Flush(ctx); err != nil {
+		zap.L().Error(err.Error())
+		return repositoryStatus(err)
+	}
+
+	return nil
+}
+
+// Redirect handler for get original URL by short link
+func (us *URLShortenerGRPC) Redirect(ctx context.Context, req *pb.RedirectRequest) (*pb.RedirectResponse, error) {
 	if req.ShortUrl == "" {
 		zap.L().Error("empty id")
-		return &pb.RedirectResponse{
-			Status: "bad request",
-		}, nil
+		return nil, status.Error(codes.InvalidArgument, "empty short_url")
 	}
 
 	// Retrieve the original URL from the `urls` map using the shortened key
-	event, found := us.URLRepository.Get(req.ShortUrl)
-	if !found {
-		err := "URL not found"
-		zap.L().Error(err)
-		return &pb.RedirectResponse{
-			Status: "not found",
-		}, nil
+	event, err := us.URLRepository.Get(ctx, req.ShortUrl)
+	if err == nil && event.IsExpired(time.Now()) {
+		err = models.ErrDeleted
+	}
+	if err != nil {
+		zap.L().Error(err.Error())
+		return nil, repositoryStatus(err)
 	}
 
-	if event.DeletedFlag {
-		err := "URL deleted"
-		zap.L().Error(err)
-		return &pb.RedirectResponse{
-			Status: "gone",
-		}, nil
+	// Ссылка могла попасть в список заблокированных после создания
+	if us.DestinationPolicy.CheckOnRedirect() {
+		if err = us.DestinationPolicy.CheckRedirect(ctx, event.OriginalURL); err != nil {
+			zap.L().Error(err.Error())
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
 	}
 
+	metrics.LinksRedirectedTotal.WithLabelValues(metrics.TransportGRPC).Inc()
+	us.trackClick(ctx, req.ShortUrl)
+
 	return &pb.RedirectResponse{
 		Status:      "ok",
 		RedirectUrl: event.OriginalURL,
 	}, nil
 }
 
-func (us *URLShortenerGRPC) GetUserURLs(ctx context.Context, req *pb.GetUserURLsRequest) (*pb.GetUserURLsResponse, error) {
-	if req.UserId == "" {
-		zap.L().Error("empty user_id")
+// trackClick ставит переход в очередь с адресом клиента из соединения и его user-agent из метаданных,
+// у вызова gRPC нет источника перехода
+func (us *URLShortenerGRPC) trackClick(ctx context.Context, shortKey string) {
+	if us.Clicks == nil {
+		return
+	}
+
+	ip := ""
+	if peerIP := interceptors.PeerIP(ctx); peerIP != nil {
+		ip = peerIP.String()
+	}
+
+	userAgent := ""
+	if values := metadata.ValueFromIncomingContext(ctx, "user-agent"); len(values) > 0 {
+		userAgent = values[0]
+	}
+
+	us.Clicks.TrackClick(models.NewClick(shortKey, time.Now(), "", userAgent, ip))
+}
+
+// GetUserURLs handler for get user's short links
+func (us *URLShortenerGRPC) GetUserURLs(ctx context.Context, _ *pb.GetUserURLsRequest) (*pb.GetUserURLsResponse, error) {
+	userID, err := requiredUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := us.URLRepository.GetEventsByUserID(ctx, userID)
+	if err != nil {
+		zap.L().Error(err.Error())
+		return nil, repositoryStatus(err)
+	}
+
+	if len(events) == 0 {
 		return &pb.GetUserURLsResponse{
 			Status: "no content",
 		}, nil
 	}
 
-	events := us.URLRepository.GetEventsByUserID(ctx, req.UserId)
-
 	// заполняем модель ответа
 	resp := make([]*pb.GetUserURLsResponse_URL, len(events))
 
@@ -195,41 +369,79 @@ func (us *URLShortenerGRPC) GetUserURLs(ctx context.Context, req *pb.GetUserURLs
 	}, nil
 }
 
-func (us *URLShortenerGRPC) DeleteBatch(_ context.Context, req *pb.DeleteBatchRequest) (*pb.DeleteBatchResponse, error) {
-	zap.L().Info("delete", zap.Any("req", req))
+// StreamUserURLs handler for streaming user's short links one message per link
+func (us *URLShortenerGRPC) StreamUserURLs(_ *pb.StreamUserURLsRequest, stream pb.URL_StreamUserURLsServer) error {
+	ctx := stream.Context()
+
+	userID, err := requiredUserID(ctx)
+	if err != nil {
+		return err
+	}
 
-	go func() {
-		err := us.URLRepository.Delete(context.TODO(), []models.DeleteRequestBatch{
-			{
-				UserID:    req.UserId,
-				ShortKeys: req.Urls,
-			},
+	var sendErr error
+	err = us.URLRepository.IterateEventsByUserID(ctx, userID, func(event *models.Event) error {
+		sendErr = stream.Send(&pb.StreamUserURLsResponse{
+			ShortUrl:    models.PrepareFullURL(event.ShortKey, ""),
+			OriginalUrl: event.OriginalURL,
+			ExpiresAt:   timeToTimestamp(event.ExpiresAt),
 		})
-		if err != nil {
-			zap.L().Error("cannot delete events", zap.String("err", err.Error()))
-		}
-	}()
+
+		return sendErr
+	})
+
+	return streamStatus(err, sendErr)
+}
+
+// ExportAll handler for streaming active links of all users, allowed only from the trusted subnet
+func (us *URLShortenerGRPC) ExportAll(_ *pb.ExportAllRequest, stream pb.URL_ExportAllServer) error {
+	var sendErr error
+	err := us.URLRepository.IterateEvents(stream.Context(), func(event *models.Event) error {
+		sendErr = stream.Send(&pb.ExportAllResponse{
+			ShortUrl:    models.PrepareFullURL(event.ShortKey, ""),
+			OriginalUrl: event.OriginalURL,
+			UserId:      event.UserID,
+			ExpiresAt:   timeToTimestamp(event.ExpiresAt),
+		})
+
+		return sendErr
+	})
+
+	return streamStatus(err, sendErr)
+}
+
+// DeleteBatch handler for delete user's short links
+func (us *URLShortenerGRPC) DeleteBatch(ctx context.Context, req *pb.DeleteBatchRequest) (*pb.DeleteBatchResponse, error) {
+	userID, err := requiredUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(req.Urls) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "empty urls")
+	}
+
+	zap.L().Info("delete", zap.String("user_id", userID), zap.Strings("urls", req.Urls))
+
+	err = us.deleteQueue.Enqueue(ctx, models.DeleteRequestBatch{
+		UserID:    userID,
+		ShortKeys: req.Urls,
+	})
+	if err != nil {
+		zap.L().Error("cannot enqueue delete request", zap.Error(err))
+		return nil, status.Error(codes.Unavailable, "delete queue unavailable")
+	}
 
 	return &pb.DeleteBatchResponse{
 		Status: "accepted",
 	}, nil
 }
 
-func (us *URLShortenerGRPC) GetStats(ctx context.Context, req *pb.GetStatsRequest) (*pb.GetStatsResponse, error) {
-	// Проверяем доступ
-	ip := net.ParseIP(req.IpAddress)
-	if us.subnet == nil || !us.subnet.Contains(ip) {
-		return &pb.GetStatsResponse{
-			Status: "forbidden",
-		}, nil
-	}
-
+// GetStats handler for get service's stats, allowed only from the trusted subnet
+func (us *URLShortenerGRPC) GetStats(ctx context.Context, _ *pb.GetStatsRequest) (*pb.GetStatsResponse, error) {
 	countUser, countURL, err := us.URLRepository.GetStats(ctx)
 	if err != nil {
 		zap.L().Error(err.Error())
-		return &pb.GetStatsResponse{
-			Status: "internal server error",
-		}, nil
+		return nil, repositoryStatus(err)
 	}
 
 	return &pb.GetStatsResponse{
@@ -238,3 +450,124 @@ func (us *URLShortenerGRPC) GetStats(ctx context.Context, req *pb.GetStatsReques
 		Urls:   int32(countURL),
 	}, nil
 }
+
+// GetURLStats handler for get short link's clicks statistic
+func (us *URLShortenerGRPC) GetURLStats(ctx context.Context, req *pb.GetURLStatsRequest) (*pb.GetURLStatsResponse, error) {
+	userID, err := requiredUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.ShortUrl == "" {
+		return nil, status.Error(codes.InvalidArgument, "empty short_url")
+	}
+
+	// Статистика доступна только владельцу ссылки
+	// Статистика удаленной ссылки остается доступной
+	event, err := us.URLRepository.Get(ctx, req.ShortUrl)
+	if err != nil && !errors.Is(err, models.ErrDeleted) {
+		zap.L().Error(err.Error())
+		return nil, repositoryStatus(err)
+	}
+
+	if event.UserID != userID {
+		zap.L().Error("URL not found")
+		return nil, repositoryStatus(models.ErrNotFound)
+	}
+
+	stats, err := us.URLRepository.GetClickStats(ctx, req.ShortUrl)
+	if err != nil {
+		zap.L().Error(err.Error())
+		return nil, repositoryStatus(err)
+	}
+
+	return &pb.GetURLStatsResponse{
+		Status:     "ok",
+		Total:      int32(stats.Total),
+		ByDay:      clickCountsToProto(stats.ByDay),
+		ByReferrer: clickCountsToProto(stats.ByReferrer),
+		ByIpPrefix: clickCountsToProto(stats.ByIPPrefix),
+	}, nil
+}
+
+// clickCountsToProto convert clicks statistic to response model
+func clickCountsToProto(counts []models.ClickCount) []*pb.GetURLStatsResponse_Count {
+	resp := make([]*pb.GetURLStatsResponse_Count, len(counts))
+	for i, count := range counts {
+		resp[i] = &pb.GetURLStatsResponse_Count{
+			Key:   count.Key,
+			Count: int32(count.Count),
+		}
+	}
+
+	return resp
+}
+
+// repositoryStatus преобразует ошибку хранилища в статус gRPC
+func repositoryStatus(err error) error {
+	switch {
+	case errors.Is(err, models.ErrNotFound):
+		return status.Error(codes.NotFound, "URL not found")
+	case errors.Is(err, models.ErrDeleted):
+		return status.Error(codes.FailedPrecondition, "URL deleted")
+	case errors.Is(err, models.ErrUnavailable):
+		return status.Error(codes.Unavailable, "repository unavailable")
+	default:
+		return status.Error(codes.Internal, "internal server error")
+	}
+}
+
+// timestampToTime convert optional protobuf timestamp
+func timestampToTime(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+
+	result := ts.AsTime()
+
+	return &result
+}
+
+// timeToTimestamp convert optional time to protobuf timestamp
+func timeToTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+
+	return timestamppb.New(*t)
+}
+
+// streamStatus ошибку отправки клиенту возвращаем как есть, ошибку хранилища переводим в код gRPC
+func streamStatus(err error, sendErr error) error {
+	if err == nil || sendErr != nil {
+		return sendErr
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+
+	zap.L().Error(err.Error())
+	return repositoryStatus(err)
+}
+
+// conflictStatus ошибка AlreadyExists с уже существующими ссылками в деталях
+func conflictStatus(resp protoadapt.MessageV1) error {
+	st, err := status.New(codes.AlreadyExists, "URL exist").WithDetails(resp)
+	if err != nil {
+		zap.L().Error("can't attach status details", zap.Error(err))
+		return status.Error(codes.AlreadyExists, "URL exist")
+	}
+
+	return st.Err()
+}
+
+// requiredUserID пользователь из токена, без токена запрос отклоняется
+func requiredUserID(ctx context.Context) (string, error) {
+	userID := auth.UserIDFromContext(ctx)
+	if userID == "" {
+		return "", status.Error(codes.Unauthenticated, auth.ErrTokenMissing.Error())
+	}
+
+	return userID, nil
+}
@@ -3,27 +3,47 @@ package app_test
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/ShukinDmitriy/shortener/internal/app"
+	"github.com/ShukinDmitriy/shortener/internal/auth"
+	"github.com/ShukinDmitriy/shortener/internal/destination"
 	"github.com/ShukinDmitriy/shortener/internal/environments"
+	"github.com/ShukinDmitriy/shortener/internal/interceptors"
 	"github.com/ShukinDmitriy/shortener/internal/models"
 	models2 "github.com/ShukinDmitriy/shortener/mocks/internal_/models"
 	pb "github.com/ShukinDmitriy/shortener/proto"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/pashagolub/pgxmock/v3"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/test/bufconn"
 )
 
 func dialer(shortenerGRPC *app.URLShortenerGRPC) func(context.Context, string) (net.Conn, error) {
 	listener := bufconn.Listen(1024 * 1024)
 
-	server := grpc.NewServer()
+	// Пользователь попадает в контекст так же, как на боевом сервере
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(interceptors.UnaryAuth(interceptors.AuthConfig{
+			AnonymousMethods: app.GRPCAnonymousMethods,
+		})),
+		grpc.StreamInterceptor(interceptors.StreamAuth(interceptors.AuthConfig{
+			AnonymousMethods: app.GRPCAnonymousMethods,
+		})),
+	)
 
 	pb.RegisterURLServer(server, shortenerGRPC)
 
@@ -38,17 +58,42 @@ func dialer(shortenerGRPC *app.URLShortenerGRPC) func(context.Context, string) (
 	}
 }
 
+// withToken добавляет токен в метаданные запроса
+func withToken(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, auth.GetAuthorizationMetadataKey(), "Bearer "+token)
+}
+
+// userToken токен пользователя, подписанный тем же ключом, что и для HTTP
+func userToken(t *testing.T, userID string) string {
+	token := jwt.NewWithClaims(auth.GetSigningMethod(), &auth.Claims{
+		ID: userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	})
+
+	tokenString, err := token.SignedString([]byte(auth.GetJWTSecret()))
+	require.NoError(t, err)
+
+	return tokenString
+}
+
 func TestURLShortenerGRPC_Create(t *testing.T) {
 	type want struct {
 		status   string
+		code     codes.Code
 		response string
 		err      *error
 	}
 	testError := errors.New("test error")
 	tests := []struct {
-		name string
-		want want
-		body string
+		name  string
+		want  want
+		body  string
+		alias string
+		token string
+		// ссылка, которая должна быть сохранена
+		saved string
 	}{
 		{
 			name: "positive test #1",
@@ -58,19 +103,27 @@ func TestURLShortenerGRPC_Create(t *testing.T) {
 			},
 			body: "http://example.com/",
 		},
+		{
+			name: "positive test #2",
+			want: want{
+				status:   "created",
+				response: "http://example.com/",
+			},
+			body:  "HTTP://Example.COM:80/Path?q=1#top",
+			saved: "http://example.com/Path?q=1",
+		},
 		{
 			name: "negative test #1",
 			want: want{
-				status:   "bad request",
-				response: "",
+				code: codes.InvalidArgument,
 			},
 			body: "",
 		},
 		{
 			name: "negative test #2",
 			want: want{
-				status:   "conflict",
-				response: "",
+				code:     codes.AlreadyExists,
+				response: "http://example.com/",
 				err:      &models.ErrURLExist,
 			},
 			body: "http://example.com/",
@@ -78,9 +131,56 @@ func TestURLShortenerGRPC_Create(t *testing.T) {
 		{
 			name: "negative test #3",
 			want: want{
-				status:   "bad request",
-				response: "",
-				err:      &testError,
+				code: codes.Internal,
+				err:  &testError,
+			},
+			body: "http://example.com/",
+		},
+		{
+			name: "negative test #4",
+			want: want{
+				code: codes.AlreadyExists,
+				err:  &models.ErrShortKeyExist,
+			},
+			body:  "http://example.com/",
+			alias: "spring-sale",
+		},
+		{
+			name: "negative test #5",
+			want: want{
+				code: codes.InvalidArgument,
+			},
+			body:  "http://example.com/",
+			alias: "ping",
+		},
+		{
+			name: "negative test #6",
+			want: want{
+				code: codes.Unauthenticated,
+			},
+			body:  "http://example.com/",
+			token: "invalid",
+		},
+		{
+			name: "negative test #7",
+			want: want{
+				code: codes.InvalidArgument,
+			},
+			body: "javascript:alert(1)",
+		},
+		{
+			name: "negative test #8",
+			want: want{
+				code: codes.InvalidArgument,
+			},
+			body: "example.com/path",
+		},
+		{
+			// Сгенерированный ключ занимают при каждой попытке
+			name: "negative test #9",
+			want: want{
+				code: codes.Internal,
+				err:  &models.ErrShortKeyExist,
 			},
 			body: "http://example.com/",
 		},
@@ -90,13 +190,8 @@ func TestURLShortenerGRPC_Create(t *testing.T) {
 	repository := new(models2.URLRepository)
 	mockConn, _ := pgxmock.NewConn()
 	defer mockConn.Close(context.Background())
-	_, subnet, err := net.ParseCIDR("127.0.0.1/24")
-	if err != nil {
-		t.Error(err)
-	}
-	shortenerGRPC := app.NewURLShortenerGRPC(repository, mockConn, subnet)
+	shortenerGRPC := app.NewURLShortenerGRPC(repository, mockConn, nil)
 
-	ctx := context.Background()
 	conn, err := grpc.NewClient(
 		"passthrough://bufnet",
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
@@ -111,23 +206,47 @@ func TestURLShortenerGRPC_Create(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
+			token := test.token
+			if token == "" {
+				token = userToken(t, "testUser")
+			}
+
 			repository.ExpectedCalls = nil
+			repository.EXPECT().Get(
+				mock.Anything,
+				mock.Anything,
+			).Return(models.Event{}, models.ErrNotFound).Maybe()
+			// Владелец ссылки берется из токена
+			saveCall := repository.EXPECT().Save(
+				mock.Anything,
+				mock.MatchedBy(func(events []*models.Event) bool {
+					return len(events) == 1 && events[0].UserID == "testUser" &&
+						(test.saved == "" || events[0].OriginalURL == test.saved)
+				}),
+			)
 			if test.want.err != nil {
-				repository.EXPECT().Save(
-					mock.Anything,
-					mock.Anything,
-				).Return(*test.want.err)
+				saveCall.Return(*test.want.err)
 			} else {
-				repository.EXPECT().Save(
-					mock.Anything,
-					mock.Anything,
-				).Return(nil)
+				saveCall.Return(nil)
 			}
 
-			resp, err := client.Create(ctx, &pb.CreateRequest{
-				UserId:      "testUser",
+			resp, err := client.Create(withToken(context.Background(), token), &pb.CreateRequest{
 				OriginalUrl: test.body,
+				Alias:       test.alias,
 			})
+			if test.want.code != codes.OK {
+				st := status.Convert(err)
+				assert.Equal(t, test.want.code, st.Code())
+
+				// Существующая ссылка передается в деталях ошибки
+				if test.want.response != "" {
+					require.Len(t, st.Details(), 1)
+					detail, ok := st.Details()[0].(*pb.CreateResponse)
+					require.True(t, ok)
+					assert.Contains(t, detail.ResponseUrl, test.want.response)
+				}
+				return
+			}
 			if err != nil {
 				t.Fatalf("gRPC Create failed: %v", err)
 			}
@@ -140,88 +259,151 @@ func TestURLShortenerGRPC_Create(t *testing.T) {
 
 func TestURLShortenerGRPC_CreateBatch(t *testing.T) {
 	type want struct {
-		status string
-		err    *error
+		status   string
+		code     codes.Code
+		statuses []string
 	}
 	testError := errors.New("test error")
+	body := []*pb.CreateBatchRequest_URL{
+		{
+			CorrelationId: "847b5414-7f41-4363-be2a-e316fbfc2b33",
+			OriginalUrl:   "https://practicum.yandex.ru",
+		},
+		{
+			CorrelationId: "022d3f81-2fb5-4fda-bb19-e89bad595b09",
+			OriginalUrl:   "https://yandex.ru",
+		},
+		{
+			CorrelationId: "847b5414-7f41-4363-be2a-e316fbfc2b33",
+			OriginalUrl:   "https://music.yandex.ru",
+		},
+	}
+	// Последняя ссылка с занятым алиасом
+	withAlias := []*pb.CreateBatchRequest_URL{body[0], body[1], {
+		CorrelationId: body[2].CorrelationId,
+		OriginalUrl:   body[2].OriginalUrl,
+		Alias:         "taken-alias",
+	}}
+	// Пакет больше допустимого
+	tooLarge := make([]*pb.CreateBatchRequest_URL, 1001)
+	for i := range tooLarge {
+		tooLarge[i] = &pb.CreateBatchRequest_URL{
+			CorrelationId: fmt.Sprint(i),
+			OriginalUrl:   fmt.Sprintf("https://yandex.ru/%d", i),
+		}
+	}
 	tests := []struct {
-		name string
-		want want
-		body []*pb.CreateBatchRequest_URL
+		name    string
+		want    want
+		body    []*pb.CreateBatchRequest_URL
+		saveErr error
 	}{
 		{
 			name: "positive test #1",
 			want: want{
 				status: "created",
+				statuses: []string{
+					models.LinkStatusCreated,
+					models.LinkStatusCreated,
+					models.LinkStatusCreated,
+				},
+			},
+			body: body,
+		},
+		{
+			name: "positive test #2",
+			want: want{
+				status: "created",
+				statuses: []string{
+					models.LinkStatusInvalid,
+					models.LinkStatusCreated,
+				},
 			},
 			body: []*pb.CreateBatchRequest_URL{
 				{
-					CorrelationId: "847b5414-7f41-4363-be2a-e316fbfc2b33",
+					CorrelationId: "",
 					OriginalUrl:   "https://practicum.yandex.ru",
 				},
 				{
 					CorrelationId: "022d3f81-2fb5-4fda-bb19-e89bad595b09",
 					OriginalUrl:   "https://yandex.ru",
 				},
-				{
-					CorrelationId: "847b5414-7f41-4363-be2a-e316fbfc2b33",
-					OriginalUrl:   "https://music.yandex.ru",
+			},
+		},
+		{
+			name: "positive test #3",
+			want: want{
+				status: "created",
+				statuses: []string{
+					models.LinkStatusCreated,
+					models.LinkStatusExisting,
+					models.LinkStatusInvalid,
 				},
 			},
+			body: withAlias,
+			saveErr: &models.SaveError{Errors: []error{
+				nil,
+				models.ErrURLExist,
+				models.ErrShortKeyExist,
+			}},
 		},
 		{
-			name: "negative test #1",
+			name: "positive test #4",
 			want: want{
-				status: "bad request",
+				status: "ok",
+				statuses: []string{
+					models.LinkStatusExisting,
+					models.LinkStatusExisting,
+					models.LinkStatusExisting,
+				},
 			},
-			body: []*pb.CreateBatchRequest_URL{
-				{
-					CorrelationId: "",
-					OriginalUrl:   "https://practicum.yandex.ru",
+			body: body,
+			saveErr: &models.SaveError{Errors: []error{
+				models.ErrURLExist,
+				models.ErrURLExist,
+				models.ErrURLExist,
+			}},
+		},
+		{
+			// Сгенерированный ключ заняли, ссылка сохраняется под новым
+			name: "positive test #5",
+			want: want{
+				status: "created",
+				statuses: []string{
+					models.LinkStatusCreated,
+					models.LinkStatusCreated,
+					models.LinkStatusCreated,
 				},
 			},
+			body: body,
+			saveErr: &models.SaveError{Errors: []error{
+				nil,
+				nil,
+				models.ErrShortKeyExist,
+			}},
 		},
 		{
-			name: "negative test #2",
+			name: "negative test #1",
 			want: want{
-				status: "conflict",
-				err:    &models.ErrURLExist,
+				code: codes.Unavailable,
 			},
-			body: []*pb.CreateBatchRequest_URL{
-				{
-					CorrelationId: "847b5414-7f41-4363-be2a-e316fbfc2b33",
-					OriginalUrl:   "https://practicum.yandex.ru",
-				},
-				{
-					CorrelationId: "022d3f81-2fb5-4fda-bb19-e89bad595b09",
-					OriginalUrl:   "https://yandex.ru",
-				},
-				{
-					CorrelationId: "847b5414-7f41-4363-be2a-e316fbfc2b33",
-					OriginalUrl:   "https://music.yandex.ru",
-				},
+			body:    body,
+			saveErr: fmt.Errorf("%w: connection refused", models.ErrUnavailable),
+		},
+		{
+			name: "negative test #2",
+			want: want{
+				code: codes.Internal,
 			},
+			body:    body,
+			saveErr: testError,
 		},
 		{
 			name: "negative test #3",
 			want: want{
-				status: "bad request",
-				err:    &testError,
-			},
-			body: []*pb.CreateBatchRequest_URL{
-				{
-					CorrelationId: "847b5414-7f41-4363-be2a-e316fbfc2b33",
-					OriginalUrl:   "https://practicum.yandex.ru",
-				},
-				{
-					CorrelationId: "022d3f81-2fb5-4fda-bb19-e89bad595b09",
-					OriginalUrl:   "https://yandex.ru",
-				},
-				{
-					CorrelationId: "847b5414-7f41-4363-be2a-e316fbfc2b33",
-					OriginalUrl:   "https://music.yandex.ru",
-				},
+				code: codes.InvalidArgument,
 			},
+			body: tooLarge,
 		},
 	}
 
@@ -229,11 +411,7 @@ func TestURLShortenerGRPC_CreateBatch(t *testing.T) {
 	repository := new(models2.URLRepository)
 	mockConn, _ := pgxmock.NewConn()
 	defer mockConn.Close(context.Background())
-	_, subnet, err := net.ParseCIDR("127.0.0.1/24")
-	if err != nil {
-		t.Error(err)
-	}
-	shortenerGRPC := app.NewURLShortenerGRPC(repository, mockConn, subnet)
+	shortenerGRPC := app.NewURLShortenerGRPC(repository, mockConn, nil)
 
 	ctx := context.Background()
 	conn, err := grpc.NewClient(
@@ -251,39 +429,72 @@ func TestURLShortenerGRPC_CreateBatch(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			repository.ExpectedCalls = nil
-			if test.want.err != nil {
-				repository.EXPECT().Save(
-					mock.Anything,
-					mock.Anything,
-				).Return(*test.want.err)
-			} else {
-				repository.EXPECT().Save(
-					mock.Anything,
-					mock.Anything,
-				).Return(nil)
-			}
+			repository.EXPECT().Get(
+				mock.Anything,
+				mock.Anything,
+			).Return(models.Event{}, models.ErrNotFound).Maybe()
+			repository.EXPECT().Save(
+				mock.Anything,
+				mock.Anything,
+			).Return(test.saveErr)
 
+			// Пакет можно создать и без токена
 			resp, err := client.CreateBatch(ctx, &pb.CreateBatchRequest{
-				UserId: "testUser",
-				Urls:   test.body,
+				Urls: test.body,
 			})
+			if test.want.code != codes.OK {
+				assert.Equal(t, test.want.code, status.Code(err))
+				return
+			}
 			if err != nil {
 				t.Fatalf("gRPC CreateBatch failed: %v", err)
 			}
 
 			assert.Equal(t, test.want.status, resp.Status)
-			if test.want.status == "created" {
-				assert.Equal(t, len(test.body), len(resp.Urls))
+			require.Equal(t, len(test.body), len(resp.Urls))
+
+			// Результат по каждой ссылке в порядке запроса
+			for i, item := range resp.Urls {
+				assert.Equal(t, test.body[i].CorrelationId, item.CorrelationId)
+				assert.Equal(t, test.want.statuses[i], item.Status)
+				assert.Equal(t, item.Status == models.LinkStatusInvalid, item.ShortUrl == "")
+				assert.Equal(t, item.Status == models.LinkStatusInvalid, item.Error != "")
 			}
 		})
 	}
 }
 
+// recordingClickTracker запоминает переходы вместо сохранения
+type recordingClickTracker struct {
+	mutex  sync.Mutex
+	clicks []*models.Click
+}
+
+func (r *recordingClickTracker) TrackClick(click *models.Click) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.clicks = append(r.clicks, click)
+}
+
+// take забирает запомненные переходы
+func (r *recordingClickTracker) take() []*models.Click {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	clicks := r.clicks
+	r.clicks = nil
+
+	return clicks
+}
+
 func TestURLShortenerGRPC_Redirect(t *testing.T) {
 	type want struct {
 		status string
+		code   codes.Code
 		event  models.Event
 	}
+	expiredAt := time.Now().Add(-time.Minute)
 	tests := []struct {
 		name string
 		want want
@@ -303,21 +514,21 @@ func TestURLShortenerGRPC_Redirect(t *testing.T) {
 		{
 			name: "negative test #1",
 			want: want{
-				status: "bad request",
+				code: codes.InvalidArgument,
 			},
 			body: "",
 		},
 		{
 			name: "negative test #2",
 			want: want{
-				status: "not found",
+				code: codes.NotFound,
 			},
 			body: "http://example.com/",
 		},
 		{
 			name: "negative test #3",
 			want: want{
-				status: "gone",
+				code: codes.FailedPrecondition,
 				event: models.Event{
 					DeletedFlag: true,
 					OriginalURL: "http://example.com/",
@@ -325,23 +536,33 @@ func TestURLShortenerGRPC_Redirect(t *testing.T) {
 			},
 			body: "http://example.com/",
 		},
+		{
+			name: "negative test #4",
+			want: want{
+				code: codes.FailedPrecondition,
+				event: models.Event{
+					OriginalURL: "http://example.com/",
+					ExpiresAt:   &expiredAt,
+				},
+			},
+			body: "http://example.com/",
+		},
 	}
 
 	environments.BaseAddr = "http://example.com"
 	repository := new(models2.URLRepository)
 	mockConn, _ := pgxmock.NewConn()
 	defer mockConn.Close(context.Background())
-	_, subnet, err := net.ParseCIDR("127.0.0.1/24")
-	if err != nil {
-		t.Error(err)
-	}
-	shortenerGRPC := app.NewURLShortenerGRPC(repository, mockConn, subnet)
+	shortenerGRPC := app.NewURLShortenerGRPC(repository, mockConn, nil)
+	clicks := &recordingClickTracker{}
+	shortenerGRPC.Clicks = clicks
 
 	ctx := context.Background()
 	conn, err := grpc.NewClient(
 		"passthrough://bufnet",
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
 		grpc.WithContextDialer(dialer(shortenerGRPC)),
+		grpc.WithUserAgent("test-agent"),
 	)
 	if err != nil {
 		t.Fatalf("Failed to dial bufnet: %v", err)
@@ -352,35 +573,119 @@ func TestURLShortenerGRPC_Redirect(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
+			var getErr error
+			if test.want.event.OriginalURL == "" {
+				getErr = models.ErrNotFound
+			} else if test.want.event.DeletedFlag {
+				getErr = models.ErrDeleted
+			}
+
 			repository.ExpectedCalls = nil
 			repository.EXPECT().Get(
 				mock.Anything,
-			).Return(test.want.event, test.want.event.OriginalURL != "")
+				mock.Anything,
+			).Return(test.want.event, getErr)
 
 			resp, err := client.Redirect(ctx, &pb.RedirectRequest{
 				ShortUrl: test.body,
 			})
+			if test.want.code != codes.OK {
+				assert.Equal(t, test.want.code, status.Code(err))
+				assert.Empty(t, clicks.take())
+				return
+			}
 			if err != nil {
 				t.Fatalf("gRPC Redirect failed: %v", err)
 			}
 
 			assert.Equal(t, test.want.status, resp.Status)
-			if resp.Status == "ok" {
-				assert.Equal(t, test.want.event.OriginalURL, resp.RedirectUrl)
-			}
+			assert.Equal(t, test.want.event.OriginalURL, resp.RedirectUrl)
+
+			// Переход учитывается так же, как редирект по HTTP
+			recorded := clicks.take()
+			require.Len(t, recorded, 1)
+			assert.Equal(t, test.body, recorded[0].ShortKey)
+			assert.Contains(t, recorded[0].UserAgent, "test-agent")
 		})
 	}
 }
 
+func TestURLShortenerGRPC_DestinationPolicy(t *testing.T) {
+	environments.BaseAddr = "http://example.com"
+	repository := new(models2.URLRepository)
+	mockConn, _ := pgxmock.NewConn()
+	defer mockConn.Close(context.Background())
+	shortenerGRPC := app.NewURLShortenerGRPC(repository, mockConn, nil)
+	shortenerGRPC.DestinationPolicy = destination.NewPolicy(destination.Options{
+		BlockPrivate:    true,
+		CheckOnRedirect: true,
+	})
+	shortenerGRPC.DestinationPolicy.SetRules(destination.Rules{Blocklist: []string{"phishing.test"}})
+
+	conn, err := grpc.NewClient(
+		"passthrough://bufnet",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(dialer(shortenerGRPC)),
+	)
+	if err != nil {
+		t.Fatalf("Failed to dial bufnet: %v", err)
+	}
+	defer conn.Close()
+
+	client := pb.NewURLClient(conn)
+	ctx := withToken(context.Background(), userToken(t, "testUser"))
+
+	tests := []struct {
+		name string
+		url  string
+		code codes.Code
+	}{
+		{
+			name: "negative test #1",
+			url:  "https://phishing.test/login",
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "negative test #2",
+			url:  "http://169.254.169.254/latest/meta-data",
+			code: codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.Create(ctx, &pb.CreateRequest{OriginalUrl: tt.url})
+			assert.Equal(t, tt.code, status.Code(err))
+
+			resp, err := client.CreateBatch(ctx, &pb.CreateBatchRequest{
+				Urls: []*pb.CreateBatchRequest_URL{{CorrelationId: "1", OriginalUrl: tt.url}},
+			})
+			require.NoError(t, err)
+			require.Len(t, resp.Urls, 1)
+			assert.Equal(t, models.LinkStatusInvalid, resp.Urls[0].Status)
+		})
+	}
+
+	t.Run("redirect", func(t *testing.T) {
+		repository.ExpectedCalls = nil
+		repository.EXPECT().Get(mock.Anything, "phished").
+			Return(models.Event{ShortKey: "phished", OriginalURL: "https://phishing.test/"}, nil)
+
+		_, err := client.Redirect(ctx, &pb.RedirectRequest{ShortUrl: "phished"})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+}
+
 func TestURLShortenerGRPC_GetUserURLs(t *testing.T) {
 	type want struct {
 		status string
+		code   codes.Code
 		events []*models.Event
 	}
 	tests := []struct {
-		name string
-		want want
-		body string
+		name   string
+		want   want
+		userID string
 	}{
 		{
 			name: "positive test #1",
@@ -393,15 +698,22 @@ func TestURLShortenerGRPC_GetUserURLs(t *testing.T) {
 					},
 				},
 			},
-			body: "testUserID",
+			userID: "testUserID",
 		},
 		{
-			name: "negative test #1",
+			name: "positive test #2",
 			want: want{
 				status: "no content",
 				events: []*models.Event{},
 			},
-			body: "",
+			userID: "testUserID",
+		},
+		{
+			name: "negative test #1",
+			want: want{
+				code: codes.Unauthenticated,
+			},
+			userID: "",
 		},
 	}
 
@@ -409,13 +721,8 @@ func TestURLShortenerGRPC_GetUserURLs(t *testing.T) {
 	repository := new(models2.URLRepository)
 	mockConn, _ := pgxmock.NewConn()
 	defer mockConn.Close(context.Background())
-	_, subnet, err := net.ParseCIDR("127.0.0.1/24")
-	if err != nil {
-		t.Error(err)
-	}
-	shortenerGRPC := app.NewURLShortenerGRPC(repository, mockConn, subnet)
+	shortenerGRPC := app.NewURLShortenerGRPC(repository, mockConn, nil)
 
-	ctx := context.Background()
 	conn, err := grpc.NewClient(
 		"passthrough://bufnet",
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
@@ -430,43 +737,238 @@ func TestURLShortenerGRPC_GetUserURLs(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			if test.userID != "" {
+				ctx = withToken(ctx, userToken(t, test.userID))
+			}
+
 			repository.ExpectedCalls = nil
 			repository.EXPECT().GetEventsByUserID(
 				mock.Anything,
-				mock.Anything,
-			).Return(test.want.events)
+				test.userID,
+			).Return(test.want.events, nil)
 
+			// Идентификатор из запроса не позволяет действовать от имени другого пользователя
 			resp, err := client.GetUserURLs(ctx, &pb.GetUserURLsRequest{
-				UserId: test.body,
+				UserId: "otherUserID",
 			})
+			if test.want.code != codes.OK {
+				assert.Equal(t, test.want.code, status.Code(err))
+				return
+			}
 			if err != nil {
 				t.Fatalf("gRPC GetUserURLs failed: %v", err)
 			}
 
 			assert.Equal(t, test.want.status, resp.Status)
-			if resp.Status == "ok" {
-				assert.Equal(t, len(test.want.events), len(resp.Urls))
+			assert.Equal(t, len(test.want.events), len(resp.Urls))
+		})
+	}
+}
+
+// iterate передает ссылки получателю и возвращает ошибку хранилища после них
+func iterate(events []*models.Event, err error) func(models.EventIterator) error {
+	return func(fn models.EventIterator) error {
+		for _, event := range events {
+			if fnErr := fn(event); fnErr != nil {
+				return fnErr
 			}
+		}
+
+		return err
+	}
+}
+
+// receiveAll вычитывает поток до конца и возвращает количество сообщений
+func receiveAll[T any](stream grpc.ServerStreamingClient[T]) (int, error) {
+	count := 0
+	for {
+		_, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return count, nil
+		}
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+}
+
+func TestURLShortenerGRPC_StreamUserURLs(t *testing.T) {
+	type want struct {
+		code  codes.Code
+		count int
+	}
+	expiresAt := time.Now().Add(time.Hour)
+	events := []*models.Event{
+		{
+			ShortKey:    "short1",
+			OriginalURL: "http://example.com/1",
+			ExpiresAt:   &expiresAt,
+		},
+		{
+			ShortKey:    "short2",
+			OriginalURL: "http://example.com/2",
+		},
+	}
+	tests := []struct {
+		name   string
+		want   want
+		userID string
+		err    error
+	}{
+		{
+			name: "positive test #1",
+			want: want{
+				count: 2,
+			},
+			userID: "testUserID",
+		},
+		{
+			name: "negative test #1",
+			want: want{
+				code: codes.Unauthenticated,
+			},
+			userID: "",
+		},
+		{
+			name: "negative test #2",
+			want: want{
+				code:  codes.Unavailable,
+				count: 2,
+			},
+			userID: "testUserID",
+			err:    fmt.Errorf("%w: connection reset", models.ErrUnavailable),
+		},
+	}
+
+	environments.BaseAddr = "http://example.com"
+	repository := new(models2.URLRepository)
+	mockConn, _ := pgxmock.NewConn()
+	defer mockConn.Close(context.Background())
+	shortenerGRPC := app.NewURLShortenerGRPC(repository, mockConn, nil)
+
+	conn, err := grpc.NewClient(
+		"passthrough://bufnet",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(dialer(shortenerGRPC)),
+	)
+	if err != nil {
+		t.Fatalf("Failed to dial bufnet: %v", err)
+	}
+	defer conn.Close()
+
+	client := pb.NewURLClient(conn)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			if test.userID != "" {
+				ctx = withToken(ctx, userToken(t, test.userID))
+			}
+
+			repository.ExpectedCalls = nil
+			repository.EXPECT().IterateEventsByUserID(
+				mock.Anything,
+				test.userID,
+				mock.Anything,
+			).RunAndReturn(func(_ context.Context, _ string, fn models.EventIterator) error {
+				return iterate(events, test.err)(fn)
+			})
+
+			stream, err := client.StreamUserURLs(ctx, &pb.StreamUserURLsRequest{})
+			require.NoError(t, err)
+
+			// Ссылки, прочитанные до сбоя, уже получены клиентом
+			count, err := receiveAll(stream)
+			assert.Equal(t, test.want.code, status.Code(err))
+			assert.Equal(t, test.want.count, count)
 		})
 	}
 }
 
+func TestURLShortenerGRPC_ExportAll(t *testing.T) {
+	environments.BaseAddr = "http://example.com"
+	repository := new(models2.URLRepository)
+	mockConn, _ := pgxmock.NewConn()
+	defer mockConn.Close(context.Background())
+	shortenerGRPC := app.NewURLShortenerGRPC(repository, mockConn, nil)
+
+	conn, err := grpc.NewClient(
+		"passthrough://bufnet",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(dialer(shortenerGRPC)),
+	)
+	if err != nil {
+		t.Fatalf("Failed to dial bufnet: %v", err)
+	}
+	defer conn.Close()
+
+	client := pb.NewURLClient(conn)
+
+	repository.EXPECT().IterateEvents(
+		mock.Anything,
+		mock.Anything,
+	).RunAndReturn(func(_ context.Context, fn models.EventIterator) error {
+		return iterate([]*models.Event{
+			{
+				ShortKey:    "short1",
+				OriginalURL: "http://example.com/1",
+				UserID:      "user1",
+			},
+			{
+				ShortKey:    "short2",
+				OriginalURL: "http://example.com/2",
+			},
+		}, nil)(fn)
+	})
+
+	stream, err := client.ExportAll(context.Background(), &pb.ExportAllRequest{})
+	require.NoError(t, err)
+
+	first, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "http://example.com/short1", first.ShortUrl)
+	assert.Equal(t, "user1", first.UserId)
+	assert.Nil(t, first.ExpiresAt)
+
+	count, err := receiveAll(stream)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
 func TestURLShortenerGRPC_DeleteBatch(t *testing.T) {
 	type want struct {
 		status string
-		events []string
+		code   codes.Code
 	}
 	tests := []struct {
-		name string
-		want want
-		body string
+		name   string
+		want   want
+		userID string
+		urls   []string
 	}{
 		{
 			name: "positive test #1",
 			want: want{
 				status: "accepted",
-				events: []string{"SYqDJ3", "4SwGPJ", "z3e7av"},
 			},
+			userID: "testUserID",
+			urls:   []string{"SYqDJ3", "4SwGPJ", "z3e7av"},
+		},
+		{
+			name: "negative test #1",
+			want: want{
+				code: codes.Unauthenticated,
+			},
+			urls: []string{"SYqDJ3"},
+		},
+		{
+			name: "negative test #2",
+			want: want{
+				code: codes.InvalidArgument,
+			},
+			userID: "testUserID",
 		},
 	}
 
@@ -474,13 +976,8 @@ func TestURLShortenerGRPC_DeleteBatch(t *testing.T) {
 	repository := new(models2.URLRepository)
 	mockConn, _ := pgxmock.NewConn()
 	defer mockConn.Close(context.Background())
-	_, subnet, err := net.ParseCIDR("127.0.0.1/24")
-	if err != nil {
-		t.Error(err)
-	}
-	shortenerGRPC := app.NewURLShortenerGRPC(repository, mockConn, subnet)
+	shortenerGRPC := app.NewURLShortenerGRPC(repository, mockConn, nil)
 
-	ctx := context.Background()
 	conn, err := grpc.NewClient(
 		"passthrough://bufnet",
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
@@ -495,21 +992,43 @@ func TestURLShortenerGRPC_DeleteBatch(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			if test.userID != "" {
+				ctx = withToken(ctx, userToken(t, test.userID))
+			}
+
+			deleted := make(chan []models.DeleteRequestBatch, 1)
 			repository.ExpectedCalls = nil
 			repository.EXPECT().Delete(
 				mock.Anything,
 				mock.Anything,
-			).Return(nil)
+			).Run(func(_ context.Context, events []models.DeleteRequestBatch) {
+				deleted <- events
+			}).Return(nil).Maybe()
 
 			resp, err := client.DeleteBatch(ctx, &pb.DeleteBatchRequest{
-				UserId: "testUserID",
-				Urls:   test.want.events,
+				UserId: "otherUserID",
+				Urls:   test.urls,
 			})
+			if test.want.code != codes.OK {
+				assert.Equal(t, test.want.code, status.Code(err))
+				return
+			}
 			if err != nil {
 				t.Fatalf("gRPC DeleteBatch failed: %v", err)
 			}
 
 			assert.Equal(t, test.want.status, resp.Status)
+
+			// Удаляются ссылки пользователя из токена, очередь отправляет их не сразу
+			select {
+			case events := <-deleted:
+				require.Len(t, events, 1)
+				assert.Equal(t, test.userID, events[0].UserID)
+				assert.Equal(t, test.urls, events[0].ShortKeys)
+			case <-time.After(5 * time.Second):
+				t.Fatal("Delete wasn't called")
+			}
 		})
 	}
 }
@@ -517,15 +1036,15 @@ func TestURLShortenerGRPC_DeleteBatch(t *testing.T) {
 func TestURLShortenerGRPC_GetStats(t *testing.T) {
 	type want struct {
 		status    string
+		code      codes.Code
 		countUser int
 		countURL  int
 		err       *error
 	}
 	testError := errors.New("test error")
 	tests := []struct {
-		name      string
-		want      want
-		ipAddress string
+		name string
+		want want
 	}{
 		{
 			name: "positive test #1",
@@ -534,24 +1053,15 @@ func TestURLShortenerGRPC_GetStats(t *testing.T) {
 				countUser: 10,
 				countURL:  158,
 			},
-			ipAddress: "127.0.0.1",
 		},
 		{
 			name: "negative test #1",
 			want: want{
-				status: "forbidden",
-			},
-			ipAddress: "192.168.0.1",
-		},
-		{
-			name: "negative test #2",
-			want: want{
-				status:    "internal server error",
+				code:      codes.Internal,
 				countUser: 10,
 				countURL:  158,
 				err:       &testError,
 			},
-			ipAddress: "127.0.0.1",
 		},
 	}
 
@@ -559,11 +1069,7 @@ func TestURLShortenerGRPC_GetStats(t *testing.T) {
 	repository := new(models2.URLRepository)
 	mockConn, _ := pgxmock.NewConn()
 	defer mockConn.Close(context.Background())
-	_, subnet, err := net.ParseCIDR("127.0.0.1/24")
-	if err != nil {
-		t.Error(err)
-	}
-	shortenerGRPC := app.NewURLShortenerGRPC(repository, mockConn, subnet)
+	shortenerGRPC := app.NewURLShortenerGRPC(repository, mockConn, nil)
 
 	ctx := context.Background()
 	conn, err := grpc.NewClient(
@@ -591,18 +1097,138 @@ func TestURLShortenerGRPC_GetStats(t *testing.T) {
 				).Return(test.want.countUser, test.want.countURL, nil)
 			}
 
-			resp, err := client.GetStats(ctx, &pb.GetStatsRequest{
-				IpAddress: test.ipAddress,
-			})
+			// Доступ по подсети проверяет перехватчик, см. пакет interceptors
+			resp, err := client.GetStats(ctx, &pb.GetStatsRequest{})
+			if test.want.code != codes.OK {
+				assert.Equal(t, test.want.code, status.Code(err))
+				return
+			}
 			if err != nil {
 				t.Fatalf("gRPC GetStats failed: %v", err)
 			}
 
 			assert.Equal(t, test.want.status, resp.Status)
-			if resp.Status == "ok" {
-				assert.Equal(t, int32(test.want.countURL), resp.Urls)
-				assert.Equal(t, int32(test.want.countUser), resp.Users)
+			assert.Equal(t, int32(test.want.countURL), resp.Urls)
+			assert.Equal(t, int32(test.want.countUser), resp.Users)
+		})
+	}
+}
+
+func TestURLShortenerGRPC_GetURLStats(t *testing.T) {
+	type want struct {
+		status string
+		code   codes.Code
+		total  int32
+	}
+	tests := []struct {
+		name   string
+		want   want
+		userID string
+		event  models.Event
+		err    error
+	}{
+		{
+			name: "positive test #1",
+			want: want{
+				status: "ok",
+				total:  2,
+			},
+			userID: "testUserID",
+			event: models.Event{
+				OriginalURL: "http://example.com/",
+				UserID:      "testUserID",
+			},
+		},
+		{
+			name: "negative test #1",
+			want: want{
+				code: codes.NotFound,
+			},
+			userID: "otherUserID",
+			event: models.Event{
+				OriginalURL: "http://example.com/",
+				UserID:      "testUserID",
+			},
+		},
+		{
+			name: "negative test #2",
+			want: want{
+				code: codes.Internal,
+			},
+			userID: "testUserID",
+			event: models.Event{
+				OriginalURL: "http://example.com/",
+				UserID:      "testUserID",
+			},
+			err: errors.New("test error"),
+		},
+		{
+			name: "negative test #3",
+			want: want{
+				code: codes.Unauthenticated,
+			},
+			event: models.Event{
+				OriginalURL: "http://example.com/",
+				UserID:      "testUserID",
+			},
+		},
+	}
+
+	environments.BaseAddr = "http://example.com"
+	repository := new(models2.URLRepository)
+	mockConn, _ := pgxmock.NewConn()
+	defer mockConn.Close(context.Background())
+	shortenerGRPC := app.NewURLShortenerGRPC(repository, mockConn, nil)
+
+	conn, err := grpc.NewClient(
+		"passthrough://bufnet",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(dialer(shortenerGRPC)),
+	)
+	if err != nil {
+		t.Fatalf("Failed to dial bufnet: %v", err)
+	}
+	defer conn.Close()
+
+	client := pb.NewURLClient(conn)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			if test.userID != "" {
+				ctx = withToken(ctx, userToken(t, test.userID))
 			}
+
+			repository.ExpectedCalls = nil
+			repository.EXPECT().Get(
+				mock.Anything,
+				mock.Anything,
+			).Return(test.event, nil)
+			repository.EXPECT().GetClickStats(
+				mock.Anything,
+				mock.Anything,
+			).Return(&models.ClickStats{
+				Total: 2,
+				ByReferrer: []models.ClickCount{
+					{Key: "https://google.com", Count: 2},
+				},
+			}, test.err)
+
+			resp, err := client.GetURLStats(ctx, &pb.GetURLStatsRequest{
+				UserId:   "testUserID",
+				ShortUrl: "short1",
+			})
+			if test.want.code != codes.OK {
+				assert.Equal(t, test.want.code, status.Code(err))
+				return
+			}
+			if err != nil {
+				t.Fatalf("gRPC GetURLStats failed: %v", err)
+			}
+
+			assert.Equal(t, test.want.status, resp.Status)
+			assert.Equal(t, test.want.total, resp.Total)
+			assert.Len(t, resp.ByReferrer, 1)
 		})
 	}
 }
@@ -4,10 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -15,12 +17,14 @@ import (
 	"github.com/ShukinDmitriy/shortener/internal/environments"
 
 	"github.com/ShukinDmitriy/shortener/internal/app"
+	"github.com/ShukinDmitriy/shortener/internal/destination"
 	"github.com/ShukinDmitriy/shortener/internal/models"
 	"github.com/ShukinDmitriy/shortener/mocks/internal_/auth"
 	models2 "github.com/ShukinDmitriy/shortener/mocks/internal_/models"
 	"github.com/labstack/echo/v4"
 	"github.com/pashagolub/pgxmock/v3"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
 
@@ -44,6 +48,16 @@ func TestURLShortener_HandleShorten(t *testing.T) {
 			},
 			body: "https://yandex.ru",
 		},
+		{
+			// Та же ссылка после нормализации
+			name: "positive test #2",
+			want: want{
+				code:        409,
+				response:    "http://example.com/",
+				contentType: "text/plain; charset=UTF-8",
+			},
+			body: " HTTPS://Yandex.RU:443#top\n",
+		},
 		{
 			name: "negative test #1",
 			want: want{
@@ -53,15 +67,39 @@ func TestURLShortener_HandleShorten(t *testing.T) {
 			},
 			body: "",
 		},
+		{
+			name: "negative test #2",
+			want: want{
+				code:     400,
+				response: `scheme "javascript" is not allowed`,
+			},
+			body: "javascript:alert(1)",
+		},
+		{
+			name: "negative test #3",
+			want: want{
+				code:     400,
+				response: "scheme is missing",
+			},
+			body: "/relative/path",
+		},
+		{
+			name: "negative test #4",
+			want: want{
+				code:     400,
+				response: "empty url",
+			},
+			body: "   ",
+		},
 	}
 
 	repository := &models.MemoryURLRepository{}
 	configuration := environments.Configuration{}
-	err := repository.Initialize(configuration)
+	err := repository.Initialize(context.TODO(), configuration)
 	require.NoError(t, err)
 	authService := new(auth.AuthServiceInterface)
 
-	shortener := app.NewURLShortener(repository, nil, authService, nil)
+	shortener := app.NewURLShortener(repository, nil, authService, nil, nil)
 
 	e := echo.New()
 
@@ -146,15 +184,51 @@ func TestURLShortener_HandleCreateShorten(t *testing.T) {
 			},
 			body: `{"test" "test"}`,
 		},
+		{
+			name: "positive test #2",
+			want: want{
+				code:        201,
+				response:    "http://example.com/spring-sale",
+				contentType: "application/json; charset=UTF-8",
+			},
+			body: `{"url":"https://yandex.ru/sale","alias":"spring-sale"}`,
+		},
+		{
+			name: "negative test #4",
+			want: want{
+				code:        409,
+				response:    "alias already exists",
+				contentType: "application/json; charset=UTF-8",
+			},
+			body: `{"url":"https://yandex.ru/other-sale","alias":"spring-sale"}`,
+		},
+		{
+			name: "negative test #5",
+			want: want{
+				code:        400,
+				response:    "alias is reserved",
+				contentType: "application/json; charset=UTF-8",
+			},
+			body: `{"url":"https://yandex.ru/api","alias":"api"}`,
+		},
+		{
+			name: "negative test #6",
+			want: want{
+				code:        400,
+				response:    "alias is invalid",
+				contentType: "application/json; charset=UTF-8",
+			},
+			body: `{"url":"https://yandex.ru/invalid","alias":"spring sale"}`,
+		},
 	}
 
 	repository := &models.MemoryURLRepository{}
 	configuration := environments.Configuration{}
-	err := repository.Initialize(configuration)
+	err := repository.Initialize(context.TODO(), configuration)
 	require.NoError(t, err)
 	authService := new(auth.AuthServiceInterface)
 
-	shortener := app.NewURLShortener(repository, nil, authService, nil)
+	shortener := app.NewURLShortener(repository, nil, authService, nil, nil)
 
 	e := echo.New()
 
@@ -197,11 +271,78 @@ func TestURLShortener_HandleCreateShorten(t *testing.T) {
 	}
 }
 
+// collidingURLRepository хранилище, в котором сгенерированный ключ занимают между проверкой и сохранением
+type collidingURLRepository struct {
+	*models.MemoryURLRepository
+	collisions int
+}
+
+func (r *collidingURLRepository) Save(ctx context.Context, events []*models.Event) error {
+	if r.collisions > 0 {
+		r.collisions--
+		return &models.SaveError{Errors: []error{models.ErrShortKeyExist}}
+	}
+
+	return r.MemoryURLRepository.Save(ctx, events)
+}
+
+func TestURLShortener_HandleCreateShortenCollision(t *testing.T) {
+	memory := &models.MemoryURLRepository{}
+	require.NoError(t, memory.Initialize(context.TODO(), environments.Configuration{}))
+	repository := &collidingURLRepository{MemoryURLRepository: memory, collisions: 1}
+	authService := new(auth.AuthServiceInterface)
+
+	shortener := app.NewURLShortener(repository, nil, authService, nil, nil)
+	shortener.KeyGenerator = models.NewSequentialKeyGenerator(models.DefaultKeyAlphabet, models.DefaultKeyLength, 0)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(`{"url":"https://yandex.ru/race"}`))
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	authService.EXPECT().GetUserID(c).Return("")
+
+	// Первый ключ заняли, ссылка сохраняется под следующим
+	require.NoError(t, shortener.HandleCreateShorten(c))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Contains(t, rec.Body.String(), "/aaaaac")
+
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("https://yandex.ru/race-text"))
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+	authService.EXPECT().GetUserID(c).Return("")
+	repository.collisions = 1
+
+	require.NoError(t, shortener.HandleShorten(c))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Contains(t, rec.Body.String(), "/aaaaae")
+
+	// В пакете ключ тоже генерируется заново, а занятым остается только алиас
+	req = httptest.NewRequest(http.MethodPost, "/api/shorten/batch", strings.NewReader(
+		`[{"correlation_id":"1","original_url":"https://yandex.ru/race-batch"},`+
+			`{"correlation_id":"2","original_url":"https://yandex.ru/race-alias","alias":"aaaaac"}]`,
+	))
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+	authService.EXPECT().GetUserID(c).Return("")
+	repository.collisions = 1
+
+	require.NoError(t, shortener.HandleCreateShortenBatch(c))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	var data []models.CreateResponseBatch
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &data))
+	require.Len(t, data, 2)
+	assert.Equal(t, models.LinkStatusCreated, data[0].Status)
+	assert.Equal(t, models.LinkStatusInvalid, data[1].Status)
+	assert.Equal(t, "alias already exists", data[1].Error)
+}
+
 func TestURLShortener_HandleCreateShortenBatch(t *testing.T) {
 	type want struct {
 		code        int
 		response    string
 		contentType string
+		statuses    []string
 	}
 	type bodyItem struct {
 		CorrelationID string `json:"correlation_id"`
@@ -217,6 +358,11 @@ func TestURLShortener_HandleCreateShortenBatch(t *testing.T) {
 			want: want{
 				code:        201,
 				contentType: "application/json; charset=UTF-8",
+				statuses: []string{
+					models.LinkStatusCreated,
+					models.LinkStatusCreated,
+					models.LinkStatusCreated,
+				},
 			},
 			body: []bodyItem{
 				{
@@ -233,15 +379,57 @@ func TestURLShortener_HandleCreateShortenBatch(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "positive test #2",
+			want: want{
+				code:        201,
+				contentType: "application/json; charset=UTF-8",
+				statuses: []string{
+					models.LinkStatusExisting,
+					models.LinkStatusInvalid,
+					models.LinkStatusCreated,
+				},
+			},
+			body: []bodyItem{
+				{
+					CorrelationID: "022d3f81-2fb5-4fda-bb19-e89bad595b09",
+					OriginalURL:   "https://yandex.ru",
+				},
+				{
+					CorrelationID: "",
+					OriginalURL:   "https://market.yandex.ru",
+				},
+				{
+					CorrelationID: "5d2b0a7e-2c1f-4d25-9b7e-3f0a1c6d8e41",
+					OriginalURL:   "https://mail.yandex.ru",
+				},
+			},
+		},
+		{
+			name: "positive test #3",
+			want: want{
+				code:        200,
+				contentType: "application/json; charset=UTF-8",
+				statuses: []string{
+					models.LinkStatusExisting,
+				},
+			},
+			body: []bodyItem{
+				{
+					CorrelationID: "847b5414-7f41-4363-be2a-e316fbfc2b33",
+					OriginalURL:   "https://practicum.yandex.ru",
+				},
+			},
+		},
 	}
 
 	repository := &models.MemoryURLRepository{}
 	configuration := environments.Configuration{}
-	err := repository.Initialize(configuration)
+	err := repository.Initialize(context.TODO(), configuration)
 	require.NoError(t, err)
 	authService := new(auth.AuthServiceInterface)
 
-	shortener := app.NewURLShortener(repository, nil, authService, nil)
+	shortener := app.NewURLShortener(repository, nil, authService, nil, nil)
 
 	e := echo.New()
 
@@ -271,18 +459,56 @@ func TestURLShortener_HandleCreateShortenBatch(t *testing.T) {
 				defer res.Body.Close()
 
 				body := rec.Body.Bytes()
-				var data []bodyItem
+				var data []models.CreateResponseBatch
 				json.Unmarshal(body, &data)
 
 				assert.Equal(t, test.want.code, res.StatusCode)
 				require.NoError(t, err)
-				assert.Equal(t, len(data), len(test.body))
+				require.Equal(t, len(data), len(test.body))
 				assert.Equal(t, test.want.contentType, res.Header.Get("Content-Type"))
+
+				// Результат по каждой ссылке в порядке запроса
+				for i, item := range data {
+					assert.Equal(t, test.want.statuses[i], item.Status)
+					assert.Equal(t, item.Status == models.LinkStatusInvalid, item.ShortURL == "")
+				}
 			}
 		})
 	}
 }
 
+func TestURLShortener_HandleCreateShortenBatchTooLarge(t *testing.T) {
+	repository := &models.MemoryURLRepository{}
+	require.NoError(t, repository.Initialize(context.TODO(), environments.Configuration{}))
+	authService := new(auth.AuthServiceInterface)
+
+	shortener := app.NewURLShortener(repository, nil, authService, nil, nil)
+
+	var body strings.Builder
+	body.WriteString("[")
+	for i := 0; i < 1001; i++ {
+		if i > 0 {
+			body.WriteString(",")
+		}
+		fmt.Fprintf(&body, `{"correlation_id":"%d","original_url":"https://yandex.ru/%d"}`, i, i)
+	}
+	body.WriteString("]")
+
+	req := httptest.NewRequest(http.MethodPost, "/api/shorten/batch", strings.NewReader(body.String()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	c := echo.New().NewContext(req, httptest.NewRecorder())
+
+	err := shortener.HandleCreateShortenBatch(c)
+	res, ok := err.(*echo.HTTPError)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+
+	// Ни одна ссылка не создана
+	_, countURLs, err := repository.GetStats(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, 0, countURLs)
+}
+
 func TestURLShortener_HandleRedirect(t *testing.T) {
 	type want struct {
 		code        int
@@ -326,11 +552,11 @@ func TestURLShortener_HandleRedirect(t *testing.T) {
 
 	repository := &models.MemoryURLRepository{}
 	configuration := environments.Configuration{}
-	err := repository.Initialize(configuration)
+	err := repository.Initialize(context.TODO(), configuration)
 	require.NoError(t, err)
 	authService := new(auth.AuthServiceInterface)
 
-	shortener := app.NewURLShortener(repository, nil, authService, nil)
+	shortener := app.NewURLShortener(repository, nil, authService, nil, nil)
 
 	e := echo.New()
 
@@ -397,6 +623,248 @@ func TestURLShortener_HandleRedirect(t *testing.T) {
 	}
 }
 
+func TestURLShortener_HandleRedirectExpired(t *testing.T) {
+	repository := &models.MemoryURLRepository{}
+	require.NoError(t, repository.Initialize(context.TODO(), environments.Configuration{}))
+	authService := new(auth.AuthServiceInterface)
+
+	shortener := app.NewURLShortener(repository, nil, authService, nil, nil)
+
+	past := time.Now().Add(-time.Minute)
+	require.NoError(t, repository.Save(context.TODO(), []*models.Event{{
+		ShortKey:    "campaign",
+		OriginalURL: "https://yandex.ru/campaign",
+		ExpiresAt:   &past,
+	}}))
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/campaign", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetPath("/:id")
+	c.SetParamNames("id")
+	c.SetParamValues("campaign")
+
+	require.NoError(t, shortener.HandleRedirect(c))
+
+	res := rec.Result()
+	defer res.Body.Close()
+
+	assert.Equal(t, http.StatusGone, res.StatusCode)
+}
+
+// publicResolver разрешает любой хост в публичный адрес без обращения к DNS
+type publicResolver struct{}
+
+func (publicResolver) LookupIPAddr(context.Context, string) ([]net.IPAddr, error) {
+	return []net.IPAddr{{IP: net.ParseIP("93.184.216.34")}}, nil
+}
+
+func TestURLShortener_DestinationPolicy(t *testing.T) {
+	repository := &models.MemoryURLRepository{}
+	require.NoError(t, repository.Initialize(context.TODO(), environments.Configuration{}))
+	authService := new(auth.AuthServiceInterface)
+	authService.EXPECT().GetUserID(mock.Anything).Return("")
+
+	shortener := app.NewURLShortener(repository, nil, authService, nil, nil)
+	shortener.DestinationPolicy = destination.NewPolicy(destination.Options{
+		BlockPrivate:    true,
+		CheckOnRedirect: true,
+		Resolver:        publicResolver{},
+	})
+	shortener.DestinationPolicy.SetRules(destination.Rules{Blocklist: []string{"phishing.test"}})
+
+	e := echo.New()
+
+	tests := []struct {
+		name    string
+		handler echo.HandlerFunc
+		body    string
+		code    int
+		reason  string
+	}{
+		{
+			name:    "negative test #1",
+			handler: shortener.HandleShorten,
+			body:    "https://login.phishing.test/",
+			code:    http.StatusBadRequest,
+			reason:  "is blocked",
+		},
+		{
+			name:    "negative test #2",
+			handler: shortener.HandleShorten,
+			body:    "http://127.0.0.1:8080/admin",
+			code:    http.StatusBadRequest,
+			reason:  "internal address 127.0.0.1",
+		},
+		{
+			name:    "negative test #3",
+			handler: shortener.HandleCreateShorten,
+			body:    `{"url":"https://phishing.test/login"}`,
+			code:    http.StatusBadRequest,
+			reason:  "is blocked",
+		},
+		{
+			name:    "negative test #4",
+			handler: shortener.HandleCreateShorten,
+			body:    `{"url":"http://[::1]/"}`,
+			code:    http.StatusBadRequest,
+			reason:  "internal address ::1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			c := e.NewContext(req, httptest.NewRecorder())
+
+			err := tt.handler(c)
+
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, tt.code, httpErr.Code)
+			assert.Contains(t, httpErr.Message, tt.reason)
+		})
+	}
+
+	t.Run("batch", func(t *testing.T) {
+		body := `[{"correlation_id":"1","original_url":"https://phishing.test/"},` +
+			`{"correlation_id":"2","original_url":"https://yandex.ru/allowed"}]`
+		req := httptest.NewRequest(http.MethodPost, "/api/shorten/batch", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		require.NoError(t, shortener.HandleCreateShortenBatch(c))
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		var resp []models.CreateResponseBatch
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		require.Len(t, resp, 2)
+		assert.Equal(t, models.LinkStatusInvalid, resp[0].Status)
+		assert.Contains(t, resp[0].Error, "is blocked")
+		assert.Equal(t, models.LinkStatusCreated, resp[1].Status)
+	})
+
+	t.Run("redirect", func(t *testing.T) {
+		// Ссылка создана до того, как домен попал в список
+		require.NoError(t, repository.Save(context.TODO(), []*models.Event{{
+			ShortKey:    "later",
+			OriginalURL: "https://later.test/",
+		}}))
+		shortener.DestinationPolicy.SetRules(destination.Rules{Blocklist: []string{"later.test"}})
+
+		req := httptest.NewRequest(http.MethodGet, "/later", nil)
+		c := e.NewContext(req, httptest.NewRecorder())
+		c.SetPath("/:id")
+		c.SetParamNames("id")
+		c.SetParamValues("later")
+
+		err := shortener.HandleRedirect(c)
+
+		var httpErr *echo.HTTPError
+		require.ErrorAs(t, err, &httpErr)
+		assert.Equal(t, http.StatusForbidden, httpErr.Code)
+	})
+}
+
+func TestURLShortener_HandleUserURLStats(t *testing.T) {
+	repository := &models.MemoryURLRepository{}
+	require.NoError(t, repository.Initialize(context.TODO(), environments.Configuration{}))
+	authService := new(auth.AuthServiceInterface)
+
+	shortener := app.NewURLShortener(repository, nil, authService, nil, nil)
+
+	require.NoError(t, repository.Save(context.TODO(), []*models.Event{{
+		ShortKey:    "stats1",
+		OriginalURL: "https://yandex.ru/stats",
+		UserID:      "owner",
+	}}))
+
+	e := echo.New()
+
+	// Переходы по ссылке
+	for _, referrer := range []string{"https://google.com", "https://google.com", ""} {
+		req := httptest.NewRequest(http.MethodGet, "/stats1", nil)
+		req.Header.Set("Referer", referrer)
+		req.Header.Set("X-Real-IP", "10.0.0.1")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/:id")
+		c.SetParamNames("id")
+		c.SetParamValues("stats1")
+
+		require.NoError(t, shortener.HandleRedirect(c))
+	}
+
+	// Переходы сохраняются при остановке
+	<-shortener.Shutdown()
+
+	type want struct {
+		code  int
+		total int
+	}
+	tests := []struct {
+		name   string
+		userID string
+		want   want
+	}{
+		{
+			name:   "positive test #1",
+			userID: "owner",
+			want: want{
+				code:  200,
+				total: 3,
+			},
+		},
+		{
+			name:   "negative test #1",
+			userID: "stranger",
+			want: want{
+				code: 404,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/user/urls/stats1/stats", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/api/user/urls/:id/stats")
+			c.SetParamNames("id")
+			c.SetParamValues("stats1")
+
+			authService.EXPECT().GetUserID(c).Return(test.userID)
+
+			err := shortener.HandleUserURLStats(c)
+
+			// Assertions
+			if err != nil {
+				res, ok := err.(*echo.HTTPError)
+
+				require.True(t, ok)
+				assert.Equal(t, test.want.code, res.Code)
+			} else {
+				res := rec.Result()
+				defer res.Body.Close()
+
+				var data models.GetURLStatsResponse
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &data))
+
+				assert.Equal(t, test.want.code, res.StatusCode)
+				assert.Equal(t, test.want.total, data.Total)
+				assert.Equal(t, []models.ClickCount{
+					{Key: "https://google.com", Count: 2},
+					{Key: "", Count: 1},
+				}, data.ByReferrer)
+				assert.Equal(t, []models.ClickCount{
+					{Key: "10.0.0.0/24", Count: 3},
+				}, data.ByIPPrefix)
+			}
+		})
+	}
+}
+
 func TestURLShortener_HandlePing(t *testing.T) {
 	mockConn, err := pgxmock.NewConn()
 	// mockConn.Ping(context.Background())
@@ -431,7 +899,7 @@ func TestURLShortener_HandlePing(t *testing.T) {
 	repository := new(models2.URLRepository)
 	authService := new(auth.AuthServiceInterface)
 
-	shortener := app.NewURLShortener(repository, mockConn, authService, nil)
+	shortener := app.NewURLShortener(repository, mockConn, authService, nil, nil)
 
 	e := echo.New()
 
@@ -516,7 +984,7 @@ func TestURLShortener_HandleUserURLGet(t *testing.T) {
 	repository := new(models2.URLRepository)
 	authService := new(auth.AuthServiceInterface)
 
-	shortener := app.NewURLShortener(repository, mockConn, authService, nil)
+	shortener := app.NewURLShortener(repository, mockConn, authService, nil, nil)
 
 	e := echo.New()
 
@@ -531,7 +999,7 @@ func TestURLShortener_HandleUserURLGet(t *testing.T) {
 			c := e.NewContext(req, rec)
 
 			authService.EXPECT().GetUserID(c).Return(userID)
-			repository.EXPECT().GetEventsByUserID(c.Request().Context(), userID).Return(events)
+			repository.EXPECT().GetEventsByUserID(c.Request().Context(), userID).Return(events, nil)
 
 			err := shortener.HandleUserURLGet(c)
 
@@ -593,7 +1061,7 @@ func TestURLShortener_HandleUserURLDelete(t *testing.T) {
 	repository := new(models2.URLRepository)
 	authService := new(auth.AuthServiceInterface)
 
-	shortener := app.NewURLShortener(repository, mockConn, authService, nil)
+	shortener := app.NewURLShortener(repository, mockConn, authService, nil, nil)
 
 	e := echo.New()
 
@@ -609,7 +1077,7 @@ func TestURLShortener_HandleUserURLDelete(t *testing.T) {
 			c := e.NewContext(req, rec)
 
 			authService.EXPECT().GetUserID(c).Return(userID)
-			repository.EXPECT().Delete(context.TODO(), []models.DeleteRequestBatch{
+			repository.EXPECT().Delete(mock.Anything, []models.DeleteRequestBatch{
 				{
 					UserID:    userID,
 					ShortKeys: events,
@@ -684,6 +1152,16 @@ func TestURLShortener_HandleGetStats(t *testing.T) {
 				error: errors.New("test error"),
 			},
 		},
+		{
+			name: "negative test #3",
+			args: args{
+				IP: "127.0.0.1",
+			},
+			want: want{
+				code:  503,
+				error: fmt.Errorf("%w: %w", models.ErrUnavailable, errors.New("connection refused")),
+			},
+		},
 	}
 
 	repository := new(models2.URLRepository)
@@ -693,7 +1171,7 @@ func TestURLShortener_HandleGetStats(t *testing.T) {
 		t.Error(err)
 	}
 
-	shortener := app.NewURLShortener(repository, mockConn, authService, subnet)
+	shortener := app.NewURLShortener(repository, mockConn, authService, subnet, nil)
 
 	e := echo.New()
 
@@ -740,6 +1218,68 @@ func TestURLShortener_HandleGetStats(t *testing.T) {
 	}
 }
 
+func TestURLShortener_HandleCompact(t *testing.T) {
+	memoryRepository := &models.MemoryURLRepository{}
+	require.NoError(t, memoryRepository.Initialize(context.TODO(), environments.Configuration{
+		FileStoragePath: filepath.Join(t.TempDir(), "events.json"),
+	}))
+
+	tests := []struct {
+		name       string
+		repository models.URLRepository
+		IP         string
+		code       int
+	}{
+		{
+			name:       "positive test #1",
+			repository: models.NewInstrumentedURLRepository(memoryRepository),
+			IP:         "127.0.0.1",
+			code:       200,
+		},
+		{
+			name:       "negative test #1",
+			repository: memoryRepository,
+			IP:         "10.0.0.1",
+			code:       403,
+		},
+		{
+			name:       "negative test #2",
+			repository: new(models2.URLRepository),
+			IP:         "127.0.0.1",
+			code:       501,
+		},
+	}
+
+	authService := new(auth.AuthServiceInterface)
+	_, subnet, err := net.ParseCIDR("127.0.0.1/24")
+	require.NoError(t, err)
+
+	e := echo.New()
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shortener := app.NewURLShortener(test.repository, nil, authService, subnet, nil)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/internal/compact", nil)
+			req.Header.Set("X-Real-IP", test.IP)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			err := shortener.HandleCompact(c)
+
+			// Assertions
+			if err != nil {
+				var res *echo.HTTPError
+				require.True(t, errors.As(err, &res))
+				assert.Equal(t, test.code, res.Code)
+				return
+			}
+
+			assert.Equal(t, test.code, rec.Code)
+		})
+	}
+}
+
 func TestURLShortener_Shutdown(t *testing.T) {
 	mockConn, err := pgxmock.NewConn()
 	if err != nil {
@@ -773,7 +1313,7 @@ func TestURLShortener_Shutdown(t *testing.T) {
 	authService := new(auth.AuthServiceInterface)
 
 	for _, test := range tests {
-		shortener := app.NewURLShortener(repository, mockConn, authService, nil)
+		shortener := app.NewURLShortener(repository, mockConn, authService, nil, nil)
 
 		t.Run(test.name, func(t *testing.T) {
 			if len(test.args.events) > 0 {
@@ -785,7 +1325,7 @@ func TestURLShortener_Shutdown(t *testing.T) {
 				c := e.NewContext(req, rec)
 
 				authService.EXPECT().GetUserID(c).Return(test.args.userID)
-				repository.EXPECT().Delete(context.TODO(), []models.DeleteRequestBatch{
+				repository.EXPECT().Delete(mock.Anything, []models.DeleteRequestBatch{
 					{
 						UserID:    test.args.userID,
 						ShortKeys: test.args.events,
@@ -35,6 +35,8 @@ func GetSigningMethod() *jwt.SigningMethodHMAC {
 // Claims struct
 type Claims struct {
 	ID string `json:"id"`
+	// Registered токен выдан при регистрации или входе, а не создан анонимному клиенту
+	Registered bool `json:"registered,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -82,6 +84,22 @@ func (authService *AuthService) GetUserID(c echo.Context) string {
 	return claims.ID
 }
 
+// RegisteredUserID get user of the request only if it signed up or signed in,
+// empty for anonymous users, whose tokens can be created by any client
+func RegisteredUserID(c echo.Context) string {
+	u, ok := c.Get("user").(*jwt.Token)
+	if !ok {
+		return ""
+	}
+
+	claims, ok := u.Claims.(*Claims)
+	if !ok || !claims.Registered {
+		return ""
+	}
+
+	return claims.ID
+}
+
 // JWTErrorChecker function for error handling
 func JWTErrorChecker(_ echo.Context, err error) error {
 	if err != nil {
@@ -108,7 +126,8 @@ func generateAccessToken(user *User) (*jwt.Token, string, time.Time, error) {
 
 func generateToken(user *User, expirationTime time.Time, secret []byte) (*jwt.Token, string, time.Time, error) {
 	claims := &Claims{
-		ID: user.ID,
+		ID:         user.ID,
+		Registered: user.Registered,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 		},
@@ -124,6 +143,18 @@ func generateToken(user *User, expirationTime time.Time, secret []byte) (*jwt.To
 	return token, tokenString, expirationTime, nil
 }
 
+// ClearTokenCookies remove token and user cookies, e.g. on logout
+func ClearTokenCookies(c echo.Context) {
+	for _, name := range []string{accessTokenCookieName, refreshTokenCookieName, "user"} {
+		c.SetCookie(&http.Cookie{
+			Name:     name,
+			Path:     "/",
+			MaxAge:   -1,
+			HttpOnly: name != "user",
+		})
+	}
+}
+
 func setTokenCookie(c echo.Context, name, token string, expiration time.Time) {
 	cookie := new(http.Cookie)
 	cookie.Name = name
@@ -1,10 +1,8 @@
 package auth
 
 import (
-	"fmt"
 	"net/http"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 )
@@ -29,22 +27,11 @@ func CreateTokenWithConfig(config CreateTokenConfig) echo.MiddlewareFunc {
 			accessTokenCookie, err := c.Request().Cookie(GetAccessTokenCookieName())
 
 			if err == nil && accessTokenCookie != nil {
-				claims := &Claims{}
-				token, err1 := jwt.ParseWithClaims(accessTokenCookie.Value, claims,
-					func(t *jwt.Token) (interface{}, error) {
-						if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-							return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
-						}
-						return []byte(GetJWTSecret()), nil
-					})
+				token, err1 := ParseAccessToken(accessTokenCookie.Value)
 				if err1 != nil {
 					return echo.NewHTTPError(http.StatusUnauthorized, "Token is incorrect")
 				}
 
-				if !token.Valid {
-					return echo.NewHTTPError(http.StatusUnauthorized, "Token is incorrect")
-				}
-
 				c.Set("user", token)
 			}
 
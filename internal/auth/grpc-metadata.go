@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	authorizationMetadataKey = "authorization"
+	bearerPrefix             = "Bearer "
+)
+
+// ErrTokenMissing request doesn't contain an access token
+var ErrTokenMissing = errors.New("token missing")
+
+// ErrTokenInvalid access token is malformed, expired or signed with another key
+var ErrTokenInvalid = errors.New("token is incorrect")
+
+// GetAuthorizationMetadataKey get name of gRPC metadata with access token
+func GetAuthorizationMetadataKey() string {
+	return authorizationMetadataKey
+}
+
+// ParseAccessToken validate access token with the same secret as the HTTP API
+func ParseAccessToken(tokenString string) (*jwt.Token, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims,
+		func(t *jwt.Token) (interface{}, error) {
+			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+			}
+			return []byte(GetJWTSecret()), nil
+		})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrTokenInvalid, err)
+	}
+
+	if !token.Valid {
+		return nil, ErrTokenInvalid
+	}
+
+	return token, nil
+}
+
+// UserIDFromMetadata get user from the access token in incoming gRPC metadata.
+// The token is passed as "authorization: Bearer <token>".
+func UserIDFromMetadata(ctx context.Context) (string, error) {
+	user, err := UserFromMetadata(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return user.ID, nil
+}
+
+// UserFromMetadata get user and its registration flag from the access token in incoming gRPC metadata
+func UserFromMetadata(ctx context.Context) (*User, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, ErrTokenMissing
+	}
+
+	values := md.Get(authorizationMetadataKey)
+	if len(values) == 0 || values[0] == "" {
+		return nil, ErrTokenMissing
+	}
+
+	// Префикс схемы необязателен, как и у cookie с токеном
+	tokenString := values[0]
+	if len(tokenString) >= len(bearerPrefix) && strings.EqualFold(tokenString[:len(bearerPrefix)], bearerPrefix) {
+		tokenString = tokenString[len(bearerPrefix):]
+	}
+
+	token, err := ParseAccessToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*Claims)
+	if !ok || claims.ID == "" {
+		return nil, ErrTokenInvalid
+	}
+
+	return &User{ID: claims.ID, Registered: claims.Registered}, nil
+}
+
+// userIDContextKey ключ пользователя в контексте запроса
+type userIDContextKey struct{}
+
+// ContextWithUserID put the authenticated user into the context
+func ContextWithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userIDContextKey{}, userID)
+}
+
+// UserIDFromContext get the authenticated user, empty for anonymous requests
+func UserIDFromContext(ctx context.Context) string {
+	userID, _ := ctx.Value(userIDContextKey{}).(string)
+	return userID
+}
+
+// registeredContextKey ключ признака зарегистрированного пользователя в контексте запроса
+type registeredContextKey struct{}
+
+// ContextWithUser put the authenticated user and its registration flag into the context
+func ContextWithUser(ctx context.Context, user *User) context.Context {
+	return context.WithValue(ContextWithUserID(ctx, user.ID), registeredContextKey{}, user.Registered)
+}
+
+// RegisteredUserIDFromContext get the authenticated user only if it signed up or signed in
+func RegisteredUserIDFromContext(ctx context.Context) string {
+	if registered, _ := ctx.Value(registeredContextKey{}).(bool); !registered {
+		return ""
+	}
+
+	return UserIDFromContext(ctx)
+}
@@ -0,0 +1,92 @@
+package auth_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ShukinDmitriy/shortener/internal/auth"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+func signToken(t *testing.T, userID string, expiresAt time.Time, secret string) string {
+	token := jwt.NewWithClaims(auth.GetSigningMethod(), &auth.Claims{
+		ID: userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	})
+
+	tokenString, err := token.SignedString([]byte(secret))
+	require.NoError(t, err)
+
+	return tokenString
+}
+
+func TestUserIDFromMetadata(t *testing.T) {
+	validToken := signToken(t, "testUserID", time.Now().Add(time.Hour), auth.GetJWTSecret())
+
+	tests := []struct {
+		name    string
+		md      metadata.MD
+		want    string
+		wantErr error
+	}{
+		{
+			name: "positive test #1",
+			md:   metadata.Pairs(auth.GetAuthorizationMetadataKey(), "Bearer "+validToken),
+			want: "testUserID",
+		},
+		{
+			name: "positive test #2",
+			md:   metadata.Pairs(auth.GetAuthorizationMetadataKey(), validToken),
+			want: "testUserID",
+		},
+		{
+			name:    "negative test #1",
+			wantErr: auth.ErrTokenMissing,
+		},
+		{
+			name:    "negative test #2",
+			md:      metadata.Pairs(auth.GetAuthorizationMetadataKey(), "Bearer invalid"),
+			wantErr: auth.ErrTokenInvalid,
+		},
+		{
+			name: "negative test #3",
+			md: metadata.Pairs(
+				auth.GetAuthorizationMetadataKey(),
+				"Bearer "+signToken(t, "testUserID", time.Now().Add(-time.Hour), auth.GetJWTSecret()),
+			),
+			wantErr: auth.ErrTokenInvalid,
+		},
+		{
+			name: "negative test #4",
+			md: metadata.Pairs(
+				auth.GetAuthorizationMetadataKey(),
+				"Bearer "+signToken(t, "testUserID", time.Now().Add(time.Hour), auth.GetRefreshJWTSecret()),
+			),
+			wantErr: auth.ErrTokenInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, tt.md)
+			}
+
+			got, err := auth.UserIDFromMetadata(ctx)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
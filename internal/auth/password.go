@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"errors"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// passwordMinLength минимальная длина пароля
+	passwordMinLength = 8
+	// passwordMaxLength bcrypt учитывает только первые 72 байта пароля
+	passwordMaxLength = 72
+)
+
+// ErrPasswordInvalid password is too short or too long
+var ErrPasswordInvalid = errors.New("password must be from 8 to 72 bytes")
+
+// HashPassword check the password and hash it with bcrypt
+func HashPassword(password string) (string, error) {
+	if len(password) < passwordMinLength || len(password) > passwordMaxLength {
+		return "", ErrPasswordInvalid
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// CheckPassword compare the password with the hash
+func CheckPassword(hash string, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// dummyPasswordHash хеш с той же стоимостью, что и у паролей аккаунтов, вычисляется при первом обращении
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+	return hash
+})
+
+// CheckDummyPassword compare the password with a hash of no account. It takes as long as CheckPassword,
+// so a sign in with an unknown email can't be told apart by the response time.
+func CheckDummyPassword(password string) {
+	_ = bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(password))
+}
@@ -0,0 +1,68 @@
+package auth_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ShukinDmitriy/shortener/internal/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashPassword(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		wantErr  error
+	}{
+		{
+			name:     "positive test #1",
+			password: "secret-password",
+		},
+		{
+			name:     "negative test #1",
+			password: "short",
+			wantErr:  auth.ErrPasswordInvalid,
+		},
+		{
+			name:     "negative test #2",
+			password: strings.Repeat("a", 73),
+			wantErr:  auth.ErrPasswordInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash, err := auth.HashPassword(tt.password)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.NotEqual(t, tt.password, hash)
+			assert.True(t, auth.CheckPassword(hash, tt.password))
+			assert.False(t, auth.CheckPassword(hash, tt.password+"!"))
+		})
+	}
+}
+
+func TestCheckDummyPassword(t *testing.T) {
+	hash, err := auth.HashPassword("secret-password")
+	require.NoError(t, err)
+
+	// Первый вызов вычисляет хеш-заглушку
+	auth.CheckDummyPassword("secret-password")
+
+	start := time.Now()
+	auth.CheckPassword(hash, "wrong-password")
+	checkDuration := time.Since(start)
+
+	start = time.Now()
+	auth.CheckDummyPassword("wrong-password")
+	dummyDuration := time.Since(start)
+
+	// Сравнение с заглушкой стоит столько же, сколько с хешем аккаунта, допускаем разброс
+	assert.Greater(t, dummyDuration, checkDuration/4)
+}
@@ -23,7 +23,8 @@ func TokenRefreshMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 		}
 
 		user := &User{
-			ID: claims.ID,
+			ID:         claims.ID,
+			Registered: claims.Registered,
 		}
 
 		if time.Until(time.Unix(claims.ExpiresAt.Unix(), 0)) < 15*time.Minute {
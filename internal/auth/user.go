@@ -7,6 +7,8 @@ import (
 // User struct
 type User struct {
 	ID string `json:"id"`
+	// Registered пользователь зарегистрирован, а не создан анонимно
+	Registered bool `json:"registered,omitempty"`
 }
 
 // LoadTestUser create user for tests
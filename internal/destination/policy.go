@@ -0,0 +1,209 @@
+// Package destination policy of destinations short links may point to
+package destination
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// redirectResolveTimeout сколько ждем DNS при проверке перед переходом
+const redirectResolveTimeout = 2 * time.Second
+
+// ErrForbidden destination is refused by the policy, the wrapping error contains the reason
+var ErrForbidden = errors.New("destination is forbidden")
+
+// Resolver resolves host names of destinations, *net.Resolver satisfies it
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// Options configuration of the policy
+type Options struct {
+	// BlockPrivate refuse destinations resolving to private, loopback, link-local, CGNAT, NAT64 and other internal IPs
+	BlockPrivate bool
+	// CheckOnRedirect check the destination again before every redirect
+	CheckOnRedirect bool
+	// Resolver of host names, net.DefaultResolver by default
+	Resolver Resolver
+	// ResolveCacheTTL how long addresses resolved on redirect are reused, a minute by default
+	ResolveCacheTTL time.Duration
+}
+
+// Policy checks destinations against domain lists and the addresses they resolve to.
+// The lists can be replaced at any time, e.g. on reload of the rules file.
+type Policy struct {
+	options Options
+
+	mutex sync.RWMutex
+	rules *compiledRules
+
+	// адреса хостов для проверки перед переходом
+	cache *resolveCache
+}
+
+// NewPolicy policy's constructor, without rules every domain is allowed
+func NewPolicy(options Options) *Policy {
+	if options.Resolver == nil {
+		options.Resolver = net.DefaultResolver
+	}
+
+	if options.ResolveCacheTTL <= 0 {
+		options.ResolveCacheTTL = defaultResolveCacheTTL
+	}
+
+	return &Policy{
+		options: options,
+		rules:   &compiledRules{},
+		cache:   newResolveCache(options.ResolveCacheTTL),
+	}
+}
+
+// SetRules replace domain lists of the policy
+func (p *Policy) SetRules(rules Rules) {
+	compiled := rules.compile()
+
+	p.mutex.Lock()
+	p.rules = compiled
+	p.mutex.Unlock()
+}
+
+// CheckOnRedirect whether destinations are checked again before redirects
+func (p *Policy) CheckOnRedirect() bool {
+	return p.options.CheckOnRedirect
+}
+
+// Check refuse the destination if its host is blocked, isn't allowed
+// or resolves to an address of a private network
+func (p *Policy) Check(ctx context.Context, rawURL string) error {
+	host, err := p.checkHost(rawURL)
+	if err != nil || !p.options.BlockPrivate {
+		return err
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		return checkIP(host, ip)
+	}
+
+	addrs, err := p.options.Resolver.LookupIPAddr(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		return fmt.Errorf("%w: host %q cannot be resolved", ErrForbidden, host)
+	}
+
+	return checkAddresses(host, ipsOf(addrs))
+}
+
+// CheckRedirect check the destination of an existing link before a redirect.
+// Unlike Check, resolved addresses are cached for ResolveCacheTTL
+// and the redirect is allowed when the host cannot be resolved: the link was checked on creation
+// and an unavailable DNS must not break redirects.
+func (p *Policy) CheckRedirect(ctx context.Context, rawURL string) error {
+	host, err := p.checkHost(rawURL)
+	if err != nil || !p.options.BlockPrivate {
+		return err
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		return checkIP(host, ip)
+	}
+
+	ips, ok := p.cache.get(host)
+	if !ok {
+		resolveCtx, cancel := context.WithTimeout(ctx, redirectResolveTimeout)
+		defer cancel()
+
+		addrs, err := p.options.Resolver.LookupIPAddr(resolveCtx, host)
+		if err != nil || len(addrs) == 0 {
+			zap.L().Warn("cannot resolve destination on redirect", zap.String("host", host), zap.Error(err))
+			return nil
+		}
+
+		ips = ipsOf(addrs)
+		p.cache.set(host, ips)
+	}
+
+	return checkAddresses(host, ips)
+}
+
+// checkHost проверяет хост ссылки по спискам доменов и возвращает его
+func (p *Policy) checkHost(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("%w: cannot parse url", ErrForbidden)
+	}
+	host := u.Hostname()
+
+	p.mutex.RLock()
+	rules := p.rules
+	p.mutex.RUnlock()
+
+	return host, rules.check(host)
+}
+
+// checkAddresses проверяет все адреса хоста, достаточно одного внутреннего, чтобы отказать
+func checkAddresses(host string, ips []net.IP) error {
+	for _, ip := range ips {
+		if err := checkIP(host, ip); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func ipsOf(addrs []net.IPAddr) []net.IP {
+	ips := make([]net.IP, len(addrs))
+	for i, addr := range addrs {
+		ips[i] = addr.IP
+	}
+
+	return ips
+}
+
+// internalPrefixes сети, недоступные или не предназначенные для переходов из интернета
+var internalPrefixes = []netip.Prefix{
+	// IPv4
+	netip.MustParsePrefix("0.0.0.0/8"),      // "эта" сеть
+	netip.MustParsePrefix("10.0.0.0/8"),     // частная сеть
+	netip.MustParsePrefix("100.64.0.0/10"),  // CGNAT
+	netip.MustParsePrefix("127.0.0.0/8"),    // loopback
+	netip.MustParsePrefix("169.254.0.0/16"), // link-local, метаданные облаков
+	netip.MustParsePrefix("172.16.0.0/12"),  // частная сеть
+	netip.MustParsePrefix("192.0.0.0/24"),   // служебные адреса IETF
+	netip.MustParsePrefix("192.168.0.0/16"), // частная сеть
+	netip.MustParsePrefix("198.18.0.0/15"),  // тестирование производительности сетей
+	netip.MustParsePrefix("224.0.0.0/4"),    // multicast
+	netip.MustParsePrefix("240.0.0.0/4"),    // зарезервировано и broadcast
+	// IPv6, адреса IPv4 внутри IPv6 проверяются как IPv4
+	netip.MustParsePrefix("::/128"),         // unspecified
+	netip.MustParsePrefix("::1/128"),        // loopback
+	netip.MustParsePrefix("64:ff9b::/96"),   // NAT64, может вести во внутреннюю сеть IPv4
+	netip.MustParsePrefix("64:ff9b:1::/48"), // локальный NAT64
+	netip.MustParsePrefix("fc00::/7"),       // unique local
+	netip.MustParsePrefix("fe80::/10"),      // link-local
+	netip.MustParsePrefix("ff00::/8"),       // multicast
+}
+
+// checkIP отказывает адресам частных, служебных, loopback и link-local сетей
+func checkIP(host string, ip net.IP) error {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return fmt.Errorf("%w: host %q resolves to invalid address", ErrForbidden, host)
+	}
+	addr = addr.Unmap()
+
+	for _, prefix := range internalPrefixes {
+		if prefix.Contains(addr) {
+			return fmt.Errorf("%w: host %q resolves to internal address %s", ErrForbidden, host, ip)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,267 @@
+package destination_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/ShukinDmitriy/shortener/internal/destination"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticResolver адреса хостов для тестов без обращения к DNS
+type staticResolver map[string][]string
+
+func (r staticResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	ips, ok := r[host]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+
+	addrs := make([]net.IPAddr, len(ips))
+	for i, ip := range ips {
+		addrs[i] = net.IPAddr{IP: net.ParseIP(ip)}
+	}
+
+	return addrs, nil
+}
+
+func TestPolicy_Check(t *testing.T) {
+	resolver := staticResolver{
+		"example.com":       {"93.184.216.34"},
+		"intranet.corp":     {"10.0.0.5"},
+		"mixed.example.net": {"93.184.216.34", "127.0.0.1"},
+		"metadata.internal": {"169.254.169.254"},
+		"v6.example.org":    {"fd00::1"},
+		"cgnat.example.net": {"100.64.1.1"},
+		"bench.example.net": {"198.18.0.1"},
+		"nat64.example.net": {"64:ff9b::a00:5"},
+	}
+	rules := destination.Rules{
+		Blocklist: []string{"phishing.test", "*.Evil.Example", "пример.рф"},
+	}
+
+	tests := []struct {
+		name         string
+		url          string
+		rules        destination.Rules
+		blockPrivate bool
+		reason       string
+	}{
+		{
+			name:  "positive test #1",
+			url:   "https://example.com/page",
+			rules: rules,
+		},
+		{
+			name:  "positive test #2",
+			url:   "http://10.0.0.5/admin",
+			rules: rules,
+		},
+		{
+			name:         "positive test #3",
+			url:          "https://example.com/page",
+			rules:        rules,
+			blockPrivate: true,
+		},
+		{
+			name:  "positive test #4",
+			url:   "https://docs.example.com/",
+			rules: destination.Rules{Allowlist: []string{"example.com"}},
+		},
+		{
+			name:   "negative test #1",
+			url:    "https://phishing.test/login",
+			rules:  rules,
+			reason: `host "phishing.test" is blocked`,
+		},
+		{
+			name:   "negative test #2",
+			url:    "https://login.evil.example/",
+			rules:  rules,
+			reason: "is blocked",
+		},
+		{
+			name:   "negative test #3",
+			url:    "http://xn--e1afmkfd.xn--p1ai/",
+			rules:  rules,
+			reason: "is blocked",
+		},
+		{
+			name:   "negative test #4",
+			url:    "https://example.org/",
+			rules:  destination.Rules{Allowlist: []string{"example.com"}},
+			reason: `host "example.org" is not allowed`,
+		},
+		{
+			name:   "negative test #5",
+			url:    "https://phishing.test/",
+			rules:  destination.Rules{Allowlist: []string{"phishing.test"}, Blocklist: []string{"phishing.test"}},
+			reason: "is blocked",
+		},
+		{
+			name:         "negative test #6",
+			url:          "http://intranet.corp/",
+			blockPrivate: true,
+			reason:       "resolves to internal address 10.0.0.5",
+		},
+		{
+			name:         "negative test #7",
+			url:          "http://mixed.example.net/",
+			blockPrivate: true,
+			reason:       "internal address 127.0.0.1",
+		},
+		{
+			name:         "negative test #8",
+			url:          "http://metadata.internal/latest",
+			blockPrivate: true,
+			reason:       "internal address 169.254.169.254",
+		},
+		{
+			name:         "negative test #9",
+			url:          "http://v6.example.org/",
+			blockPrivate: true,
+			reason:       "internal address fd00::1",
+		},
+		{
+			name:         "negative test #10",
+			url:          "http://[::1]:8080/",
+			blockPrivate: true,
+			reason:       "internal address ::1",
+		},
+		{
+			name:         "negative test #11",
+			url:          "http://unknown.example/",
+			blockPrivate: true,
+			reason:       "cannot be resolved",
+		},
+		{
+			name:         "negative test #12",
+			url:          "http://cgnat.example.net/",
+			blockPrivate: true,
+			reason:       "internal address 100.64.1.1",
+		},
+		{
+			name:         "negative test #13",
+			url:          "http://0.0.0.1/",
+			blockPrivate: true,
+			reason:       "internal address 0.0.0.1",
+		},
+		{
+			name:         "negative test #14",
+			url:          "http://bench.example.net/",
+			blockPrivate: true,
+			reason:       "internal address 198.18.0.1",
+		},
+		{
+			name:         "negative test #15",
+			url:          "http://nat64.example.net/",
+			blockPrivate: true,
+			reason:       "internal address 64:ff9b::a00:5",
+		},
+		{
+			name:         "negative test #16",
+			url:          "http://[::ffff:127.0.0.1]/",
+			blockPrivate: true,
+			reason:       "internal address 127.0.0.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := destination.NewPolicy(destination.Options{
+				BlockPrivate: tt.blockPrivate,
+				Resolver:     resolver,
+			})
+			policy.SetRules(tt.rules)
+
+			err := policy.Check(context.Background(), tt.url)
+			if tt.reason == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			require.ErrorIs(t, err, destination.ErrForbidden)
+			assert.Contains(t, err.Error(), tt.reason)
+		})
+	}
+}
+
+// countingResolver считает обращения к DNS, хосты без адресов не разрешаются
+type countingResolver struct {
+	staticResolver
+	calls map[string]int
+}
+
+func (r *countingResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	r.calls[host]++
+	return r.staticResolver.LookupIPAddr(ctx, host)
+}
+
+func TestPolicy_CheckRedirect(t *testing.T) {
+	tests := []struct {
+		name   string
+		url    string
+		reason string
+		// обращений к DNS за две проверки
+		lookups int
+	}{
+		{
+			name:    "positive test #1",
+			url:     "https://example.com/page",
+			lookups: 1,
+		},
+		{
+			name:    "positive test #2",
+			url:     "http://unknown.example/",
+			lookups: 2,
+		},
+		{
+			name:    "negative test #1",
+			url:     "http://intranet.corp/",
+			reason:  "internal address 10.0.0.5",
+			lookups: 1,
+		},
+		{
+			name:   "negative test #2",
+			url:    "https://phishing.test/login",
+			reason: "is blocked",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver := &countingResolver{
+				staticResolver: staticResolver{
+					"example.com":   {"93.184.216.34"},
+					"intranet.corp": {"10.0.0.5"},
+				},
+				calls: make(map[string]int),
+			}
+			policy := destination.NewPolicy(destination.Options{
+				BlockPrivate: true,
+				Resolver:     resolver,
+			})
+			policy.SetRules(destination.Rules{Blocklist: []string{"phishing.test"}})
+
+			for range 2 {
+				err := policy.CheckRedirect(context.Background(), tt.url)
+				if tt.reason == "" {
+					assert.NoError(t, err)
+					continue
+				}
+
+				require.ErrorIs(t, err, destination.ErrForbidden)
+				assert.Contains(t, err.Error(), tt.reason)
+			}
+
+			lookups := 0
+			for _, calls := range resolver.calls {
+				lookups += calls
+			}
+			assert.Equal(t, tt.lookups, lookups)
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package destination
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultResolveCacheTTL сколько по умолчанию используем адреса хоста без повторного запроса к DNS
+	defaultResolveCacheTTL = time.Minute
+	// resolveCacheSize после стольких хостов устаревшие записи удаляются
+	resolveCacheSize = 10000
+)
+
+// resolvedHost адреса хоста и время, до которого они действительны
+type resolvedHost struct {
+	ips       []net.IP
+	expiresAt time.Time
+}
+
+// resolveCache адреса хостов, разрешенные при переходах по ссылкам
+type resolveCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mutex sync.Mutex
+	hosts map[string]resolvedHost
+}
+
+func newResolveCache(ttl time.Duration) *resolveCache {
+	return &resolveCache{
+		ttl:   ttl,
+		now:   time.Now,
+		hosts: make(map[string]resolvedHost),
+	}
+}
+
+// get адреса хоста, если они еще не устарели
+func (c *resolveCache) get(host string) ([]net.IP, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	resolved, ok := c.hosts[host]
+	if !ok || !c.now().Before(resolved.expiresAt) {
+		return nil, false
+	}
+
+	return resolved.ips, true
+}
+
+// set запоминает адреса хоста на ttl
+func (c *resolveCache) set(host string, ips []net.IP) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := c.now()
+	if len(c.hosts) >= resolveCacheSize {
+		for key, resolved := range c.hosts {
+			if !now.Before(resolved.expiresAt) {
+				delete(c.hosts, key)
+			}
+		}
+	}
+	// Все записи свежие: проще начать заново, чем выбирать, какие вытеснить
+	if len(c.hosts) >= resolveCacheSize {
+		c.hosts = make(map[string]resolvedHost)
+	}
+
+	c.hosts[host] = resolvedHost{ips: ips, expiresAt: now.Add(c.ttl)}
+}
@@ -0,0 +1,30 @@
+package destination
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveCache(t *testing.T) {
+	now := time.Now()
+	cache := newResolveCache(time.Minute)
+	cache.now = func() time.Time {
+		return now
+	}
+
+	_, ok := cache.get("example.com")
+	assert.False(t, ok)
+
+	cache.set("example.com", []net.IP{net.ParseIP("93.184.216.34")})
+	ips, ok := cache.get("example.com")
+	assert.True(t, ok)
+	assert.Equal(t, []net.IP{net.ParseIP("93.184.216.34")}, ips)
+
+	// Адреса устаревают через ttl и запрашиваются заново
+	now = now.Add(time.Minute)
+	_, ok = cache.get("example.com")
+	assert.False(t, ok)
+}
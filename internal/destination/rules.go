@@ -0,0 +1,154 @@
+package destination
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/net/idna"
+)
+
+// Rules domain lists of the policy, a domain matches its subdomains as well
+type Rules struct {
+	// Blocklist domains destinations can't point to
+	Blocklist []string `json:"blocklist"`
+	// Allowlist if not empty, only these domains are allowed
+	Allowlist []string `json:"allowlist"`
+}
+
+// compiledRules списки доменов в нижнем регистре и punycode
+type compiledRules struct {
+	blocklist map[string]struct{}
+	allowlist map[string]struct{}
+}
+
+// LoadRules read rules from the JSON file
+func LoadRules(filename string) (Rules, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return Rules{}, err
+	}
+
+	var rules Rules
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err = dec.Decode(&rules); err != nil {
+		return Rules{}, fmt.Errorf("invalid destination rules %s: %w", filename, err)
+	}
+
+	return rules, nil
+}
+
+// WatchFile load rules from the file and reload them when the file changes until ctx is done.
+// A broken file doesn't replace the rules loaded before.
+func (p *Policy) WatchFile(ctx context.Context, filename string, interval time.Duration) error {
+	rules, err := LoadRules(filename)
+	if err != nil {
+		return err
+	}
+	p.SetRules(rules)
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		modTime, size := info.ModTime(), info.Size()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				info, err := os.Stat(filename)
+				if err != nil {
+					zap.L().Warn("cannot stat destination rules", zap.String("file", filename), zap.Error(err))
+					continue
+				}
+				if info.ModTime().Equal(modTime) && info.Size() == size {
+					continue
+				}
+				modTime, size = info.ModTime(), info.Size()
+
+				rules, err := LoadRules(filename)
+				if err != nil {
+					zap.L().Error("cannot reload destination rules", zap.String("file", filename), zap.Error(err))
+					continue
+				}
+				p.SetRules(rules)
+
+				zap.L().Info("destination rules reloaded",
+					zap.Int("blocklist", len(rules.Blocklist)),
+					zap.Int("allowlist", len(rules.Allowlist)),
+				)
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (r Rules) compile() *compiledRules {
+	return &compiledRules{
+		blocklist: domainSet(r.Blocklist),
+		allowlist: domainSet(r.Allowlist),
+	}
+}
+
+// domainSet приводит домены к виду, в котором хранятся исходные ссылки
+func domainSet(domains []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(domains))
+
+	for _, domain := range domains {
+		domain = strings.Trim(strings.TrimPrefix(strings.TrimSpace(domain), "*."), ".")
+		if domain == "" {
+			continue
+		}
+
+		if ascii, err := idna.Lookup.ToASCII(strings.ToLower(domain)); err == nil {
+			domain = ascii
+		}
+		set[strings.ToLower(domain)] = struct{}{}
+	}
+
+	return set
+}
+
+// check проверяет хост по спискам, блокировка важнее разрешения
+func (r *compiledRules) check(host string) error {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	if matchDomain(r.blocklist, host) {
+		return fmt.Errorf("%w: host %q is blocked", ErrForbidden, host)
+	}
+
+	if len(r.allowlist) > 0 && !matchDomain(r.allowlist, host) {
+		return fmt.Errorf("%w: host %q is not allowed", ErrForbidden, host)
+	}
+
+	return nil
+}
+
+// matchDomain хост совпадает с доменом из списка или является его поддоменом
+func matchDomain(domains map[string]struct{}, host string) bool {
+	for {
+		if _, ok := domains[host]; ok {
+			return true
+		}
+
+		i := strings.IndexByte(host, '.')
+		if i < 0 {
+			return false
+		}
+		host = host[i+1:]
+	}
+}
@@ -0,0 +1,86 @@
+package destination_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ShukinDmitriy/shortener/internal/destination"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    destination.Rules
+		wantErr bool
+	}{
+		{
+			name:    "positive test #1",
+			content: `{"blocklist":["phishing.test"],"allowlist":["example.com"]}`,
+			want: destination.Rules{
+				Blocklist: []string{"phishing.test"},
+				Allowlist: []string{"example.com"},
+			},
+		},
+		{
+			name:    "negative test #1",
+			content: `{"blocklist":`,
+			wantErr: true,
+		},
+		{
+			name:    "negative test #2",
+			content: `{"blocked":["phishing.test"]}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "destinations.json")
+			require.NoError(t, os.WriteFile(filename, []byte(tt.content), 0o600))
+
+			rules, err := destination.LoadRules(filename)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, rules)
+		})
+	}
+}
+
+func TestPolicy_WatchFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "destinations.json")
+	require.NoError(t, os.WriteFile(filename, []byte(`{"blocklist":["phishing.test"]}`), 0o600))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	policy := destination.NewPolicy(destination.Options{})
+	require.NoError(t, policy.WatchFile(ctx, filename, 10*time.Millisecond))
+
+	assert.ErrorIs(t, policy.Check(ctx, "https://phishing.test/"), destination.ErrForbidden)
+	assert.NoError(t, policy.Check(ctx, "https://scam.test/"))
+
+	// Новые списки применяются без перезапуска
+	require.NoError(t, os.WriteFile(filename, []byte(`{"blocklist":["phishing.test","scam.test"]}`), 0o600))
+	assert.Eventually(t, func() bool {
+		return policy.Check(ctx, "https://scam.test/") != nil
+	}, time.Second, 10*time.Millisecond)
+
+	// Сломанный файл не отменяет загруженные ранее списки
+	require.NoError(t, os.WriteFile(filename, []byte(`{"blocklist":`), 0o600))
+	time.Sleep(50 * time.Millisecond)
+	assert.ErrorIs(t, policy.Check(ctx, "https://scam.test/"), destination.ErrForbidden)
+
+	_, err := destination.LoadRules(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+	assert.Error(t, destination.NewPolicy(destination.Options{}).WatchFile(ctx, "/nonexistent/destinations.json", time.Second))
+}
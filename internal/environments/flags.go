@@ -7,6 +7,7 @@ import (
 	"io"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/labstack/gommon/log"
@@ -21,7 +22,54 @@ type configFile struct {
 	DatabaseDSN     string `json:"database_dsn"`
 	EnableHTTPS     bool   `json:"enable_https"`
 	TrustedSubnet   string `json:"trusted_subnet"`
+	TrustedProxies  string `json:"trusted_proxies"`
 	GrpcPort        int    `json:"grpc_port"`
+	RedisURL        string `json:"redis_url"`
+
+	ShortKeyGenerator string `json:"short_key_generator"`
+	ShortKeyLength    int    `json:"short_key_length"`
+	ShortKeyAlphabet  string `json:"short_key_alphabet"`
+
+	CacheEnabled bool   `json:"cache_enabled"`
+	CacheSize    int    `json:"cache_size"`
+	CacheTTL     string `json:"cache_ttl"`
+
+	MetricsAddress string `json:"metrics_address"`
+
+	TracingEnabled bool   `json:"tracing_enabled"`
+	OTLPEndpoint   string `json:"otlp_endpoint"`
+	TraceFile      string `json:"trace_file"`
+
+	TLSCertFile      string `json:"tls_cert_file"`
+	TLSKeyFile       string `json:"tls_key_file"`
+	GrpcTLSCertFile  string `json:"grpc_tls_cert_file"`
+	GrpcTLSKeyFile   string `json:"grpc_tls_key_file"`
+	GrpcClientCAFile string `json:"grpc_client_ca_file"`
+
+	AutocertEnabled     bool   `json:"autocert_enabled"`
+	ACMEDirectoryURL    string `json:"acme_directory_url"`
+	ACMECAFile          string `json:"acme_ca_file"`
+	AutocertCacheDir    string `json:"autocert_cache_dir"`
+	HTTPRedirectAddress string `json:"http_redirect_address"`
+
+	DeleteQueuePath string `json:"delete_queue_path"`
+
+	FileCompactInterval string `json:"file_compact_interval"`
+
+	RateLimitShorten  int    `json:"rate_limit_shorten"`
+	RateLimitCreate   int    `json:"rate_limit_create"`
+	RateLimitRedirect int    `json:"rate_limit_redirect"`
+	RateLimitAuth     int    `json:"rate_limit_auth"`
+	RateLimitRedisURL string `json:"rate_limit_redis_url"`
+
+	URLMaxLength    int  `json:"url_max_length"`
+	URLKeepFragment bool `json:"url_keep_fragment"`
+	URLSortQuery    bool `json:"url_sort_query"`
+
+	DestinationPolicyFile      string `json:"destination_policy_file"`
+	DestinationPolicyReload    string `json:"destination_policy_reload"`
+	BlockPrivateDestinations   bool   `json:"block_private_destinations"`
+	CheckDestinationOnRedirect bool   `json:"check_destination_on_redirect"`
 }
 
 // getConfigFromFile Чтение конфигурации из файла
@@ -54,9 +102,95 @@ type Configuration struct {
 	DatabaseDSN     string
 	EnableHTTPS     bool
 	TrustedSubnet   string
+	TrustedProxies  string
 	GrpcPort        int
+	RedisURL        string
+
+	ShortKeyGenerator string
+	ShortKeyLength    int
+	ShortKeyAlphabet  string
+
+	CacheEnabled bool
+	CacheSize    int
+	CacheTTL     time.Duration
+
+	MetricsAddr string
+
+	TracingEnabled bool
+	OTLPEndpoint   string
+	TraceFile      string
+
+	// Сертификат и ключ HTTPS
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// Сертификат и ключ gRPC, без них gRPC работает без шифрования.
+	// При заданном CA клиенты обязаны предъявить подписанный им сертификат
+	GrpcTLSCertFile  string
+	GrpcTLSKeyFile   string
+	GrpcClientCAFile string
+
+	// Получение сертификата HTTPS по ACME для хоста из BaseAddr
+	AutocertEnabled  bool
+	ACMEDirectoryURL string
+	// CA, которому доверяем при обращении к ACME, например к локальному тестовому стенду
+	ACMECAFile       string
+	AutocertCacheDir string
+
+	// Адрес HTTP сервера, перенаправляющего запросы на HTTPS
+	HTTPRedirectAddr string
+
+	// Журнал очереди на удаление, восстанавливается при запуске
+	DeleteQueuePath string
+
+	// Как часто журнал в файле хранения заменяется снимком ссылок
+	FileCompactInterval time.Duration
+
+	// Ограничение запросов клиента в минуту, отрицательное значение отключает ограничение
+	RateLimitShorten  int
+	RateLimitCreate   int
+	RateLimitRedirect int
+	RateLimitAuth     int
+	// Общие для нескольких экземпляров счетчики в Redis
+	RateLimitRedisURL string
+
+	// Нормализация исходных ссылок: максимальная длина, сохранение фрагмента и сортировка параметров запроса
+	URLMaxLength    int
+	URLKeepFragment bool
+	URLSortQuery    bool
+
+	// Файл со списками заблокированных и разрешенных доменов и период его перечитывания
+	DestinationPolicyFile   string
+	DestinationPolicyReload time.Duration
+	// Отказывать ссылкам на адреса частных сетей, loopback и link-local
+	BlockPrivateDestinations bool
+	// Проверять ссылку политикой еще раз при переходе
+	CheckDestinationOnRedirect bool
 }
 
+const (
+	// DefaultTLSCertFile certificate for HTTPS when it isn't configured
+	DefaultTLSCertFile = "ssl/localhost.crt"
+	// DefaultTLSKeyFile private key for HTTPS when it isn't configured
+	DefaultTLSKeyFile = "ssl/device.key"
+	// DefaultAutocertCacheDir directory for certificates issued by ACME
+	DefaultAutocertCacheDir = "ssl/autocert"
+	// DefaultDeleteQueuePath journal of pending deletions when it isn't configured
+	DefaultDeleteQueuePath = "/tmp/short-url-delete-queue.json"
+	// DefaultFileCompactInterval how often the storage file is compacted when it isn't configured
+	DefaultFileCompactInterval = time.Hour
+	// DefaultRateLimitShorten requests per minute to /api/shorten* when it isn't configured
+	DefaultRateLimitShorten = 60
+	// DefaultRateLimitCreate requests per minute to POST / when it isn't configured
+	DefaultRateLimitCreate = 60
+	// DefaultRateLimitRedirect redirects per minute when it isn't configured
+	DefaultRateLimitRedirect = 600
+	// DefaultRateLimitAuth sign up and sign in attempts per minute when it isn't configured
+	DefaultRateLimitAuth = 10
+	// DefaultDestinationPolicyReload how often the destination rules file is checked for changes
+	DefaultDestinationPolicyReload = 10 * time.Second
+)
+
 // flagConfig содержит путь к файлу конфигурации в формате JSON
 var flagConfig string
 
@@ -84,9 +218,117 @@ var flagEnableHTTPS bool
 // flagTrustedSubnet строковое представление бесклассовой адресации
 var flagTrustedSubnet string
 
+// flagTrustedProxies сети доверенных прокси через запятую
+var flagTrustedProxies string
+
 // flagGrpcPort порт для запуска grpc
 var flagGrpcPort int
 
+// flagRedisURL содержит адрес подключения к redis
+var flagRedisURL string
+
+// flagShortKeyGenerator способ генерации коротких ключей
+var flagShortKeyGenerator string
+
+// flagShortKeyLength длина коротких ключей
+var flagShortKeyLength int
+
+// flagShortKeyAlphabet алфавит коротких ключей
+var flagShortKeyAlphabet string
+
+// flagCacheEnabled включен ли кеш ссылок
+var flagCacheEnabled bool
+
+// flagCacheSize количество ссылок в кеше
+var flagCacheSize int
+
+// flagCacheTTL время жизни ссылки в кеше
+var flagCacheTTL time.Duration
+
+// flagMetricsAddr адрес отдельного сервера метрик
+var flagMetricsAddr string
+
+// flagTracingEnabled включена ли трассировка
+var flagTracingEnabled bool
+
+// flagOTLPEndpoint адрес коллектора OpenTelemetry
+var flagOTLPEndpoint string
+
+// flagTraceFile путь до файла трассировок
+var flagTraceFile string
+
+// flagTLSCertFile путь до сертификата HTTPS
+var flagTLSCertFile string
+
+// flagTLSKeyFile путь до ключа HTTPS
+var flagTLSKeyFile string
+
+// flagGrpcTLSCertFile путь до сертификата gRPC
+var flagGrpcTLSCertFile string
+
+// flagGrpcTLSKeyFile путь до ключа gRPC
+var flagGrpcTLSKeyFile string
+
+// flagGrpcClientCAFile путь до CA для проверки клиентских сертификатов gRPC
+var flagGrpcClientCAFile string
+
+// flagAutocertEnabled включено ли получение сертификата по ACME
+var flagAutocertEnabled bool
+
+// flagACMEDirectoryURL адрес каталога ACME
+var flagACMEDirectoryURL string
+
+// flagACMECAFile путь до CA сервера ACME
+var flagACMECAFile string
+
+// flagAutocertCacheDir каталог для полученных сертификатов
+var flagAutocertCacheDir string
+
+// flagHTTPRedirectAddr адрес сервера перенаправления на HTTPS
+var flagHTTPRedirectAddr string
+
+// flagDeleteQueuePath путь до журнала очереди на удаление
+var flagDeleteQueuePath string
+
+// flagFileCompactInterval период сжатия файла хранения
+var flagFileCompactInterval time.Duration
+
+// flagRateLimitShorten ограничение запросов к /api/shorten* в минуту
+var flagRateLimitShorten int
+
+// flagRateLimitCreate ограничение запросов к POST / в минуту
+var flagRateLimitCreate int
+
+// flagRateLimitRedirect ограничение переходов в минуту
+var flagRateLimitRedirect int
+
+// flagRateLimitAuth ограничение попыток регистрации и входа в минуту
+var flagRateLimitAuth int
+
+// flagRateLimitRedisURL адрес Redis для общих счетчиков
+var flagRateLimitRedisURL string
+
+// flagURLMaxLength максимальная длина исходной ссылки
+var flagURLMaxLength int
+
+// flagURLKeepFragment сохранять ли фрагмент исходной ссылки
+var flagURLKeepFragment bool
+
+// flagURLSortQuery сортировать ли параметры запроса исходной ссылки
+var flagURLSortQuery bool
+
+// flagDestinationPolicyFile файл со списками доменов
+var flagDestinationPolicyFile string
+
+// flagDestinationPolicyReload период проверки файла со списками доменов
+var flagDestinationPolicyReload time.Duration
+
+// flagBlockPrivateDestinations отказывать ли ссылкам на внутренние адреса
+var flagBlockPrivateDestinations bool
+
+// flagCheckDestinationOnRedirect проверять ли ссылку при переходе
+var flagCheckDestinationOnRedirect bool
+
 // ParseFlags обрабатывает аргументы командной строки
 // и сохраняет их значения в соответствующих переменных
 func ParseFlags() Configuration {
@@ -144,12 +386,228 @@ func ParseFlags() Configuration {
 		flag.StringVar(&flagTrustedSubnet, "t", "", "CIDR")
 	}
 
+	// регистрируем переменную flagTrustedProxies
+	// как аргумент -trusted-proxies с пустым значением по умолчанию
+	if flag.Lookup("trusted-proxies") == nil {
+		flag.StringVar(&flagTrustedProxies, "trusted-proxies", "", "comma separated CIDRs of proxies trusted to set X-Forwarded-For")
+	}
+
 	// регистрируем переменную flagGrpcPort
 	// как аргумент -g со значением по умолчанию 5050
 	if flag.Lookup("g") == nil {
 		flag.IntVar(&flagGrpcPort, "g", 5050, "gRPC port")
 	}
 
+	// регистрируем переменную flagRedisURL
+	// как аргумент -r с пустым значением по умолчанию
+	if flag.Lookup("r") == nil {
+		flag.StringVar(&flagRedisURL, "r", "", "redis URL")
+	}
+
+	// регистрируем переменную flagShortKeyGenerator
+	// как аргумент -key-generator с пустым значением по умолчанию
+	if flag.Lookup("key-generator") == nil {
+		flag.StringVar(&flagShortKeyGenerator, "key-generator", "", "short key generator: random, sequential or hash")
+	}
+
+	// регистрируем переменную flagShortKeyLength
+	// как аргумент -key-length с нулевым значением по умолчанию
+	if flag.Lookup("key-length") == nil {
+		flag.IntVar(&flagShortKeyLength, "key-length", 0, "short key length")
+	}
+
+	// регистрируем переменную flagShortKeyAlphabet
+	// как аргумент -key-alphabet с пустым значением по умолчанию
+	if flag.Lookup("key-alphabet") == nil {
+		flag.StringVar(&flagShortKeyAlphabet, "key-alphabet", "", "short key alphabet")
+	}
+
+	// регистрируем переменную flagCacheEnabled
+	// как аргумент -cache с ложным значением по умолчанию
+	if flag.Lookup("cache") == nil {
+		flag.BoolVar(&flagCacheEnabled, "cache", false, "enable short links cache")
+	}
+
+	// регистрируем переменную flagCacheSize
+	// как аргумент -cache-size с нулевым значением по умолчанию
+	if flag.Lookup("cache-size") == nil {
+		flag.IntVar(&flagCacheSize, "cache-size", 0, "short links cache size")
+	}
+
+	// регистрируем переменную flagCacheTTL
+	// как аргумент -cache-ttl с нулевым значением по умолчанию
+	if flag.Lookup("cache-ttl") == nil {
+		flag.DurationVar(&flagCacheTTL, "cache-ttl", 0, "short links cache TTL")
+	}
+
+	// регистрируем переменную flagMetricsAddr
+	// как аргумент -metrics-addr с пустым значением по умолчанию
+	if flag.Lookup("metrics-addr") == nil {
+		flag.StringVar(&flagMetricsAddr, "metrics-addr", "", "address and port to serve metrics separately")
+	}
+
+	// регистрируем переменную flagTracingEnabled
+	// как аргумент -tracing с ложным значением по умолчанию
+	if flag.Lookup("tracing") == nil {
+		flag.BoolVar(&flagTracingEnabled, "tracing", false, "enable tracing")
+	}
+
+	// регистрируем переменную flagOTLPEndpoint
+	// как аргумент -otlp-endpoint с пустым значением по умолчанию
+	if flag.Lookup("otlp-endpoint") == nil {
+		flag.StringVar(&flagOTLPEndpoint, "otlp-endpoint", "", "OTLP gRPC collector endpoint")
+	}
+
+	// регистрируем переменную flagTraceFile
+	// как аргумент -trace-file с пустым значением по умолчанию
+	if flag.Lookup("trace-file") == nil {
+		flag.StringVar(&flagTraceFile, "trace-file", "", "file to write spans when OTLP endpoint is not set")
+	}
+
+	// регистрируем переменную flagTLSCertFile
+	// как аргумент -tls-cert с пустым значением по умолчанию
+	if flag.Lookup("tls-cert") == nil {
+		flag.StringVar(&flagTLSCertFile, "tls-cert", "", "HTTPS certificate file")
+	}
+
+	// регистрируем переменную flagTLSKeyFile
+	// как аргумент -tls-key с пустым значением по умолчанию
+	if flag.Lookup("tls-key") == nil {
+		flag.StringVar(&flagTLSKeyFile, "tls-key", "", "HTTPS private key file")
+	}
+
+	// регистрируем переменную flagGrpcTLSCertFile
+	// как аргумент -grpc-tls-cert с пустым значением по умолчанию
+	if flag.Lookup("grpc-tls-cert") == nil {
+		flag.StringVar(&flagGrpcTLSCertFile, "grpc-tls-cert", "", "gRPC certificate file, enables TLS")
+	}
+
+	// регистрируем переменную flagGrpcTLSKeyFile
+	// как аргумент -grpc-tls-key с пустым значением по умолчанию
+	if flag.Lookup("grpc-tls-key") == nil {
+		flag.StringVar(&flagGrpcTLSKeyFile, "grpc-tls-key", "", "gRPC private key file")
+	}
+
+	// регистрируем переменную flagGrpcClientCAFile
+	// как аргумент -grpc-client-ca с пустым значением по умолчанию
+	if flag.Lookup("grpc-client-ca") == nil {
+		flag.StringVar(&flagGrpcClientCAFile, "grpc-client-ca", "", "CA bundle to verify gRPC client certificates, enables mTLS")
+	}
+
+	// регистрируем переменную flagAutocertEnabled
+	// как аргумент -autocert с ложным значением по умолчанию
+	if flag.Lookup("autocert") == nil {
+		flag.BoolVar(&flagAutocertEnabled, "autocert", false, "obtain HTTPS certificate via ACME")
+	}
+
+	// регистрируем переменную flagACMEDirectoryURL
+	// как аргумент -acme-directory с пустым значением по умолчанию
+	if flag.Lookup("acme-directory") == nil {
+		flag.StringVar(&flagACMEDirectoryURL, "acme-directory", "", "ACME directory URL, Let's Encrypt by default")
+	}
+
+	// регистрируем переменную flagACMECAFile
+	// как аргумент -acme-ca с пустым значением по умолчанию
+	if flag.Lookup("acme-ca") == nil {
+		flag.StringVar(&flagACMECAFile, "acme-ca", "", "CA bundle to trust the ACME directory")
+	}
+
+	// регистрируем переменную flagAutocertCacheDir
+	// как аргумент -autocert-cache с пустым значением по умолчанию
+	if flag.Lookup("autocert-cache") == nil {
+		flag.StringVar(&flagAutocertCacheDir, "autocert-cache", "", "directory to cache ACME certificates")
+	}
+
+	// регистрируем переменную flagHTTPRedirectAddr
+	// как аргумент -redirect-addr с пустым значением по умолчанию
+	if flag.Lookup("redirect-addr") == nil {
+		flag.StringVar(&flagHTTPRedirectAddr, "redirect-addr", "", "address and port to redirect HTTP to HTTPS")
+	}
+
+	// регистрируем переменную flagDeleteQueuePath
+	// как аргумент -delete-queue с пустым значением по умолчанию
+	if flag.Lookup("delete-queue") == nil {
+		flag.StringVar(&flagDeleteQueuePath, "delete-queue", "", "pending deletions journal path")
+	}
+
+	// регистрируем переменную flagFileCompactInterval
+	// как аргумент -compact-interval с нулевым значением по умолчанию
+	if flag.Lookup("compact-interval") == nil {
+		flag.DurationVar(&flagFileCompactInterval, "compact-interval", 0, "db file compaction interval")
+	}
+
+	// регистрируем переменную flagRateLimitShorten
+	// как аргумент -rate-limit-shorten с нулевым значением по умолчанию
+	if flag.Lookup("rate-limit-shorten") == nil {
+		flag.IntVar(&flagRateLimitShorten, "rate-limit-shorten", 0, "requests per minute to /api/shorten*, negative disables")
+	}
+
+	// регистрируем переменную flagRateLimitCreate
+	// как аргумент -rate-limit-create с нулевым значением по умолчанию
+	if flag.Lookup("rate-limit-create") == nil {
+		flag.IntVar(&flagRateLimitCreate, "rate-limit-create", 0, "requests per minute to POST /, negative disables")
+	}
+
+	// регистрируем переменную flagRateLimitRedirect
+	// как аргумент -rate-limit-redirect с нулевым значением по умолчанию
+	if flag.Lookup("rate-limit-redirect") == nil {
+		flag.IntVar(&flagRateLimitRedirect, "rate-limit-redirect", 0, "redirects per minute, negative disables")
+	}
+
+	// регистрируем переменную flagRateLimitAuth
+	// как аргумент -rate-limit-auth с нулевым значением по умолчанию
+	if flag.Lookup("rate-limit-auth") == nil {
+		flag.IntVar(&flagRateLimitAuth, "rate-limit-auth", 0, "sign up and sign in attempts per minute, negative disables")
+	}
+
+	// регистрируем переменную flagRateLimitRedisURL
+	// как аргумент -rate-limit-redis с пустым значением по умолчанию
+	if flag.Lookup("rate-limit-redis") == nil {
+		flag.StringVar(&flagRateLimitRedisURL, "rate-limit-redis", "", "redis url for rate limits shared by instances")
+	}
+
+	// регистрируем переменную flagURLMaxLength
+	// как аргумент -url-max-length с нулевым значением по умолчанию
+	if flag.Lookup("url-max-length") == nil {
+		flag.IntVar(&flagURLMaxLength, "url-max-length", 0, "max length of original url")
+	}
+
+	// регистрируем переменную flagURLKeepFragment
+	// как аргумент -url-keep-fragment с ложным значением по умолчанию
+	if flag.Lookup("url-keep-fragment") == nil {
+		flag.BoolVar(&flagURLKeepFragment, "url-keep-fragment", false, "keep fragment of original url")
+	}
+
+	// регистрируем переменную flagURLSortQuery
+	// как аргумент -url-sort-query с ложным значением по умолчанию
+	if flag.Lookup("url-sort-query") == nil {
+		flag.BoolVar(&flagURLSortQuery, "url-sort-query", false, "sort query parameters of original url")
+	}
+
+	// регистрируем переменную flagDestinationPolicyFile
+	// как аргумент -destination-policy с пустым значением по умолчанию
+	if flag.Lookup("destination-policy") == nil {
+		flag.StringVar(&flagDestinationPolicyFile, "destination-policy", "", "json file with blocked and allowed domains")
+	}
+
+	// регистрируем переменную flagDestinationPolicyReload
+	// как аргумент -destination-policy-reload с нулевым значением по умолчанию
+	if flag.Lookup("destination-policy-reload") == nil {
+		flag.DurationVar(&flagDestinationPolicyReload, "destination-policy-reload", 0, "destination policy file reload interval")
+	}
+
+	// регистрируем переменную flagBlockPrivateDestinations
+	// как аргумент -block-private-destinations с ложным значением по умолчанию
+	if flag.Lookup("block-private-destinations") == nil {
+		flag.BoolVar(&flagBlockPrivateDestinations, "block-private-destinations", false, "refuse urls resolving to private networks")
+	}
+
+	// регистрируем переменную flagCheckDestinationOnRedirect
+	// как аргумент -check-destination-on-redirect с ложным значением по умолчанию
+	if flag.Lookup("check-destination-on-redirect") == nil {
+		flag.BoolVar(&flagCheckDestinationOnRedirect, "check-destination-on-redirect", false, "check destination policy on redirect")
+	}
+
 	// парсим переданные серверу аргументы в зарегистрированные переменные
 	flag.Parse()
 
@@ -213,6 +671,13 @@ func ParseFlags() Configuration {
 		flagTrustedSubnet = envTrustedSubnet
 	}
 
+	// для случаев, когда в переменной окружения TRUSTED_PROXIES присутствует значение,
+	// переопределим сети доверенных прокси,
+	// даже если они были переданы через аргумент командной строки
+	if envTrustedProxies, isExist := os.LookupEnv("TRUSTED_PROXIES"); isExist {
+		flagTrustedProxies = envTrustedProxies
+	}
+
 	// для случаев, когда в переменной окружения GRPC_PORT присутствует значение,
 	// переопределим порт для старта GRPC,
 	// даже если он был передан через аргумент командной строки
@@ -220,6 +685,251 @@ func ParseFlags() Configuration {
 		flagGrpcPort, _ = strconv.Atoi(envGrpcPort)
 	}
 
+	// для случаев, когда в переменной окружения REDIS_URL присутствует значение,
+	// переопределим подключение к redis,
+	// даже если он был передан через аргумент командной строки
+	if envRedisURL, isExist := os.LookupEnv("REDIS_URL"); isExist {
+		flagRedisURL = envRedisURL
+	}
+
+	// для случаев, когда в переменной окружения SHORT_KEY_GENERATOR присутствует значение,
+	// переопределим способ генерации коротких ключей,
+	// даже если он был передан через аргумент командной строки
+	if envShortKeyGenerator, isExist := os.LookupEnv("SHORT_KEY_GENERATOR"); isExist {
+		flagShortKeyGenerator = envShortKeyGenerator
+	}
+
+	// для случаев, когда в переменной окружения SHORT_KEY_LENGTH присутствует значение,
+	// переопределим длину коротких ключей,
+	// даже если он был передан через аргумент командной строки
+	if envShortKeyLength, isExist := os.LookupEnv("SHORT_KEY_LENGTH"); isExist {
+		flagShortKeyLength, _ = strconv.Atoi(envShortKeyLength)
+	}
+
+	// для случаев, когда в переменной окружения SHORT_KEY_ALPHABET присутствует значение,
+	// переопределим алфавит коротких ключей,
+	// даже если он был передан через аргумент командной строки
+	if envShortKeyAlphabet, isExist := os.LookupEnv("SHORT_KEY_ALPHABET"); isExist {
+		flagShortKeyAlphabet = envShortKeyAlphabet
+	}
+
+	// для случаев, когда в переменной окружения CACHE_ENABLED присутствует значение,
+	// переопределим включение кеша,
+	// даже если он был передан через аргумент командной строки
+	if envCacheEnabled, isExist := os.LookupEnv("CACHE_ENABLED"); isExist {
+		flagCacheEnabled = envCacheEnabled == "true" || envCacheEnabled == "1"
+	}
+
+	// для случаев, когда в переменной окружения CACHE_SIZE присутствует значение,
+	// переопределим размер кеша,
+	// даже если он был передан через аргумент командной строки
+	if envCacheSize, isExist := os.LookupEnv("CACHE_SIZE"); isExist {
+		flagCacheSize, _ = strconv.Atoi(envCacheSize)
+	}
+
+	// для случаев, когда в переменной окружения CACHE_TTL присутствует значение,
+	// переопределим время жизни ссылки в кеше,
+	// даже если он был передан через аргумент командной строки
+	if envCacheTTL, isExist := os.LookupEnv("CACHE_TTL"); isExist {
+		flagCacheTTL, _ = time.ParseDuration(envCacheTTL)
+	}
+
+	// для случаев, когда в переменной окружения METRICS_ADDRESS присутствует значение,
+	// переопределим адрес сервера метрик,
+	// даже если он был передан через аргумент командной строки
+	if envMetricsAddr, isExist := os.LookupEnv("METRICS_ADDRESS"); isExist {
+		flagMetricsAddr = envMetricsAddr
+	}
+
+	// для случаев, когда в переменной окружения TRACING_ENABLED присутствует значение,
+	// переопределим включение трассировки,
+	// даже если он был передан через аргумент командной строки
+	if envTracingEnabled, isExist := os.LookupEnv("TRACING_ENABLED"); isExist {
+		flagTracingEnabled = envTracingEnabled == "true" || envTracingEnabled == "1"
+	}
+
+	// для случаев, когда в переменной окружения OTEL_EXPORTER_OTLP_ENDPOINT присутствует значение,
+	// переопределим адрес коллектора,
+	// даже если он был передан через аргумент командной строки
+	if envOTLPEndpoint, isExist := os.LookupEnv("OTEL_EXPORTER_OTLP_ENDPOINT"); isExist {
+		flagOTLPEndpoint = envOTLPEndpoint
+	}
+
+	// для случаев, когда в переменной окружения TRACE_FILE присутствует значение,
+	// переопределим файл трассировок,
+	// даже если он был передан через аргумент командной строки
+	if envTraceFile, isExist := os.LookupEnv("TRACE_FILE"); isExist {
+		flagTraceFile = envTraceFile
+	}
+
+	// для случаев, когда в переменной окружения TLS_CERT_FILE присутствует значение,
+	// переопределим сертификат HTTPS,
+	// даже если он был передан через аргумент командной строки
+	if envTLSCertFile, isExist := os.LookupEnv("TLS_CERT_FILE"); isExist {
+		flagTLSCertFile = envTLSCertFile
+	}
+
+	// для случаев, когда в переменной окружения TLS_KEY_FILE присутствует значение,
+	// переопределим ключ HTTPS,
+	// даже если он был передан через аргумент командной строки
+	if envTLSKeyFile, isExist := os.LookupEnv("TLS_KEY_FILE"); isExist {
+		flagTLSKeyFile = envTLSKeyFile
+	}
+
+	// для случаев, когда в переменной окружения GRPC_TLS_CERT_FILE присутствует значение,
+	// переопределим сертификат gRPC,
+	// даже если он был передан через аргумент командной строки
+	if envGrpcTLSCertFile, isExist := os.LookupEnv("GRPC_TLS_CERT_FILE"); isExist {
+		flagGrpcTLSCertFile = envGrpcTLSCertFile
+	}
+
+	// для случаев, когда в переменной окружения GRPC_TLS_KEY_FILE присутствует значение,
+	// переопределим ключ gRPC,
+	// даже если он был передан через аргумент командной строки
+	if envGrpcTLSKeyFile, isExist := os.LookupEnv("GRPC_TLS_KEY_FILE"); isExist {
+		flagGrpcTLSKeyFile = envGrpcTLSKeyFile
+	}
+
+	// для случаев, когда в переменной окружения GRPC_CLIENT_CA_FILE присутствует значение,
+	// переопределим CA клиентских сертификатов gRPC,
+	// даже если он был передан через аргумент командной строки
+	if envGrpcClientCAFile, isExist := os.LookupEnv("GRPC_CLIENT_CA_FILE"); isExist {
+		flagGrpcClientCAFile = envGrpcClientCAFile
+	}
+
+	// для случаев, когда в переменной окружения AUTOCERT_ENABLED присутствует значение,
+	// переопределим получение сертификата по ACME,
+	// даже если он был передан через аргумент командной строки
+	if envAutocertEnabled, isExist := os.LookupEnv("AUTOCERT_ENABLED"); isExist {
+		flagAutocertEnabled = envAutocertEnabled == "true" || envAutocertEnabled == "1"
+	}
+
+	// для случаев, когда в переменной окружения ACME_DIRECTORY_URL присутствует значение,
+	// переопределим адрес каталога ACME,
+	// даже если он был передан через аргумент командной строки
+	if envACMEDirectoryURL, isExist := os.LookupEnv("ACME_DIRECTORY_URL"); isExist {
+		flagACMEDirectoryURL = envACMEDirectoryURL
+	}
+
+	// для случаев, когда в переменной окружения ACME_CA_FILE присутствует значение,
+	// переопределим CA сервера ACME,
+	// даже если он был передан через аргумент командной строки
+	if envACMECAFile, isExist := os.LookupEnv("ACME_CA_FILE"); isExist {
+		flagACMECAFile = envACMECAFile
+	}
+
+	// для случаев, когда в переменной окружения AUTOCERT_CACHE_DIR присутствует значение,
+	// переопределим каталог для сертификатов,
+	// даже если он был передан через аргумент командной строки
+	if envAutocertCacheDir, isExist := os.LookupEnv("AUTOCERT_CACHE_DIR"); isExist {
+		flagAutocertCacheDir = envAutocertCacheDir
+	}
+
+	// для случаев, когда в переменной окружения HTTP_REDIRECT_ADDRESS присутствует значение,
+	// переопределим адрес сервера перенаправления,
+	// даже если он был передан через аргумент командной строки
+	if envHTTPRedirectAddr, isExist := os.LookupEnv("HTTP_REDIRECT_ADDRESS"); isExist {
+		flagHTTPRedirectAddr = envHTTPRedirectAddr
+	}
+
+	// для случаев, когда в переменной окружения DELETE_QUEUE_PATH присутствует значение,
+	// переопределим путь до журнала очереди на удаление,
+	// даже если он был передан через аргумент командной строки
+	if envDeleteQueuePath, isExist := os.LookupEnv("DELETE_QUEUE_PATH"); isExist {
+		flagDeleteQueuePath = envDeleteQueuePath
+	}
+
+	// для случаев, когда в переменной окружения FILE_COMPACT_INTERVAL присутствует значение,
+	// переопределим период сжатия файла хранения,
+	// даже если он был передан через аргумент командной строки
+	if envFileCompactInterval, isExist := os.LookupEnv("FILE_COMPACT_INTERVAL"); isExist {
+		flagFileCompactInterval, _ = time.ParseDuration(envFileCompactInterval)
+	}
+
+	// для случаев, когда в переменной окружения RATE_LIMIT_SHORTEN присутствует значение,
+	// переопределим ограничение запросов к /api/shorten*,
+	// даже если оно было передано через аргумент командной строки
+	if envRateLimitShorten, isExist := os.LookupEnv("RATE_LIMIT_SHORTEN"); isExist {
+		flagRateLimitShorten, _ = strconv.Atoi(envRateLimitShorten)
+	}
+
+	// для случаев, когда в переменной окружения RATE_LIMIT_CREATE присутствует значение,
+	// переопределим ограничение запросов к POST /,
+	// даже если оно было передано через аргумент командной строки
+	if envRateLimitCreate, isExist := os.LookupEnv("RATE_LIMIT_CREATE"); isExist {
+		flagRateLimitCreate, _ = strconv.Atoi(envRateLimitCreate)
+	}
+
+	// для случаев, когда в переменной окружения RATE_LIMIT_REDIRECT присутствует значение,
+	// переопределим ограничение переходов,
+	// даже если оно было передано через аргумент командной строки
+	if envRateLimitRedirect, isExist := os.LookupEnv("RATE_LIMIT_REDIRECT"); isExist {
+		flagRateLimitRedirect, _ = strconv.Atoi(envRateLimitRedirect)
+	}
+
+	// для случаев, когда в переменной окружения RATE_LIMIT_AUTH присутствует значение,
+	// переопределим ограничение попыток регистрации и входа,
+	// даже если оно было передано через аргумент командной строки
+	if envRateLimitAuth, isExist := os.LookupEnv("RATE_LIMIT_AUTH"); isExist {
+		flagRateLimitAuth, _ = strconv.Atoi(envRateLimitAuth)
+	}
+
+	// для случаев, когда в переменной окружения RATE_LIMIT_REDIS_URL присутствует значение,
+	// переопределим адрес Redis для общих счетчиков,
+	// даже если он был передан через аргумент командной строки
+	if envRateLimitRedisURL, isExist := os.LookupEnv("RATE_LIMIT_REDIS_URL"); isExist {
+		flagRateLimitRedisURL = envRateLimitRedisURL
+	}
+
+	// для случаев, когда в переменной окружения URL_MAX_LENGTH присутствует значение,
+	// переопределим максимальную длину исходной ссылки,
+	// даже если она была передана через аргумент командной строки
+	if envURLMaxLength, isExist := os.LookupEnv("URL_MAX_LENGTH"); isExist {
+		flagURLMaxLength, _ = strconv.Atoi(envURLMaxLength)
+	}
+
+	// для случаев, когда в переменной окружения URL_KEEP_FRAGMENT присутствует значение,
+	// переопределим сохранение фрагмента исходной ссылки,
+	// даже если оно было передано через аргумент командной строки
+	if envURLKeepFragment, isExist := os.LookupEnv("URL_KEEP_FRAGMENT"); isExist {
+		flagURLKeepFragment = envURLKeepFragment == "true" || envURLKeepFragment == "1"
+	}
+
+	// для случаев, когда в переменной окружения URL_SORT_QUERY присутствует значение,
+	// переопределим сортировку параметров запроса исходной ссылки,
+	// даже если она была передана через аргумент командной строки
+	if envURLSortQuery, isExist := os.LookupEnv("URL_SORT_QUERY"); isExist {
+		flagURLSortQuery = envURLSortQuery == "true" || envURLSortQuery == "1"
+	}
+
+	// для случаев, когда в переменной окружения DESTINATION_POLICY_FILE присутствует значение,
+	// переопределим файл со списками доменов,
+	// даже если он был передан через аргумент командной строки
+	if envDestinationPolicyFile, isExist := os.LookupEnv("DESTINATION_POLICY_FILE"); isExist {
+		flagDestinationPolicyFile = envDestinationPolicyFile
+	}
+
+	// для случаев, когда в переменной окружения DESTINATION_POLICY_RELOAD присутствует значение,
+	// переопределим период проверки файла со списками доменов,
+	// даже если он был передан через аргумент командной строки
+	if envDestinationPolicyReload, isExist := os.LookupEnv("DESTINATION_POLICY_RELOAD"); isExist {
+		flagDestinationPolicyReload, _ = time.ParseDuration(envDestinationPolicyReload)
+	}
+
+	// для случаев, когда в переменной окружения BLOCK_PRIVATE_DESTINATIONS присутствует значение,
+	// переопределим запрет ссылок на внутренние адреса,
+	// даже если он был передан через аргумент командной строки
+	if envBlockPrivateDestinations, isExist := os.LookupEnv("BLOCK_PRIVATE_DESTINATIONS"); isExist {
+		flagBlockPrivateDestinations = envBlockPrivateDestinations == "true" || envBlockPrivateDestinations == "1"
+	}
+
+	// для случаев, когда в переменной окружения CHECK_DESTINATION_ON_REDIRECT присутствует значение,
+	// переопределим проверку ссылки при переходе,
+	// даже если она была передана через аргумент командной строки
+	if envCheckDestinationOnRedirect, isExist := os.LookupEnv("CHECK_DESTINATION_ON_REDIRECT"); isExist {
+		flagCheckDestinationOnRedirect = envCheckDestinationOnRedirect == "true" || envCheckDestinationOnRedirect == "1"
+	}
+
 	fileConfig := configFile{}
 	if flagConfig != "" {
 		fileConfig = getConfigFromFile(flagConfig)
@@ -249,9 +959,147 @@ func ParseFlags() Configuration {
 	if configuration.TrustedSubnet = flagTrustedSubnet; configuration.TrustedSubnet == "" {
 		configuration.TrustedSubnet = fileConfig.TrustedSubnet
 	}
+	if configuration.TrustedProxies = flagTrustedProxies; configuration.TrustedProxies == "" {
+		configuration.TrustedProxies = fileConfig.TrustedProxies
+	}
 	if configuration.GrpcPort = flagGrpcPort; configuration.GrpcPort == 0 {
 		configuration.GrpcPort = fileConfig.GrpcPort
 	}
+	if configuration.RedisURL = flagRedisURL; configuration.RedisURL == "" {
+		configuration.RedisURL = fileConfig.RedisURL
+	}
+	if configuration.ShortKeyGenerator = flagShortKeyGenerator; configuration.ShortKeyGenerator == "" {
+		configuration.ShortKeyGenerator = fileConfig.ShortKeyGenerator
+	}
+	if configuration.ShortKeyLength = flagShortKeyLength; configuration.ShortKeyLength == 0 {
+		configuration.ShortKeyLength = fileConfig.ShortKeyLength
+	}
+	if configuration.ShortKeyAlphabet = flagShortKeyAlphabet; configuration.ShortKeyAlphabet == "" {
+		configuration.ShortKeyAlphabet = fileConfig.ShortKeyAlphabet
+	}
+	if configuration.CacheEnabled = flagCacheEnabled; !configuration.CacheEnabled {
+		configuration.CacheEnabled = fileConfig.CacheEnabled
+	}
+	if configuration.CacheSize = flagCacheSize; configuration.CacheSize == 0 {
+		configuration.CacheSize = fileConfig.CacheSize
+	}
+	if configuration.CacheTTL = flagCacheTTL; configuration.CacheTTL == 0 {
+		configuration.CacheTTL, _ = time.ParseDuration(fileConfig.CacheTTL)
+	}
+	if configuration.MetricsAddr = flagMetricsAddr; configuration.MetricsAddr == "" {
+		configuration.MetricsAddr = fileConfig.MetricsAddress
+	}
+	if configuration.TracingEnabled = flagTracingEnabled; !configuration.TracingEnabled {
+		configuration.TracingEnabled = fileConfig.TracingEnabled
+	}
+	if configuration.OTLPEndpoint = flagOTLPEndpoint; configuration.OTLPEndpoint == "" {
+		configuration.OTLPEndpoint = fileConfig.OTLPEndpoint
+	}
+	if configuration.TraceFile = flagTraceFile; configuration.TraceFile == "" {
+		configuration.TraceFile = fileConfig.TraceFile
+	}
+	if configuration.TLSCertFile = flagTLSCertFile; configuration.TLSCertFile == "" {
+		configuration.TLSCertFile = fileConfig.TLSCertFile
+	}
+	if configuration.TLSCertFile == "" {
+		configuration.TLSCertFile = DefaultTLSCertFile
+	}
+	if configuration.TLSKeyFile = flagTLSKeyFile; configuration.TLSKeyFile == "" {
+		configuration.TLSKeyFile = fileConfig.TLSKeyFile
+	}
+	if configuration.TLSKeyFile == "" {
+		configuration.TLSKeyFile = DefaultTLSKeyFile
+	}
+	if configuration.GrpcTLSCertFile = flagGrpcTLSCertFile; configuration.GrpcTLSCertFile == "" {
+		configuration.GrpcTLSCertFile = fileConfig.GrpcTLSCertFile
+	}
+	if configuration.GrpcTLSKeyFile = flagGrpcTLSKeyFile; configuration.GrpcTLSKeyFile == "" {
+		configuration.GrpcTLSKeyFile = fileConfig.GrpcTLSKeyFile
+	}
+	if configuration.GrpcClientCAFile = flagGrpcClientCAFile; configuration.GrpcClientCAFile == "" {
+		configuration.GrpcClientCAFile = fileConfig.GrpcClientCAFile
+	}
+	if configuration.AutocertEnabled = flagAutocertEnabled; !configuration.AutocertEnabled {
+		configuration.AutocertEnabled = fileConfig.AutocertEnabled
+	}
+	if configuration.ACMEDirectoryURL = flagACMEDirectoryURL; configuration.ACMEDirectoryURL == "" {
+		configuration.ACMEDirectoryURL = fileConfig.ACMEDirectoryURL
+	}
+	if configuration.ACMECAFile = flagACMECAFile; configuration.ACMECAFile == "" {
+		configuration.ACMECAFile = fileConfig.ACMECAFile
+	}
+	if configuration.AutocertCacheDir = flagAutocertCacheDir; configuration.AutocertCacheDir == "" {
+		configuration.AutocertCacheDir = fileConfig.AutocertCacheDir
+	}
+	if configuration.AutocertCacheDir == "" {
+		configuration.AutocertCacheDir = DefaultAutocertCacheDir
+	}
+	if configuration.HTTPRedirectAddr = flagHTTPRedirectAddr; configuration.HTTPRedirectAddr == "" {
+		configuration.HTTPRedirectAddr = fileConfig.HTTPRedirectAddress
+	}
+	if configuration.DeleteQueuePath = flagDeleteQueuePath; configuration.DeleteQueuePath == "" {
+		configuration.DeleteQueuePath = fileConfig.DeleteQueuePath
+	}
+	if configuration.DeleteQueuePath == "" {
+		configuration.DeleteQueuePath = DefaultDeleteQueuePath
+	}
+	if configuration.FileCompactInterval = flagFileCompactInterval; configuration.FileCompactInterval == 0 {
+		configuration.FileCompactInterval, _ = time.ParseDuration(fileConfig.FileCompactInterval)
+	}
+	if configuration.FileCompactInterval == 0 {
+		configuration.FileCompactInterval = DefaultFileCompactInterval
+	}
+	if configuration.RateLimitShorten = flagRateLimitShorten; configuration.RateLimitShorten == 0 {
+		configuration.RateLimitShorten = fileConfig.RateLimitShorten
+	}
+	if configuration.RateLimitShorten == 0 {
+		configuration.RateLimitShorten = DefaultRateLimitShorten
+	}
+	if configuration.RateLimitCreate = flagRateLimitCreate; configuration.RateLimitCreate == 0 {
+		configuration.RateLimitCreate = fileConfig.RateLimitCreate
+	}
+	if configuration.RateLimitCreate == 0 {
+		configuration.RateLimitCreate = DefaultRateLimitCreate
+	}
+	if configuration.RateLimitRedirect = flagRateLimitRedirect; configuration.RateLimitRedirect == 0 {
+		configuration.RateLimitRedirect = fileConfig.RateLimitRedirect
+	}
+	if configuration.RateLimitRedirect == 0 {
+		configuration.RateLimitRedirect = DefaultRateLimitRedirect
+	}
+	if configuration.RateLimitAuth = flagRateLimitAuth; configuration.RateLimitAuth == 0 {
+		configuration.RateLimitAuth = fileConfig.RateLimitAuth
+	}
+	if configuration.RateLimitAuth == 0 {
+		configuration.RateLimitAuth = DefaultRateLimitAuth
+	}
+	if configuration.RateLimitRedisURL = flagRateLimitRedisURL; configuration.RateLimitRedisURL == "" {
+		configuration.RateLimitRedisURL = fileConfig.RateLimitRedisURL
+	}
+	if configuration.URLMaxLength = flagURLMaxLength; configuration.URLMaxLength == 0 {
+		configuration.URLMaxLength = fileConfig.URLMaxLength
+	}
+	if configuration.URLKeepFragment = flagURLKeepFragment; !configuration.URLKeepFragment {
+		configuration.URLKeepFragment = fileConfig.URLKeepFragment
+	}
+	if configuration.URLSortQuery = flagURLSortQuery; !configuration.URLSortQuery {
+		configuration.URLSortQuery = fileConfig.URLSortQuery
+	}
+	if configuration.DestinationPolicyFile = flagDestinationPolicyFile; configuration.DestinationPolicyFile == "" {
+		configuration.DestinationPolicyFile = fileConfig.DestinationPolicyFile
+	}
+	if configuration.DestinationPolicyReload = flagDestinationPolicyReload; configuration.DestinationPolicyReload == 0 {
+		configuration.DestinationPolicyReload, _ = time.ParseDuration(fileConfig.DestinationPolicyReload)
+	}
+	if configuration.DestinationPolicyReload == 0 {
+		configuration.DestinationPolicyReload = DefaultDestinationPolicyReload
+	}
+	if configuration.BlockPrivateDestinations = flagBlockPrivateDestinations; !configuration.BlockPrivateDestinations {
+		configuration.BlockPrivateDestinations = fileConfig.BlockPrivateDestinations
+	}
+	if configuration.CheckDestinationOnRedirect = flagCheckDestinationOnRedirect; !configuration.CheckDestinationOnRedirect {
+		configuration.CheckDestinationOnRedirect = fileConfig.CheckDestinationOnRedirect
+	}
 
 	return configuration
 }
@@ -0,0 +1,84 @@
+package interceptors
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ShukinDmitriy/shortener/internal/auth"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// AuthConfig configuration of the auth interceptor
+type AuthConfig struct {
+	Skipper Skipper
+	// AnonymousMethods can be called without a token, an invalid token is still rejected
+	AnonymousMethods []string
+}
+
+// UnaryAuth interceptor for taking the user from the access token in metadata.
+// The user is available to handlers through auth.UserIDFromContext.
+func UnaryAuth(config AuthConfig) grpc.UnaryServerInterceptor {
+	skipper, anonymous := config.prepare()
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if skipper(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		ctx, err := authenticate(ctx, isAnonymous(anonymous, info.FullMethod))
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamAuth interceptor for taking the user from the access token in stream metadata
+func StreamAuth(config AuthConfig) grpc.StreamServerInterceptor {
+	skipper, anonymous := config.prepare()
+
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if skipper(info.FullMethod) {
+			return handler(srv, ss)
+		}
+
+		ctx, err := authenticate(ss.Context(), isAnonymous(anonymous, info.FullMethod))
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func (config AuthConfig) prepare() (Skipper, map[string]struct{}) {
+	skipper := config.Skipper
+	if skipper == nil {
+		skipper = DefaultSkipper
+	}
+
+	return skipper, methodSet(config.AnonymousMethods)
+}
+
+func isAnonymous(anonymous map[string]struct{}, fullMethod string) bool {
+	_, ok := anonymous[fullMethod]
+	return ok
+}
+
+// authenticate кладет пользователя из токена в контекст
+func authenticate(ctx context.Context, anonymous bool) (context.Context, error) {
+	user, err := auth.UserFromMetadata(ctx)
+	if errors.Is(err, auth.ErrTokenMissing) && anonymous {
+		return ctx, nil
+	}
+	if err != nil {
+		zap.L().Debug("unauthenticated request", zap.Error(err))
+		return ctx, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	return auth.ContextWithUser(ctx, user), nil
+}
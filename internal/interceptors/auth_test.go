@@ -0,0 +1,127 @@
+package interceptors_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ShukinDmitriy/shortener/internal/auth"
+	"github.com/ShukinDmitriy/shortener/internal/interceptors"
+	pb "github.com/ShukinDmitriy/shortener/proto"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// userToken токен пользователя, подписанный тем же ключом, что и для HTTP
+func userToken(t *testing.T, userID string) string {
+	token := jwt.NewWithClaims(auth.GetSigningMethod(), &auth.Claims{
+		ID: userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	})
+
+	tokenString, err := token.SignedString([]byte(auth.GetJWTSecret()))
+	require.NoError(t, err)
+
+	return tokenString
+}
+
+func TestUnaryAuth(t *testing.T) {
+	type want struct {
+		code   codes.Code
+		userID string
+	}
+	tests := []struct {
+		name   string
+		config interceptors.AuthConfig
+		token  string
+		want   want
+	}{
+		{
+			name:  "positive test #1",
+			token: userToken(t, "testUserID"),
+			want: want{
+				userID: "testUserID",
+			},
+		},
+		{
+			name: "positive test #2",
+			config: interceptors.AuthConfig{
+				AnonymousMethods: []string{pb.URL_Redirect_FullMethodName},
+			},
+			want: want{},
+		},
+		{
+			name: "positive test #3",
+			config: interceptors.AuthConfig{
+				Skipper: interceptors.MethodsSkipper(pb.URL_Redirect_FullMethodName),
+			},
+			token: "invalid",
+			want:  want{},
+		},
+		{
+			name: "negative test #1",
+			want: want{
+				code: codes.Unauthenticated,
+			},
+		},
+		{
+			name: "negative test #2",
+			config: interceptors.AuthConfig{
+				AnonymousMethods: []string{pb.URL_Redirect_FullMethodName},
+			},
+			token: "invalid",
+			want: want{
+				code: codes.Unauthenticated,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newClient(t, &urlServer{}, grpc.UnaryInterceptor(interceptors.UnaryAuth(tt.config)))
+
+			ctx := context.Background()
+			if tt.token != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, auth.GetAuthorizationMetadataKey(), "Bearer "+tt.token)
+			}
+
+			resp, err := client.Redirect(ctx, &pb.RedirectRequest{})
+			if tt.want.code != codes.OK {
+				assert.Equal(t, tt.want.code, status.Code(err))
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want.userID, resp.RedirectUrl)
+		})
+	}
+}
+
+func TestStreamAuth(t *testing.T) {
+	interceptor := interceptors.StreamAuth(interceptors.AuthConfig{})
+	info := &grpc.StreamServerInfo{FullMethod: "/shortener.URL/Stream"}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		auth.GetAuthorizationMetadataKey(), "Bearer "+userToken(t, "testUserID"),
+	))
+
+	var userID string
+	err := interceptor(nil, &serverStream{ctx: ctx}, info, func(_ any, ss grpc.ServerStream) error {
+		userID = auth.UserIDFromContext(ss.Context())
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "testUserID", userID)
+
+	err = interceptor(nil, &serverStream{ctx: context.Background()}, info, func(any, grpc.ServerStream) error {
+		return nil
+	})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
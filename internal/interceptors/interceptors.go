@@ -0,0 +1,47 @@
+// Package interceptors gRPC server interceptors
+package interceptors
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// Skipper defines a function to skip an interceptor for the method
+type Skipper func(fullMethod string) bool
+
+// DefaultSkipper processes every method
+func DefaultSkipper(string) bool {
+	return false
+}
+
+// MethodsSkipper skips the listed methods
+func MethodsSkipper(methods ...string) Skipper {
+	set := methodSet(methods)
+
+	return func(fullMethod string) bool {
+		_, ok := set[fullMethod]
+		return ok
+	}
+}
+
+// methodSet множество полных имен методов
+func methodSet(methods []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(methods))
+	for _, method := range methods {
+		set[method] = struct{}{}
+	}
+
+	return set
+}
+
+// serverStream поток с подмененным контекстом
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the replaced context
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
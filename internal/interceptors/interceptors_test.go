@@ -0,0 +1,89 @@
+package interceptors_test
+
+import (
+	"context"
+	"log"
+	"net"
+	"testing"
+
+	"github.com/ShukinDmitriy/shortener/internal/auth"
+	pb "github.com/ShukinDmitriy/shortener/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// urlServer сервис для проверки перехватчиков, возвращает пользователя из контекста
+type urlServer struct {
+	pb.UnimplementedURLServer
+
+	handler func(ctx context.Context) error
+}
+
+func (s *urlServer) Redirect(ctx context.Context, _ *pb.RedirectRequest) (*pb.RedirectResponse, error) {
+	if s.handler != nil {
+		if err := s.handler(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return &pb.RedirectResponse{
+		Status:      "ok",
+		RedirectUrl: auth.UserIDFromContext(ctx),
+	}, nil
+}
+
+func (s *urlServer) GetStats(ctx context.Context, _ *pb.GetStatsRequest) (*pb.GetStatsResponse, error) {
+	return &pb.GetStatsResponse{
+		Status: "ok",
+	}, nil
+}
+
+// newClient клиент к серверу с перехватчиками поверх bufconn
+func newClient(t *testing.T, server *urlServer, opts ...grpc.ServerOption) pb.URLClient {
+	listener := bufconn.Listen(1024 * 1024)
+
+	grpcServer := grpc.NewServer(opts...)
+	pb.RegisterURLServer(grpcServer, server)
+
+	go func() {
+		if err := grpcServer.Serve(listener); err != nil {
+			log.Fatal(err)
+		}
+	}()
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough://bufnet",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to dial bufnet: %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	return pb.NewURLClient(conn)
+}
+
+// serverStream поток для проверки потоковых перехватчиков
+type serverStream struct {
+	grpc.ServerStream
+
+	ctx    context.Context
+	header metadata.MD
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *serverStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
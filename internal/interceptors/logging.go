@@ -0,0 +1,76 @@
+package interceptors
+
+import (
+	"context"
+	"time"
+
+	"github.com/ShukinDmitriy/shortener/internal/logger"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// LoggingConfig configuration of the logging interceptor
+type LoggingConfig struct {
+	Skipper Skipper
+	Logger  logger.Logger
+}
+
+// UnaryLogging interceptor for logging requests the same way as the HTTP RequestInfo middleware
+func UnaryLogging(config LoggingConfig) grpc.UnaryServerInterceptor {
+	config = config.withDefaults()
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if config.Skipper(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		requestStart := time.Now()
+		resp, err := handler(ctx, req)
+		config.log(info.FullMethod, requestStart, err)
+
+		return resp, err
+	}
+}
+
+// StreamLogging interceptor for logging streaming requests
+func StreamLogging(config LoggingConfig) grpc.StreamServerInterceptor {
+	config = config.withDefaults()
+
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if config.Skipper(info.FullMethod) {
+			return handler(srv, ss)
+		}
+
+		requestStart := time.Now()
+		err := handler(srv, ss)
+		config.log(info.FullMethod, requestStart, err)
+
+		return err
+	}
+}
+
+func (config LoggingConfig) withDefaults() LoggingConfig {
+	if config.Skipper == nil {
+		config.Skipper = DefaultSkipper
+	}
+	if config.Logger == nil {
+		config.Logger = zap.L()
+	}
+
+	return config
+}
+
+// log записывает результат обработки запроса
+func (config LoggingConfig) log(fullMethod string, requestStart time.Time, err error) {
+	if err != nil {
+		config.Logger.Error("error process gRPC request", zap.String("err", err.Error()))
+	}
+
+	duration := time.Since(requestStart)
+	config.Logger.Info("got incoming gRPC request",
+		zap.String("method", fullMethod),
+		zap.String("code", status.Code(err).String()),
+		zap.String("duration", duration.String()),
+	)
+}
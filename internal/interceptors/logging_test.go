@@ -0,0 +1,109 @@
+package interceptors_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ShukinDmitriy/shortener/internal/interceptors"
+	logger2 "github.com/ShukinDmitriy/shortener/mocks/internal_/logger"
+	pb "github.com/ShukinDmitriy/shortener/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryLogging(t *testing.T) {
+	type want struct {
+		infoCallCount  int
+		errorCallCount int
+	}
+	tests := []struct {
+		name    string
+		err     error
+		skipper interceptors.Skipper
+		want    want
+	}{
+		{
+			name: "positive test #1",
+			want: want{
+				infoCallCount: 1,
+			},
+		},
+		{
+			name: "positive test #2",
+			skipper: interceptors.MethodsSkipper(
+				pb.URL_Redirect_FullMethodName,
+			),
+			want: want{},
+		},
+		{
+			name: "negative test #1",
+			err:  status.Error(codes.NotFound, "URL not found"),
+			want: want{
+				infoCallCount:  1,
+				errorCallCount: 1,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockLogger := new(logger2.Logger)
+			mockLogger.EXPECT().Info(
+				"got incoming gRPC request",
+				mock.AnythingOfType("zapcore.Field"),
+				mock.AnythingOfType("zapcore.Field"),
+				mock.AnythingOfType("zapcore.Field"),
+			).Return().Maybe()
+			mockLogger.EXPECT().Error(
+				"error process gRPC request",
+				mock.AnythingOfType("zapcore.Field"),
+			).Return().Maybe()
+
+			client := newClient(t, &urlServer{
+				handler: func(context.Context) error {
+					return tt.err
+				},
+			}, grpc.UnaryInterceptor(interceptors.UnaryLogging(interceptors.LoggingConfig{
+				Skipper: tt.skipper,
+				Logger:  mockLogger,
+			})))
+
+			_, err := client.Redirect(context.Background(), &pb.RedirectRequest{})
+			assert.Equal(t, status.Code(tt.err), status.Code(err))
+
+			mockLogger.AssertNumberOfCalls(t, "Info", tt.want.infoCallCount)
+			mockLogger.AssertNumberOfCalls(t, "Error", tt.want.errorCallCount)
+		})
+	}
+}
+
+func TestStreamLogging(t *testing.T) {
+	mockLogger := new(logger2.Logger)
+	mockLogger.EXPECT().Info(
+		"got incoming gRPC request",
+		mock.AnythingOfType("zapcore.Field"),
+		mock.AnythingOfType("zapcore.Field"),
+		mock.AnythingOfType("zapcore.Field"),
+	).Return()
+	mockLogger.EXPECT().Error(
+		"error process gRPC request",
+		mock.AnythingOfType("zapcore.Field"),
+	).Return()
+
+	interceptor := interceptors.StreamLogging(interceptors.LoggingConfig{Logger: mockLogger})
+	testError := errors.New("test error")
+
+	err := interceptor(nil, &serverStream{ctx: context.Background()}, &grpc.StreamServerInfo{
+		FullMethod: "/shortener.URL/Stream",
+	}, func(any, grpc.ServerStream) error {
+		return testError
+	})
+
+	assert.Equal(t, testError, err)
+	mockLogger.AssertNumberOfCalls(t, "Info", 1)
+	mockLogger.AssertNumberOfCalls(t, "Error", 1)
+}
@@ -0,0 +1,103 @@
+package interceptors
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/ShukinDmitriy/shortener/internal/auth"
+	"github.com/ShukinDmitriy/shortener/internal/metrics"
+	"github.com/ShukinDmitriy/shortener/internal/ratelimit"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// RateLimitConfig configuration of the rate limit interceptor
+type RateLimitConfig struct {
+	Limiter ratelimit.Limiter
+	// Methods policies by full method name, other methods are not limited
+	Methods map[string]ratelimit.Policy
+}
+
+// UnaryRateLimit interceptor for limiting calls of a client, every link a call creates
+// beyond the first one takes one more token (see ratelimit.ChargeLinks).
+// The client is the user put into the context by the auth interceptor or the peer IP.
+// Rejected calls get ResourceExhausted and the retry-after header in seconds.
+func UnaryRateLimit(config RateLimitConfig) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		decision, policy, limited := config.allow(ctx, info.FullMethod)
+		if limited && !decision.Allowed {
+			grpc.SetHeader(ctx, retryAfterMetadata(decision))
+			return nil, rateLimitedError(policy)
+		}
+		if limited {
+			ctx = config.withCharge(ctx, policy)
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamRateLimit interceptor for limiting stream calls of a client, a stream takes one token
+// and every link it creates beyond the first one takes one more
+func StreamRateLimit(config RateLimitConfig) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		decision, policy, limited := config.allow(ss.Context(), info.FullMethod)
+		if limited && !decision.Allowed {
+			ss.SetHeader(retryAfterMetadata(decision))
+			return rateLimitedError(policy)
+		}
+		if limited {
+			ss = &serverStream{ServerStream: ss, ctx: config.withCharge(ss.Context(), policy)}
+		}
+
+		return handler(srv, ss)
+	}
+}
+
+// allow забирает токен клиента, limited ложно для методов без ограничения и при недоступном хранилище
+func (config RateLimitConfig) allow(ctx context.Context, method string) (ratelimit.Decision, ratelimit.Policy, bool) {
+	policy, ok := config.Methods[method]
+	if !ok || !policy.Enabled() {
+		return ratelimit.Decision{}, policy, false
+	}
+
+	decision, err := config.Limiter.Allow(ctx, policy, rateLimitKey(ctx))
+	if err != nil {
+		zap.L().Warn("rate limiter is unavailable", zap.String("policy", policy.Name), zap.Error(err))
+		return ratelimit.Decision{}, policy, false
+	}
+
+	return decision, policy, true
+}
+
+// withCharge кладет в контекст списание за ссылки, созданные вызовом
+func (config RateLimitConfig) withCharge(ctx context.Context, policy ratelimit.Policy) context.Context {
+	return ratelimit.ContextWithCharge(ctx, ratelimit.NewCharge(config.Limiter, policy, rateLimitKey(ctx)))
+}
+
+// rateLimitKey зарегистрированный пользователь из токена или адрес клиента.
+// Анонимный токен может выпустить себе любой клиент, поэтому анонимных считаем по адресу.
+func rateLimitKey(ctx context.Context) string {
+	if userID := auth.RegisteredUserIDFromContext(ctx); userID != "" {
+		return "user:" + userID
+	}
+
+	if ip := PeerIP(ctx); ip != nil {
+		return "ip:" + ip.String()
+	}
+
+	return "ip:unknown"
+}
+
+func retryAfterMetadata(decision ratelimit.Decision) metadata.MD {
+	return metadata.Pairs("retry-after", strconv.Itoa(decision.RetryAfterSeconds()))
+}
+
+func rateLimitedError(policy ratelimit.Policy) error {
+	metrics.RateLimitedTotal.WithLabelValues(metrics.TransportGRPC, policy.Name).Inc()
+
+	return status.Error(codes.ResourceExhausted, "too many requests")
+}
@@ -0,0 +1,118 @@
+package interceptors_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/ShukinDmitriy/shortener/internal/auth"
+	"github.com/ShukinDmitriy/shortener/internal/interceptors"
+	"github.com/ShukinDmitriy/shortener/internal/ratelimit"
+	pb "github.com/ShukinDmitriy/shortener/proto"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryRateLimit(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		// последний вызов делает другой пользователь с того же адреса
+		otherUser  string
+		registered bool
+		// сколько ссылок создает каждый вызов
+		links int
+		codes []codes.Code
+	}{
+		{
+			name:   "positive test #1",
+			method: pb.URL_GetUserURLs_FullMethodName,
+			codes:  []codes.Code{codes.OK, codes.OK, codes.OK},
+		},
+		{
+			name:       "positive test #2",
+			method:     pb.URL_Create_FullMethodName,
+			otherUser:  "user2",
+			registered: true,
+			codes:      []codes.Code{codes.OK, codes.OK, codes.OK},
+		},
+		{
+			name:   "negative test #1",
+			method: pb.URL_Create_FullMethodName,
+			codes:  []codes.Code{codes.OK, codes.OK, codes.ResourceExhausted},
+		},
+		{
+			name:   "negative test #2",
+			method: pb.URL_Redirect_FullMethodName,
+			codes:  []codes.Code{codes.OK, codes.OK, codes.ResourceExhausted},
+		},
+		{
+			name:      "negative test #3",
+			method:    pb.URL_Create_FullMethodName,
+			otherUser: "anonymous",
+			codes:     []codes.Code{codes.OK, codes.OK, codes.ResourceExhausted},
+		},
+		{
+			name:   "negative test #4",
+			method: pb.URL_Create_FullMethodName,
+			links:  2,
+			codes:  []codes.Code{codes.OK, codes.ResourceExhausted},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interceptor := interceptors.UnaryRateLimit(interceptors.RateLimitConfig{
+				Limiter: ratelimit.NewMemoryLimiter(),
+				Methods: map[string]ratelimit.Policy{
+					pb.URL_Create_FullMethodName:   ratelimit.PerMinute("shorten", 2),
+					pb.URL_Redirect_FullMethodName: ratelimit.PerMinute("redirect", 2),
+				},
+			})
+
+			ctx := peer.NewContext(context.Background(), &peer.Peer{
+				Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 50000},
+			})
+
+			for i, code := range tt.codes {
+				callCtx := ctx
+				if i == len(tt.codes)-1 && tt.otherUser != "" {
+					callCtx = auth.ContextWithUser(ctx, &auth.User{ID: tt.otherUser, Registered: tt.registered})
+				}
+
+				_, err := interceptor(callCtx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method},
+					func(ctx context.Context, _ any) (any, error) {
+						ratelimit.ChargeLinks(ctx, tt.links)
+						return nil, nil
+					},
+				)
+				assert.Equal(t, code, status.Code(err), "call #%d", i+1)
+			}
+		})
+	}
+}
+
+func TestStreamRateLimit(t *testing.T) {
+	interceptor := interceptors.StreamRateLimit(interceptors.RateLimitConfig{
+		Limiter: ratelimit.NewMemoryLimiter(),
+		Methods: map[string]ratelimit.Policy{
+			pb.URL_ImportURLs_FullMethodName: ratelimit.PerMinute("shorten", 2),
+		},
+	})
+	stream := &serverStream{ctx: auth.ContextWithUserID(context.Background(), "user1")}
+	info := &grpc.StreamServerInfo{FullMethod: pb.URL_ImportURLs_FullMethodName}
+	// Импорт двух ссылок тратит оба токена
+	handler := func(_ any, ss grpc.ServerStream) error {
+		ratelimit.ChargeLinks(ss.Context(), 2)
+		return nil
+	}
+
+	assert.NoError(t, interceptor(nil, stream, info, handler))
+
+	err := interceptor(nil, stream, info, handler)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, []string{"30"}, stream.header.Get("retry-after"))
+}
@@ -0,0 +1,79 @@
+package interceptors
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/ShukinDmitriy/shortener/internal/logger"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RecoveryConfig configuration of the recovery interceptor
+type RecoveryConfig struct {
+	Skipper Skipper
+	Logger  logger.Logger
+}
+
+// UnaryRecovery interceptor for converting a handler panic into codes.Internal
+func UnaryRecovery(config RecoveryConfig) grpc.UnaryServerInterceptor {
+	config = config.withDefaults()
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		if config.Skipper(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		defer func() {
+			if r := recover(); r != nil {
+				err = config.recovered(info.FullMethod, r)
+			}
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamRecovery interceptor for converting a stream handler panic into codes.Internal
+func StreamRecovery(config RecoveryConfig) grpc.StreamServerInterceptor {
+	config = config.withDefaults()
+
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		if config.Skipper(info.FullMethod) {
+			return handler(srv, ss)
+		}
+
+		defer func() {
+			if r := recover(); r != nil {
+				err = config.recovered(info.FullMethod, r)
+			}
+		}()
+
+		return handler(srv, ss)
+	}
+}
+
+func (config RecoveryConfig) withDefaults() RecoveryConfig {
+	if config.Skipper == nil {
+		config.Skipper = DefaultSkipper
+	}
+	if config.Logger == nil {
+		config.Logger = zap.L()
+	}
+
+	return config
+}
+
+// recovered логирует панику и скрывает ее подробности от клиента
+func (config RecoveryConfig) recovered(fullMethod string, r any) error {
+	config.Logger.Error("panic in gRPC handler",
+		zap.String("method", fullMethod),
+		zap.String("panic", fmt.Sprint(r)),
+		zap.String("stack", string(debug.Stack())),
+	)
+
+	return status.Error(codes.Internal, "internal server error")
+}
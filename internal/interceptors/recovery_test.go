@@ -0,0 +1,80 @@
+package interceptors_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ShukinDmitriy/shortener/internal/interceptors"
+	logger2 "github.com/ShukinDmitriy/shortener/mocks/internal_/logger"
+	pb "github.com/ShukinDmitriy/shortener/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryRecovery(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(ctx context.Context) error
+		code    codes.Code
+	}{
+		{
+			name: "positive test #1",
+			code: codes.OK,
+		},
+		{
+			name: "negative test #1",
+			handler: func(context.Context) error {
+				panic("test panic")
+			},
+			code: codes.Internal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockLogger := new(logger2.Logger)
+			mockLogger.EXPECT().Error(
+				"panic in gRPC handler",
+				mock.AnythingOfType("zapcore.Field"),
+				mock.AnythingOfType("zapcore.Field"),
+				mock.AnythingOfType("zapcore.Field"),
+			).Return().Maybe()
+
+			client := newClient(t, &urlServer{
+				handler: tt.handler,
+			}, grpc.UnaryInterceptor(interceptors.UnaryRecovery(interceptors.RecoveryConfig{
+				Logger: mockLogger,
+			})))
+
+			// Сервер продолжает работать после паники
+			for i := 0; i < 2; i++ {
+				_, err := client.Redirect(context.Background(), &pb.RedirectRequest{})
+				assert.Equal(t, tt.code, status.Code(err))
+			}
+		})
+	}
+}
+
+func TestStreamRecovery(t *testing.T) {
+	mockLogger := new(logger2.Logger)
+	mockLogger.EXPECT().Error(
+		"panic in gRPC handler",
+		mock.AnythingOfType("zapcore.Field"),
+		mock.AnythingOfType("zapcore.Field"),
+		mock.AnythingOfType("zapcore.Field"),
+	).Return()
+
+	interceptor := interceptors.StreamRecovery(interceptors.RecoveryConfig{Logger: mockLogger})
+
+	err := interceptor(nil, &serverStream{ctx: context.Background()}, &grpc.StreamServerInfo{
+		FullMethod: "/shortener.URL/Stream",
+	}, func(any, grpc.ServerStream) error {
+		panic("test panic")
+	})
+
+	assert.Equal(t, codes.Internal, status.Code(err))
+	mockLogger.AssertNumberOfCalls(t, "Error", 1)
+}
@@ -0,0 +1,77 @@
+package interceptors
+
+import (
+	"context"
+	"net"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// TrustedSubnetConfig configuration of the trusted subnet interceptor
+type TrustedSubnetConfig struct {
+	// Subnet allowed to call the methods, nil denies everyone
+	Subnet *net.IPNet
+	// Methods restricted to the subnet, other methods are not checked
+	Methods []string
+}
+
+// UnaryTrustedSubnet interceptor for allowing methods only for clients from the trusted subnet.
+// The client IP is taken from the peer address, not from the request.
+func UnaryTrustedSubnet(config TrustedSubnetConfig) grpc.UnaryServerInterceptor {
+	restricted := methodSet(config.Methods)
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if _, ok := restricted[info.FullMethod]; ok && !config.allowed(ctx) {
+			return nil, status.Error(codes.PermissionDenied, "forbidden")
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamTrustedSubnet interceptor for allowing stream methods only for clients from the trusted subnet
+func StreamTrustedSubnet(config TrustedSubnetConfig) grpc.StreamServerInterceptor {
+	restricted := methodSet(config.Methods)
+
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if _, ok := restricted[info.FullMethod]; ok && !config.allowed(ss.Context()) {
+			return status.Error(codes.PermissionDenied, "forbidden")
+		}
+
+		return handler(srv, ss)
+	}
+}
+
+// allowed проверяет адрес клиента
+func (config TrustedSubnetConfig) allowed(ctx context.Context) bool {
+	ip := PeerIP(ctx)
+	if config.Subnet == nil || ip == nil || !config.Subnet.Contains(ip) {
+		zap.L().Debug("client is not in the trusted subnet", zap.Stringer("ip", ip))
+		return false
+	}
+
+	return true
+}
+
+// PeerIP get client IP from the connection
+func PeerIP(ctx context.Context) net.IP {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return nil
+	}
+
+	if addr, ok := p.Addr.(*net.TCPAddr); ok {
+		return addr.IP
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return nil
+	}
+
+	return net.ParseIP(host)
+}
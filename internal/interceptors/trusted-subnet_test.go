@@ -0,0 +1,128 @@
+package interceptors_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/ShukinDmitriy/shortener/internal/interceptors"
+	pb "github.com/ShukinDmitriy/shortener/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryTrustedSubnet(t *testing.T) {
+	_, subnet, err := net.ParseCIDR("127.0.0.1/24")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		subnet *net.IPNet
+		ip     string
+		method string
+		code   codes.Code
+	}{
+		{
+			name:   "positive test #1",
+			subnet: subnet,
+			ip:     "127.0.0.1",
+			method: pb.URL_GetStats_FullMethodName,
+			code:   codes.OK,
+		},
+		{
+			name:   "positive test #2",
+			subnet: subnet,
+			ip:     "192.168.0.1",
+			method: pb.URL_Redirect_FullMethodName,
+			code:   codes.OK,
+		},
+		{
+			name:   "negative test #1",
+			subnet: subnet,
+			ip:     "192.168.0.1",
+			method: pb.URL_GetStats_FullMethodName,
+			code:   codes.PermissionDenied,
+		},
+		{
+			name:   "negative test #2",
+			ip:     "127.0.0.1",
+			method: pb.URL_GetStats_FullMethodName,
+			code:   codes.PermissionDenied,
+		},
+		{
+			name:   "negative test #3",
+			subnet: subnet,
+			method: pb.URL_GetStats_FullMethodName,
+			code:   codes.PermissionDenied,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interceptor := interceptors.UnaryTrustedSubnet(interceptors.TrustedSubnetConfig{
+				Subnet:  tt.subnet,
+				Methods: []string{pb.URL_GetStats_FullMethodName},
+			})
+
+			ctx := context.Background()
+			if tt.ip != "" {
+				ctx = peer.NewContext(ctx, &peer.Peer{
+					Addr: &net.TCPAddr{IP: net.ParseIP(tt.ip), Port: 50000},
+				})
+			}
+
+			_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method},
+				func(context.Context, any) (any, error) {
+					return nil, nil
+				})
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+}
+
+func TestUnaryTrustedSubnet_Bufconn(t *testing.T) {
+	_, subnet, err := net.ParseCIDR("127.0.0.1/24")
+	require.NoError(t, err)
+
+	client := newClient(t, &urlServer{}, grpc.UnaryInterceptor(interceptors.UnaryTrustedSubnet(
+		interceptors.TrustedSubnetConfig{
+			Subnet:  subnet,
+			Methods: []string{pb.URL_GetStats_FullMethodName},
+		},
+	)))
+
+	// У bufconn нет IP-адреса клиента, а адрес из запроса не учитывается
+	_, err = client.GetStats(context.Background(), &pb.GetStatsRequest{IpAddress: "127.0.0.1"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.Redirect(context.Background(), &pb.RedirectRequest{})
+	assert.NoError(t, err)
+}
+
+func TestStreamTrustedSubnet(t *testing.T) {
+	_, subnet, err := net.ParseCIDR("127.0.0.1/24")
+	require.NoError(t, err)
+
+	interceptor := interceptors.StreamTrustedSubnet(interceptors.TrustedSubnetConfig{
+		Subnet:  subnet,
+		Methods: []string{"/shortener.URL/Stream"},
+	})
+	info := &grpc.StreamServerInfo{FullMethod: "/shortener.URL/Stream"}
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 50000},
+	})
+	err = interceptor(nil, &serverStream{ctx: ctx}, info, func(any, grpc.ServerStream) error {
+		return nil
+	})
+	assert.NoError(t, err)
+
+	err = interceptor(nil, &serverStream{ctx: context.Background()}, info, func(any, grpc.ServerStream) error {
+		return nil
+	})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
@@ -0,0 +1,66 @@
+package metrics_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ShukinDmitriy/shortener/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code string
+	}{
+		{
+			name: "positive test #1",
+			code: codes.OK.String(),
+		},
+		{
+			name: "negative test #1",
+			err:  status.Error(codes.NotFound, "not found"),
+			code: codes.NotFound.String(),
+		},
+	}
+
+	interceptor := metrics.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/shortener.URL/Redirect"}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter := metrics.GRPCRequestsTotal.WithLabelValues(info.FullMethod, tt.code)
+			before := testutil.ToFloat64(counter)
+
+			_, err := interceptor(context.TODO(), nil, info, func(ctx context.Context, req any) (any, error) {
+				return nil, tt.err
+			})
+
+			assert.Equal(t, tt.err, err)
+			assert.Equal(t, before+1, testutil.ToFloat64(counter))
+		})
+	}
+}
+
+func TestRegisterDeleteQueueDepth(t *testing.T) {
+	assert.NoError(t, metrics.RegisterDeleteQueueDepth(func() int {
+		return 3
+	}))
+
+	families, err := metrics.Registry.Gather()
+	assert.NoError(t, err)
+
+	found := false
+	for _, family := range families {
+		if family.GetName() == "shortener_delete_queue_depth" {
+			found = true
+			assert.Equal(t, float64(3), family.GetMetric()[0].GetGauge().GetValue())
+		}
+	}
+	assert.True(t, found)
+}
@@ -0,0 +1,170 @@
+// Package metrics Prometheus metrics of the application
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "shortener"
+
+const (
+	// TransportHTTP метка запросов, пришедших по HTTP
+	TransportHTTP = "http"
+	// TransportGRPC метка запросов, пришедших по gRPC
+	TransportGRPC = "grpc"
+)
+
+// Registry registry of the application metrics
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequestsTotal count of HTTP requests by route and status
+	HTTPRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Count of HTTP requests by route and status.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPRequestDuration latency of HTTP requests by route and status
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of HTTP requests by route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// GRPCRequestsTotal count of gRPC requests by method and code
+	GRPCRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "requests_total",
+		Help:      "Count of gRPC requests by method and code.",
+	}, []string{"method", "code"})
+
+	// GRPCRequestDuration latency of gRPC requests by method and code
+	GRPCRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of gRPC requests by method and code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "code"})
+
+	// RepositoryOperationDuration latency of repository operations
+	RepositoryOperationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "repository",
+		Name:      "operation_duration_seconds",
+		Help:      "Latency of URL repository operations.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	// RepositoryErrorsTotal count of failed repository operations
+	RepositoryErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "repository",
+		Name:      "errors_total",
+		Help:      "Count of failed URL repository operations.",
+	}, []string{"operation"})
+
+	// LinksCreatedTotal count of created short links
+	LinksCreatedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "links_created_total",
+		Help:      "Count of created short links.",
+	}, []string{"transport"})
+
+	// LinksRedirectedTotal count of redirects by short links
+	LinksRedirectedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "links_redirected_total",
+		Help:      "Count of redirects by short links.",
+	}, []string{"transport"})
+
+	// RateLimitedTotal count of requests rejected by the rate limiter
+	RateLimitedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_total",
+		Help:      "Count of requests rejected by the rate limiter.",
+	}, []string{"transport", "policy"})
+
+	// DeleteRequestsParkedTotal count of delete requests given up after repeated failures
+	DeleteRequestsParkedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "delete_requests_parked_total",
+		Help:      "Count of delete requests given up after repeated failures, they stay in the journal until restart.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequestsTotal,
+		HTTPRequestDuration,
+		GRPCRequestsTotal,
+		GRPCRequestDuration,
+		RepositoryOperationDuration,
+		RepositoryErrorsTotal,
+		LinksCreatedTotal,
+		LinksRedirectedTotal,
+		RateLimitedTotal,
+		DeleteRequestsParkedTotal,
+	)
+}
+
+// Handler HTTP handler exposing the application metrics
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// ObserveRepositoryOperation record duration and error of the repository operation
+func ObserveRepositoryOperation(operation string, start time.Time, err error) {
+	RepositoryOperationDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		RepositoryErrorsTotal.WithLabelValues(operation).Inc()
+	}
+}
+
+// RegisterDeleteQueueDepth expose length of the deletion queue
+func RegisterDeleteQueueDepth(depth func() int) error {
+	return Registry.Register(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "delete_queue_depth",
+		Help:      "Count of delete requests waiting in the queue.",
+	}, func() float64 {
+		return float64(depth())
+	}))
+}
+
+// RegisterCache expose hit and miss counters of the repository cache
+func RegisterCache(hits func() uint64, misses func() uint64) error {
+	err := Registry.Register(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "hits_total",
+		Help:      "Count of short links served from the cache.",
+	}, func() float64 {
+		return float64(hits())
+	}))
+	if err != nil {
+		return err
+	}
+
+	return Registry.Register(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "misses_total",
+		Help:      "Count of short links loaded from the repository.",
+	}, func() float64 {
+		return float64(misses())
+	}))
+}
@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ShukinDmitriy/shortener/internal/auth"
+	"github.com/ShukinDmitriy/shortener/internal/metrics"
+	"github.com/ShukinDmitriy/shortener/internal/ratelimit"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// RateLimitRule policy of the routes, routes are echo path templates
+type RateLimitRule struct {
+	Policy ratelimit.Policy
+	Method string
+	Routes []string
+}
+
+// RateLimitConfig configuration of the rate limit middleware
+type RateLimitConfig struct {
+	Limiter ratelimit.Limiter
+	Rules   []RateLimitRule
+	// KeyFunc client of the request, by default the client IP
+	KeyFunc func(c echo.Context) string
+}
+
+// RateLimit middleware for limiting requests of a client to the routes of the rules.
+// A request takes a token, every link it creates beyond the first one takes one more
+// (see ratelimit.ChargeLinks). Rejected requests get 429 with the Retry-After header.
+// When the limiter is unavailable requests are allowed.
+func RateLimit(config RateLimitConfig) echo.MiddlewareFunc {
+	if config.KeyFunc == nil {
+		config.KeyFunc = IPKey
+	}
+
+	policies := make(map[string]ratelimit.Policy)
+	for _, rule := range config.Rules {
+		if !rule.Policy.Enabled() {
+			continue
+		}
+		for _, route := range rule.Routes {
+			policies[rule.Method+" "+route] = rule.Policy
+		}
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			policy, ok := policies[c.Request().Method+" "+c.Path()]
+			if !ok {
+				return next(c)
+			}
+
+			key := config.KeyFunc(c)
+			decision, err := config.Limiter.Allow(c.Request().Context(), policy, key)
+			if err != nil {
+				zap.L().Warn("rate limiter is unavailable", zap.String("policy", policy.Name), zap.Error(err))
+				return next(c)
+			}
+
+			c.Response().Header().Set("X-RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+
+			if !decision.Allowed {
+				metrics.RateLimitedTotal.WithLabelValues(metrics.TransportHTTP, policy.Name).Inc()
+				c.Response().Header().Set("Retry-After", strconv.Itoa(decision.RetryAfterSeconds()))
+
+				return echo.NewHTTPError(http.StatusTooManyRequests, "Too Many Requests")
+			}
+
+			// Ссылки пакета и импорта сверх первой обработчик спишет из того же ведра
+			charge := ratelimit.NewCharge(config.Limiter, policy, key)
+			c.SetRequest(c.Request().WithContext(ratelimit.ContextWithCharge(c.Request().Context(), charge)))
+
+			return next(c)
+		}
+	}
+}
+
+// IPKey key of the client by its IP.
+// The IP is taken by the IPExtractor of the server, so forwarding headers count only from trusted proxies.
+func IPKey(c echo.Context) string {
+	return "ip:" + c.RealIP()
+}
+
+// UserOrIPKey key of the client by the registered user from the access token, by IP for anonymous clients.
+// Any client can get a new anonymous token on every call, so anonymous users are limited by IP.
+func UserOrIPKey(c echo.Context) string {
+	if userID := auth.RegisteredUserID(c); userID != "" {
+		return "user:" + userID
+	}
+
+	return IPKey(c)
+}
+
+// NewIPExtractor IP extractor of the server. Without trusted proxies the IP of the connection is used
+// and X-Forwarded-For and X-Real-IP are ignored, otherwise X-Forwarded-For is read
+// only up to the first address outside of the trusted proxies.
+func NewIPExtractor(trustedProxies string) (echo.IPExtractor, error) {
+	var options []echo.TrustOption
+	for _, cidr := range strings.Split(trustedProxies, ",") {
+		if cidr = strings.TrimSpace(cidr); cidr == "" {
+			continue
+		}
+
+		_, ipRange, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q: %w", cidr, err)
+		}
+		options = append(options, echo.TrustIPRange(ipRange))
+	}
+
+	if len(options) == 0 {
+		return echo.ExtractIPDirect(), nil
+	}
+
+	// Доверяем только явно перечисленным сетям
+	options = append(options, echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false))
+
+	return echo.ExtractIPFromXFFHeader(options...), nil
+}
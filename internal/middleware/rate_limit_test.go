@@ -0,0 +1,278 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ShukinDmitriy/shortener/internal/auth"
+	"github.com/ShukinDmitriy/shortener/internal/middleware"
+	"github.com/ShukinDmitriy/shortener/internal/ratelimit"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingLimiter хранилище ограничений недоступно
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(context.Context, ratelimit.Policy, string) (ratelimit.Decision, error) {
+	return ratelimit.Decision{}, errors.New("unavailable")
+}
+
+func (failingLimiter) Take(context.Context, ratelimit.Policy, string, int) error {
+	return errors.New("unavailable")
+}
+
+func TestRateLimit(t *testing.T) {
+	type want struct {
+		statuses   []int
+		retryAfter string
+	}
+	tests := []struct {
+		name    string
+		method  string
+		target  string
+		limiter ratelimit.Limiter
+		// запросы идут с разных адресов
+		ips  []string
+		want want
+	}{
+		{
+			name:    "positive test #1",
+			method:  http.MethodPost,
+			target:  "/api/shorten",
+			limiter: ratelimit.NewMemoryLimiter(),
+			ips:     []string{"10.0.0.1", "10.0.0.1"},
+			want:    want{statuses: []int{http.StatusOK, http.StatusOK}},
+		},
+		{
+			name:    "positive test #2",
+			method:  http.MethodPost,
+			target:  "/api/shorten",
+			limiter: ratelimit.NewMemoryLimiter(),
+			ips:     []string{"10.0.0.1", "10.0.0.1", "10.0.0.2"},
+			want:    want{statuses: []int{http.StatusOK, http.StatusOK, http.StatusOK}},
+		},
+		{
+			name:    "positive test #3",
+			method:  http.MethodGet,
+			target:  "/api/user/urls",
+			limiter: ratelimit.NewMemoryLimiter(),
+			ips:     []string{"10.0.0.1", "10.0.0.1", "10.0.0.1"},
+			want:    want{statuses: []int{http.StatusOK, http.StatusOK, http.StatusOK}},
+		},
+		{
+			name:    "positive test #4",
+			method:  http.MethodPost,
+			target:  "/api/shorten",
+			limiter: failingLimiter{},
+			ips:     []string{"10.0.0.1", "10.0.0.1", "10.0.0.1"},
+			want:    want{statuses: []int{http.StatusOK, http.StatusOK, http.StatusOK}},
+		},
+		{
+			name:    "negative test #1",
+			method:  http.MethodPost,
+			target:  "/api/shorten",
+			limiter: ratelimit.NewMemoryLimiter(),
+			ips:     []string{"10.0.0.1", "10.0.0.1", "10.0.0.1"},
+			want: want{
+				statuses:   []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+				retryAfter: "30",
+			},
+		},
+		{
+			name:    "negative test #2",
+			method:  http.MethodGet,
+			target:  "/short1",
+			limiter: ratelimit.NewMemoryLimiter(),
+			ips:     []string{"10.0.0.1", "10.0.0.1", "10.0.0.1"},
+			want: want{
+				statuses:   []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+				retryAfter: "30",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.IPExtractor = echo.ExtractIPDirect()
+			e.Use(middleware.RateLimit(middleware.RateLimitConfig{
+				Limiter: tt.limiter,
+				Rules: []middleware.RateLimitRule{
+					{Policy: ratelimit.PerMinute("shorten", 2), Method: http.MethodPost, Routes: []string{"/api/shorten"}},
+					{Policy: ratelimit.PerMinute("redirect", 2), Method: http.MethodGet, Routes: []string{"/:id"}},
+				},
+			}))
+			handler := func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			}
+			e.POST("/api/shorten", handler)
+			e.GET("/:id", handler)
+			e.GET("/api/user/urls", handler)
+
+			var rec *httptest.ResponseRecorder
+			for i, ip := range tt.ips {
+				req := httptest.NewRequest(tt.method, tt.target, nil)
+				req.RemoteAddr = ip + ":50000"
+				rec = httptest.NewRecorder()
+				e.ServeHTTP(rec, req)
+
+				assert.Equal(t, tt.want.statuses[i], rec.Code, "request #%d", i+1)
+			}
+			assert.Equal(t, tt.want.retryAfter, rec.Header().Get("Retry-After"))
+		})
+	}
+}
+
+func TestRateLimitChargeLinks(t *testing.T) {
+	e := echo.New()
+	e.IPExtractor = echo.ExtractIPDirect()
+	e.Use(middleware.RateLimit(middleware.RateLimitConfig{
+		Limiter: ratelimit.NewMemoryLimiter(),
+		Rules: []middleware.RateLimitRule{
+			{Policy: ratelimit.PerMinute("shorten", 3), Method: http.MethodPost, Routes: []string{"/api/shorten/batch"}},
+		},
+	}))
+	// Пакет из трех ссылок тратит все токены
+	e.POST("/api/shorten/batch", func(c echo.Context) error {
+		ratelimit.ChargeLinks(c.Request().Context(), 3)
+		return c.NoContent(http.StatusCreated)
+	})
+
+	statuses := []int{http.StatusCreated, http.StatusTooManyRequests}
+	for i, status := range statuses {
+		req := httptest.NewRequest(http.MethodPost, "/api/shorten/batch", nil)
+		req.RemoteAddr = "10.0.0.1:50000"
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		assert.Equal(t, status, rec.Code, "request #%d", i+1)
+	}
+}
+
+func TestRateLimitSpoofedIP(t *testing.T) {
+	tests := []struct {
+		name           string
+		trustedProxies string
+		// адрес соединения и подставленный клиентом X-Forwarded-For
+		remoteAddrs []string
+		forwarded   []string
+		statuses    []int
+	}{
+		{
+			name:        "positive test #1",
+			remoteAddrs: []string{"10.0.0.1", "10.0.0.2"},
+			forwarded:   []string{"203.0.113.1", "203.0.113.1"},
+			statuses:    []int{http.StatusOK, http.StatusOK},
+		},
+		{
+			name:           "positive test #2",
+			trustedProxies: "10.0.0.0/24",
+			remoteAddrs:    []string{"10.0.0.1", "10.0.0.1", "10.0.0.1"},
+			forwarded:      []string{"203.0.113.1", "203.0.113.2", "203.0.113.3"},
+			statuses:       []int{http.StatusOK, http.StatusOK, http.StatusOK},
+		},
+		{
+			name:        "negative test #1",
+			remoteAddrs: []string{"10.0.0.1", "10.0.0.1", "10.0.0.1"},
+			forwarded:   []string{"203.0.113.1", "203.0.113.2", "203.0.113.3"},
+			statuses:    []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+		},
+		{
+			name:           "negative test #2",
+			trustedProxies: "192.168.0.0/24",
+			remoteAddrs:    []string{"10.0.0.1", "10.0.0.1", "10.0.0.1"},
+			forwarded:      []string{"203.0.113.1", "203.0.113.2", "203.0.113.3"},
+			statuses:       []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+		},
+		{
+			name:           "negative test #3",
+			trustedProxies: "10.0.0.0/24",
+			remoteAddrs:    []string{"10.0.0.1", "10.0.0.1", "10.0.0.1"},
+			forwarded:      []string{"203.0.113.2, 203.0.113.1", "203.0.113.3, 203.0.113.1", "203.0.113.4, 203.0.113.1"},
+			statuses:       []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			extractor, err := middleware.NewIPExtractor(tt.trustedProxies)
+			require.NoError(t, err)
+
+			e := echo.New()
+			e.IPExtractor = extractor
+			e.Use(middleware.RateLimit(middleware.RateLimitConfig{
+				Limiter: ratelimit.NewMemoryLimiter(),
+				Rules: []middleware.RateLimitRule{
+					{Policy: ratelimit.PerMinute("shorten", 2), Method: http.MethodPost, Routes: []string{"/api/shorten"}},
+				},
+			}))
+			e.POST("/api/shorten", func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			})
+
+			for i := range tt.statuses {
+				req := httptest.NewRequest(http.MethodPost, "/api/shorten", nil)
+				req.RemoteAddr = tt.remoteAddrs[i] + ":50000"
+				req.Header.Set(echo.HeaderXForwardedFor, tt.forwarded[i])
+				req.Header.Set(echo.HeaderXRealIP, tt.forwarded[i])
+				rec := httptest.NewRecorder()
+				e.ServeHTTP(rec, req)
+
+				assert.Equal(t, tt.statuses[i], rec.Code, "request #%d", i+1)
+			}
+		})
+	}
+}
+
+func TestNewIPExtractor(t *testing.T) {
+	_, err := middleware.NewIPExtractor("10.0.0.0/8, 192.168.0.0/16")
+	assert.NoError(t, err)
+
+	_, err = middleware.NewIPExtractor("10.0.0.1")
+	assert.Error(t, err)
+}
+
+func TestUserOrIPKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims *auth.Claims
+		want   string
+	}{
+		{
+			name:   "positive test #1",
+			claims: &auth.Claims{ID: "user1", Registered: true},
+			want:   "user:user1",
+		},
+		{
+			name: "positive test #2",
+			want: "ip:10.0.0.1",
+		},
+		{
+			name:   "positive test #3",
+			claims: &auth.Claims{ID: "anonymous"},
+			want:   "ip:10.0.0.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/shorten", nil)
+			req.RemoteAddr = "10.0.0.1:50000"
+			req.Header.Set(echo.HeaderXRealIP, "203.0.113.1")
+			e := echo.New()
+			e.IPExtractor = echo.ExtractIPDirect()
+			c := e.NewContext(req, httptest.NewRecorder())
+			if tt.claims != nil {
+				c.Set("user", jwt.NewWithClaims(auth.GetSigningMethod(), tt.claims))
+			}
+
+			assert.Equal(t, tt.want, middleware.UserOrIPKey(c))
+		})
+	}
+}
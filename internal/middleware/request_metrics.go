@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/ShukinDmitriy/shortener/internal/metrics"
+
+	"github.com/labstack/echo/v4"
+)
+
+// unknownRoute метка запросов, для которых не нашелся маршрут
+const unknownRoute = "unknown"
+
+// RequestMetrics middleware for collecting count and latency of requests per route and status
+func RequestMetrics() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			requestStart := time.Now()
+
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+
+			// Берем шаблон маршрута, чтобы не плодить метки для каждого короткого ключа
+			route := c.Path()
+			if route == "" {
+				route = unknownRoute
+			}
+			status := strconv.Itoa(c.Response().Status)
+
+			metrics.HTTPRequestsTotal.WithLabelValues(c.Request().Method, route, status).Inc()
+			metrics.HTTPRequestDuration.WithLabelValues(c.Request().Method, route, status).
+				Observe(time.Since(requestStart).Seconds())
+
+			return nil
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ShukinDmitriy/shortener/internal/metrics"
+	"github.com/ShukinDmitriy/shortener/internal/middleware"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestMetrics(t *testing.T) {
+	type want struct {
+		route  string
+		status string
+	}
+	tests := []struct {
+		name       string
+		targetPath string
+		want       want
+	}{
+		{
+			name:       "positive test #1",
+			targetPath: "/short1",
+			want: want{
+				route:  "/:id",
+				status: "307",
+			},
+		},
+		{
+			name:       "negative test #1",
+			targetPath: "/api/fail",
+			want: want{
+				route:  "/api/fail",
+				status: "400",
+			},
+		},
+	}
+
+	e := echo.New()
+	e.Use(middleware.RequestMetrics())
+	e.GET("/:id", func(c echo.Context) error {
+		return c.Redirect(http.StatusTemporaryRedirect, "https://example.com")
+	})
+	e.GET("/api/fail", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusBadRequest, "bad request")
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter := metrics.HTTPRequestsTotal.WithLabelValues(http.MethodGet, tt.want.route, tt.want.status)
+			before := testutil.ToFloat64(counter)
+
+			req := httptest.NewRequest(http.MethodGet, tt.targetPath, nil)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			res := rec.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, before+1, testutil.ToFloat64(counter))
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ShukinDmitriy/shortener/internal/middleware"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRequestTracing(t *testing.T) {
+	type want struct {
+		spanName string
+		traceID  string
+		status   codes.Code
+	}
+	tests := []struct {
+		name        string
+		targetPath  string
+		traceparent string
+		want        want
+	}{
+		{
+			name:        "positive test #1",
+			targetPath:  "/short1",
+			traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			want: want{
+				spanName: "GET /:id",
+				traceID:  "4bf92f3577b34da6a3ce929d0e0e4736",
+				status:   codes.Unset,
+			},
+		},
+		{
+			name:       "negative test #1",
+			targetPath: "/api/fail",
+			want: want{
+				spanName: "GET /api/fail",
+				status:   codes.Error,
+			},
+		},
+	}
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var traceID trace.TraceID
+	e := echo.New()
+	e.Use(middleware.RequestTracing())
+	e.GET("/:id", func(c echo.Context) error {
+		traceID = trace.SpanContextFromContext(c.Request().Context()).TraceID()
+		return c.Redirect(http.StatusTemporaryRedirect, "https://example.com")
+	})
+	e.GET("/api/fail", func(c echo.Context) error {
+		traceID = trace.SpanContextFromContext(c.Request().Context()).TraceID()
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "unavailable")
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.targetPath, nil)
+			if tt.traceparent != "" {
+				req.Header.Set("traceparent", tt.traceparent)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			res := rec.Result()
+			defer res.Body.Close()
+
+			spans := recorder.Ended()
+			span := spans[len(spans)-1]
+			assert.Equal(t, tt.want.spanName, span.Name())
+			assert.Equal(t, tt.want.status, span.Status().Code)
+			// Обработчик получает контекст со спаном запроса
+			assert.Equal(t, span.SpanContext().TraceID(), traceID)
+			if tt.want.traceID != "" {
+				assert.Equal(t, tt.want.traceID, traceID.String())
+			}
+		})
+	}
+}
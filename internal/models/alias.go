@@ -0,0 +1,66 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+const (
+	// aliasCharset допустимые символы пользовательского ключа
+	aliasCharset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_"
+	// aliasMinLength минимальная длина пользовательского ключа
+	aliasMinLength = 3
+	// aliasMaxLength максимальная длина пользовательского ключа
+	aliasMaxLength = 64
+)
+
+// ErrAliasInvalid alias contains forbidden symbols or has wrong length
+var ErrAliasInvalid = errors.New("alias is invalid")
+
+// ErrAliasReserved alias is reserved by the application
+var ErrAliasReserved = errors.New("alias is reserved")
+
+// reservedAliases ключи, которые совпадают с маршрутами приложения
+var reservedAliases = map[string]struct{}{
+	"api":      {},
+	"ping":     {},
+	"user":     {},
+	"internal": {},
+	"admin":    {},
+	"metrics":  {},
+	"health":   {},
+	"static":   {},
+}
+
+// ValidateAlias check custom short key
+func ValidateAlias(alias string) error {
+	if len(alias) < aliasMinLength || len(alias) > aliasMaxLength {
+		return ErrAliasInvalid
+	}
+
+	for _, symbol := range alias {
+		if !strings.ContainsRune(aliasCharset, symbol) {
+			return ErrAliasInvalid
+		}
+	}
+
+	if _, ok := reservedAliases[strings.ToLower(alias)]; ok {
+		return ErrAliasReserved
+	}
+
+	return nil
+}
+
+// PrepareShortKey return validated alias or generate unique short key
+func PrepareShortKey(ctx context.Context, generator KeyGenerator, repository URLRepository, alias string, originalURL string) (string, error) {
+	if alias == "" {
+		return GenerateUniqueShortKey(ctx, generator, repository, originalURL)
+	}
+
+	if err := ValidateAlias(alias); err != nil {
+		return "", err
+	}
+
+	return alias, nil
+}
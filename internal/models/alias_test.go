@@ -0,0 +1,74 @@
+package models
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ShukinDmitriy/shortener/internal/environments"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateAlias(t *testing.T) {
+	tests := []struct {
+		name  string
+		alias string
+		want  error
+	}{
+		{
+			name:  "positive test #1",
+			alias: "spring-sale",
+		},
+		{
+			name:  "positive test #2",
+			alias: "Spring_Sale_2024",
+		},
+		{
+			name:  "negative test #1",
+			alias: "ab",
+			want:  ErrAliasInvalid,
+		},
+		{
+			name:  "negative test #2",
+			alias: "spring sale",
+			want:  ErrAliasInvalid,
+		},
+		{
+			name:  "negative test #3",
+			alias: "весна",
+			want:  ErrAliasInvalid,
+		},
+		{
+			name:  "negative test #4",
+			alias: "API",
+			want:  ErrAliasReserved,
+		},
+		{
+			name:  "negative test #5",
+			alias: "ping",
+			want:  ErrAliasReserved,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, ValidateAlias(tt.alias), tt.want)
+		})
+	}
+}
+
+func TestPrepareShortKey(t *testing.T) {
+	repository := &MemoryURLRepository{}
+	require.NoError(t, repository.Initialize(context.TODO(), environments.Configuration{}))
+	generator := NewRandomKeyGenerator(DefaultKeyAlphabet, DefaultKeyLength)
+
+	shortKey, err := PrepareShortKey(context.TODO(), generator, repository, "", "https://example.com")
+	assert.NoError(t, err)
+	assert.Len(t, shortKey, 6)
+
+	shortKey, err = PrepareShortKey(context.TODO(), generator, repository, "spring-sale", "https://example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "spring-sale", shortKey)
+
+	_, err = PrepareShortKey(context.TODO(), generator, repository, "api", "https://example.com")
+	assert.ErrorIs(t, err, ErrAliasReserved)
+}
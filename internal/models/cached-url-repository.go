@@ -0,0 +1,227 @@
+package models
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ShukinDmitriy/shortener/internal/environments"
+)
+
+const (
+	// DefaultCacheSize количество ссылок в кеше по умолчанию
+	DefaultCacheSize = 1000
+	// DefaultCacheTTL время жизни записи в кеше по умолчанию
+	DefaultCacheTTL = time.Minute
+)
+
+// cacheEntry запись кеша, err=ErrNotFound означает отсутствие ключа в хранилище
+type cacheEntry struct {
+	shortKey  string
+	event     Event
+	err       error
+	expiresAt time.Time
+}
+
+// CachedURLRepository decorator with bounded LRU/TTL cache for Get
+type CachedURLRepository struct {
+	URLRepository
+
+	size int
+	ttl  time.Duration
+
+	mutex   sync.Mutex
+	entries map[string]*list.Element
+	// порядок использования, в начале самые свежие записи
+	order *list.List
+	// увеличивается при каждой инвалидации, чтобы не сохранить устаревший ответ хранилища
+	generation uint64
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// NewCachedURLRepository wrap repository with cache of the size entries living ttl
+func NewCachedURLRepository(repository URLRepository, size int, ttl time.Duration) *CachedURLRepository {
+	if size <= 0 {
+		size = DefaultCacheSize
+	}
+
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+
+	return &CachedURLRepository{
+		URLRepository: repository,
+		size:          size,
+		ttl:           ttl,
+		entries:       make(map[string]*list.Element, size),
+		order:         list.New(),
+	}
+}
+
+// Initialize wrapped repository and reset cache
+func (r *CachedURLRepository) Initialize(ctx context.Context, configuration environments.Configuration) error {
+	r.purge()
+
+	return r.URLRepository.Initialize(ctx, configuration)
+}
+
+// Get event by short key from cache or wrapped repository
+func (r *CachedURLRepository) Get(ctx context.Context, shortKey string) (Event, error) {
+	event, err, ok, generation := r.load(shortKey)
+	if ok {
+		r.hits.Add(1)
+		return event, err
+	}
+
+	r.misses.Add(1)
+	event, err = r.URLRepository.Get(ctx, shortKey)
+
+	// Сбой хранилища не кешируем, следующий запрос должен повторить попытку
+	if err == nil || errors.Is(err, ErrNotFound) || errors.Is(err, ErrDeleted) {
+		r.store(shortKey, event, err, generation)
+	}
+
+	return event, err
+}
+
+// Save batch save events and invalidate their short keys
+func (r *CachedURLRepository) Save(ctx context.Context, events []*Event) error {
+	for _, event := range events {
+		r.invalidate(event.ShortKey)
+	}
+
+	err := r.URLRepository.Save(ctx, events)
+
+	// При конфликте хранилище подменяет короткий ключ на существующий
+	for _, event := range events {
+		r.invalidate(event.ShortKey)
+	}
+
+	return err
+}
+
+// Delete batch delete events and invalidate their short keys
+func (r *CachedURLRepository) Delete(ctx context.Context, events []DeleteRequestBatch) error {
+	err := r.URLRepository.Delete(ctx, events)
+
+	for _, event := range events {
+		for _, shortKey := range event.ShortKeys {
+			r.invalidate(shortKey)
+		}
+	}
+
+	return err
+}
+
+// DeleteExpired soft delete expired links and reset cache
+func (r *CachedURLRepository) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	count, err := r.URLRepository.DeleteExpired(ctx, now)
+
+	// Хранилище не сообщает, какие ключи удалены
+	if count > 0 {
+		r.purge()
+	}
+
+	return count, err
+}
+
+// Hits number of Get calls served from cache
+func (r *CachedURLRepository) Hits() uint64 {
+	return r.hits.Load()
+}
+
+// Misses number of Get calls passed to wrapped repository
+func (r *CachedURLRepository) Misses() uint64 {
+	return r.misses.Load()
+}
+
+// Len number of cached entries
+func (r *CachedURLRepository) Len() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.order.Len()
+}
+
+// load возвращает запись из кеша, ok=false если записи нет или она устарела
+func (r *CachedURLRepository) load(shortKey string) (event Event, err error, ok bool, generation uint64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	element, exist := r.entries[shortKey]
+	if !exist {
+		return Event{}, nil, false, r.generation
+	}
+
+	entry := element.Value.(*cacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		r.remove(element)
+		return Event{}, nil, false, r.generation
+	}
+
+	r.order.MoveToFront(element)
+
+	return entry.event, entry.err, true, r.generation
+}
+
+// store сохраняет результат запроса, вытесняя самую старую запись
+func (r *CachedURLRepository) store(shortKey string, event Event, err error, generation uint64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// Пока шел запрос в хранилище, данные могли измениться
+	if generation != r.generation {
+		return
+	}
+
+	entry := &cacheEntry{
+		shortKey:  shortKey,
+		event:     event,
+		err:       err,
+		expiresAt: time.Now().Add(r.ttl),
+	}
+
+	if element, exist := r.entries[shortKey]; exist {
+		element.Value = entry
+		r.order.MoveToFront(element)
+		return
+	}
+
+	r.entries[shortKey] = r.order.PushFront(entry)
+
+	if r.order.Len() > r.size {
+		r.remove(r.order.Back())
+	}
+}
+
+// invalidate удаляет ключ из кеша
+func (r *CachedURLRepository) invalidate(shortKey string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.generation++
+	if element, exist := r.entries[shortKey]; exist {
+		r.remove(element)
+	}
+}
+
+// purge очищает кеш
+func (r *CachedURLRepository) purge() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.generation++
+	r.entries = make(map[string]*list.Element, r.size)
+	r.order.Init()
+}
+
+// remove удаляет запись, вызывается под блокировкой
+func (r *CachedURLRepository) remove(element *list.Element) {
+	r.order.Remove(element)
+	delete(r.entries, element.Value.(*cacheEntry).shortKey)
+}
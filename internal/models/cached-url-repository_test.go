@@ -0,0 +1,160 @@
+package models_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ShukinDmitriy/shortener/internal/models"
+	mocks "github.com/ShukinDmitriy/shortener/mocks/internal_/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCachedURLRepository_Get(t *testing.T) {
+	type want struct {
+		err    error
+		calls  int
+		hits   uint64
+		misses uint64
+	}
+	tests := []struct {
+		name     string
+		shortKey string
+		event    models.Event
+		err      error
+		gets     int
+		want     want
+	}{
+		{
+			name:     "positive test #1",
+			shortKey: "short1",
+			event: models.Event{
+				ShortKey:    "short1",
+				OriginalURL: "https://example.com",
+			},
+			gets: 3,
+			want: want{
+				calls:  1,
+				hits:   2,
+				misses: 1,
+			},
+		},
+		{
+			name:     "negative caching #1",
+			shortKey: "unknown",
+			err:      models.ErrNotFound,
+			gets:     3,
+			want: want{
+				err:    models.ErrNotFound,
+				calls:  1,
+				hits:   2,
+				misses: 1,
+			},
+		},
+		{
+			name:     "negative test #1",
+			shortKey: "short1",
+			err:      models.ErrUnavailable,
+			gets:     3,
+			want: want{
+				err:    models.ErrUnavailable,
+				calls:  3,
+				hits:   0,
+				misses: 3,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := mocks.NewURLRepository(t)
+			repository.EXPECT().Get(mock.Anything, tt.shortKey).Return(tt.event, tt.err).Times(tt.want.calls)
+
+			cached := models.NewCachedURLRepository(repository, 10, time.Minute)
+			for i := 0; i < tt.gets; i++ {
+				event, err := cached.Get(context.TODO(), tt.shortKey)
+				assert.Equal(t, tt.want.err, err)
+				assert.Equal(t, tt.event.OriginalURL, event.OriginalURL)
+			}
+
+			assert.Equal(t, tt.want.hits, cached.Hits())
+			assert.Equal(t, tt.want.misses, cached.Misses())
+		})
+	}
+}
+
+func TestCachedURLRepository_Eviction(t *testing.T) {
+	repository := mocks.NewURLRepository(t)
+	repository.EXPECT().Get(mock.Anything, mock.Anything).Return(models.Event{}, models.ErrNotFound)
+
+	cached := models.NewCachedURLRepository(repository, 2, time.Minute)
+	cached.Get(context.TODO(), "short1")
+	cached.Get(context.TODO(), "short2")
+	// short1 становится самым свежим, вытеснен будет short2
+	cached.Get(context.TODO(), "short1")
+	cached.Get(context.TODO(), "short3")
+	assert.Equal(t, 2, cached.Len())
+
+	cached.Get(context.TODO(), "short1")
+	assert.Equal(t, uint64(2), cached.Hits())
+
+	cached.Get(context.TODO(), "short2")
+	assert.Equal(t, uint64(2), cached.Hits())
+	assert.Equal(t, uint64(4), cached.Misses())
+}
+
+func TestCachedURLRepository_TTL(t *testing.T) {
+	repository := mocks.NewURLRepository(t)
+	repository.EXPECT().Get(mock.Anything, "short1").Return(models.Event{}, models.ErrNotFound).Times(2)
+
+	cached := models.NewCachedURLRepository(repository, 10, 10*time.Millisecond)
+	cached.Get(context.TODO(), "short1")
+	time.Sleep(20 * time.Millisecond)
+	cached.Get(context.TODO(), "short1")
+
+	assert.Equal(t, uint64(0), cached.Hits())
+	assert.Equal(t, uint64(2), cached.Misses())
+}
+
+func TestCachedURLRepository_Invalidate(t *testing.T) {
+	repository := mocks.NewURLRepository(t)
+	event := models.Event{
+		ShortKey:    "short1",
+		OriginalURL: "https://example.com",
+		UserID:      "1",
+	}
+
+	cached := models.NewCachedURLRepository(repository, 10, time.Minute)
+
+	// Отрицательный ответ сбрасывается после сохранения
+	repository.EXPECT().Get(mock.Anything, "short1").Return(models.Event{}, models.ErrNotFound).Once()
+	_, err := cached.Get(context.TODO(), "short1")
+	assert.ErrorIs(t, err, models.ErrNotFound)
+
+	repository.EXPECT().Save(mock.Anything, []*models.Event{&event}).Return(nil).Once()
+	assert.NoError(t, cached.Save(context.TODO(), []*models.Event{&event}))
+
+	repository.EXPECT().Get(mock.Anything, "short1").Return(event, nil).Once()
+	_, err = cached.Get(context.TODO(), "short1")
+	assert.NoError(t, err)
+
+	// Удаление сбрасывает закешированную ссылку
+	deleteRequest := []models.DeleteRequestBatch{{ShortKeys: []string{"short1"}, UserID: "1"}}
+	repository.EXPECT().Delete(mock.Anything, deleteRequest).Return(nil).Once()
+	assert.NoError(t, cached.Delete(context.TODO(), deleteRequest))
+
+	deleted := event
+	deleted.DeletedFlag = true
+	repository.EXPECT().Get(mock.Anything, "short1").Return(deleted, models.ErrDeleted).Once()
+	getEvent, err := cached.Get(context.TODO(), "short1")
+	assert.ErrorIs(t, err, models.ErrDeleted)
+	assert.True(t, getEvent.DeletedFlag)
+
+	// Очистка просроченных ссылок сбрасывает весь кеш
+	repository.EXPECT().DeleteExpired(mock.Anything, mock.Anything).Return(1, nil).Once()
+	count, err := cached.DeleteExpired(context.TODO(), time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, 0, cached.Len())
+}
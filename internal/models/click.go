@@ -0,0 +1,117 @@
+package models
+
+import (
+	"net"
+	"sort"
+	"time"
+)
+
+const (
+	// clickIPv4PrefixLength длина префикса для группировки IPv4 адресов
+	clickIPv4PrefixLength = 24
+	// clickIPv6PrefixLength длина префикса для группировки IPv6 адресов
+	clickIPv6PrefixLength = 48
+	// clickDayLayout формат дня в статистике переходов
+	clickDayLayout = "2006-01-02"
+)
+
+// Click redirect by short link event structure
+type Click struct {
+	ShortKey  string    `json:"short_key"`
+	CreatedAt time.Time `json:"created_at"`
+	Referrer  string    `json:"referrer,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	IP        string    `json:"ip,omitempty"`
+	IPPrefix  string    `json:"ip_prefix,omitempty"`
+}
+
+// NewClick create click event and calculate client's network prefix
+func NewClick(shortKey string, createdAt time.Time, referrer string, userAgent string, ip string) *Click {
+	return &Click{
+		ShortKey:  shortKey,
+		CreatedAt: createdAt.UTC(),
+		Referrer:  referrer,
+		UserAgent: userAgent,
+		IP:        ip,
+		IPPrefix:  IPPrefix(ip),
+	}
+}
+
+// ClickCount count of clicks grouped by key
+type ClickCount struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+}
+
+// ClickStats short link's clicks statistic
+type ClickStats struct {
+	Total      int          `json:"total"`
+	ByDay      []ClickCount `json:"by_day"`
+	ByReferrer []ClickCount `json:"by_referrer"`
+	ByIPPrefix []ClickCount `json:"by_ip_prefix"`
+}
+
+// IPPrefix get client's network (/24 for IPv4 and /48 for IPv6)
+func IPPrefix(ip string) string {
+	parsedIP := net.ParseIP(ip)
+	if parsedIP == nil {
+		return ""
+	}
+
+	if ipv4 := parsedIP.To4(); ipv4 != nil {
+		mask := net.CIDRMask(clickIPv4PrefixLength, 32)
+		return (&net.IPNet{IP: ipv4.Mask(mask), Mask: mask}).String()
+	}
+
+	mask := net.CIDRMask(clickIPv6PrefixLength, 128)
+
+	return (&net.IPNet{IP: parsedIP.Mask(mask), Mask: mask}).String()
+}
+
+// NewClickStats aggregate clicks
+func NewClickStats(clicks []Click) *ClickStats {
+	byDay := make(map[string]int)
+	byReferrer := make(map[string]int)
+	byIPPrefix := make(map[string]int)
+
+	for _, click := range clicks {
+		byDay[click.CreatedAt.UTC().Format(clickDayLayout)]++
+		byReferrer[click.Referrer]++
+		byIPPrefix[click.IPPrefix]++
+	}
+
+	stats := &ClickStats{
+		Total:      len(clicks),
+		ByDay:      clickCounts(byDay),
+		ByReferrer: clickCounts(byReferrer),
+		ByIPPrefix: clickCounts(byIPPrefix),
+	}
+
+	// Статистика по дням отображается в хронологическом порядке
+	sort.Slice(stats.ByDay, func(i, j int) bool {
+		return stats.ByDay[i].Key < stats.ByDay[j].Key
+	})
+
+	return stats
+}
+
+// clickCounts преобразует счетчики в список по убыванию количества переходов
+func clickCounts(counts map[string]int) []ClickCount {
+	result := make([]ClickCount, 0, len(counts))
+	for key, count := range counts {
+		result = append(result, ClickCount{
+			Key:   key,
+			Count: count,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count == result[j].Count {
+			return result[i].Key < result[j].Key
+		}
+
+		return result[i].Count > result[j].Count
+	})
+
+	return result
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIPPrefix(t *testing.T) {
+	tests := []struct {
+		name string
+		ip   string
+		want string
+	}{
+		{
+			name: "positive test #1",
+			ip:   "192.168.10.25",
+			want: "192.168.10.0/24",
+		},
+		{
+			name: "positive test #2",
+			ip:   "2001:db8:abcd:12::1",
+			want: "2001:db8:abcd::/48",
+		},
+		{
+			name: "negative test #1",
+			ip:   "not an ip",
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IPPrefix(tt.ip))
+		})
+	}
+}
+
+func TestNewClickStats(t *testing.T) {
+	day1 := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	day2 := time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC)
+
+	clicks := []Click{
+		*NewClick("short1", day2, "https://google.com", "agent", "10.0.0.1"),
+		*NewClick("short1", day1, "https://google.com", "agent", "10.0.0.2"),
+		*NewClick("short1", day1, "", "agent", "10.0.1.1"),
+	}
+
+	stats := NewClickStats(clicks)
+
+	assert.Equal(t, 3, stats.Total)
+	assert.Equal(t, []ClickCount{
+		{Key: "2024-03-01", Count: 2},
+		{Key: "2024-03-02", Count: 1},
+	}, stats.ByDay)
+	assert.Equal(t, []ClickCount{
+		{Key: "https://google.com", Count: 2},
+		{Key: "", Count: 1},
+	}, stats.ByReferrer)
+	assert.Equal(t, []ClickCount{
+		{Key: "10.0.0.0/24", Count: 2},
+		{Key: "10.0.1.0/24", Count: 1},
+	}, stats.ByIPPrefix)
+
+	empty := NewClickStats(nil)
+	assert.Equal(t, 0, empty.Total)
+	assert.Empty(t, empty.ByDay)
+}
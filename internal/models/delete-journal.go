@@ -0,0 +1,238 @@
+package models
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// DeleteJournalEntry delete request written to the journal before it is applied
+type DeleteJournalEntry struct {
+	ID      uint64
+	Request DeleteRequestBatch
+}
+
+// DeleteJournal write-ahead journal of the deletion queue.
+// A request is appended before it is accepted and acknowledged once the repository
+// has applied it, so pending requests survive a restart and are replayed.
+type DeleteJournal interface {
+	Append(req DeleteRequestBatch) (uint64, error)
+	Ack(ids []uint64) error
+	Pending() []DeleteJournalEntry
+	Close() error
+}
+
+// deleteJournalRecord строка журнала: запрос на удаление или подтверждение выполненных запросов
+type deleteJournalRecord struct {
+	ID        uint64   `json:"id,omitempty"`
+	UserID    string   `json:"user_id,omitempty"`
+	ShortKeys []string `json:"short_keys,omitempty"`
+	Ack       []uint64 `json:"ack,omitempty"`
+}
+
+// FileDeleteJournal journal in a JSON lines file, each record is synced to disk
+type FileDeleteJournal struct {
+	mu       sync.Mutex
+	filename string
+	file     *os.File
+	nextID   uint64
+	pending  map[uint64]DeleteRequestBatch
+}
+
+// NewFileDeleteJournal open the journal, read pending requests and compact the file
+func NewFileDeleteJournal(filename string) (*FileDeleteJournal, error) {
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return nil, err
+	}
+
+	journal := &FileDeleteJournal{
+		filename: filename,
+		nextID:   1,
+		pending:  map[uint64]DeleteRequestBatch{},
+	}
+
+	if err := journal.load(); err != nil {
+		return nil, err
+	}
+
+	if err := journal.compact(); err != nil {
+		return nil, err
+	}
+
+	return journal, nil
+}
+
+// Append write the request to the journal, it is pending until acknowledged
+func (j *FileDeleteJournal) Append(req DeleteRequestBatch) (uint64, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	id := j.nextID
+	err := j.write(deleteJournalRecord{
+		ID:        id,
+		UserID:    req.UserID,
+		ShortKeys: req.ShortKeys,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	j.nextID++
+	j.pending[id] = req
+
+	return id, nil
+}
+
+// Ack mark requests as applied, the file is truncated once nothing is pending
+func (j *FileDeleteJournal) Ack(ids []uint64) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if err := j.write(deleteJournalRecord{Ack: ids}); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		delete(j.pending, id)
+	}
+
+	if len(j.pending) > 0 {
+		return nil
+	}
+
+	return j.file.Truncate(0)
+}
+
+// Pending requests that were not acknowledged, in the order they were appended
+func (j *FileDeleteJournal) Pending() []DeleteJournalEntry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.pendingLocked()
+}
+
+// Close the journal file
+func (j *FileDeleteJournal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.file.Close()
+}
+
+// load читает журнал, оборванная при сбое последняя строка пропускается
+func (j *FileDeleteJournal) load() error {
+	file, err := os.Open(j.filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 16*1024*1024)
+	for scanner.Scan() {
+		var record deleteJournalRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+
+		for _, id := range record.Ack {
+			delete(j.pending, id)
+		}
+
+		if record.ID == 0 {
+			continue
+		}
+
+		j.pending[record.ID] = DeleteRequestBatch{
+			UserID:    record.UserID,
+			ShortKeys: record.ShortKeys,
+		}
+		if record.ID >= j.nextID {
+			j.nextID = record.ID + 1
+		}
+	}
+
+	return scanner.Err()
+}
+
+// compact переписывает журнал, оставляя только невыполненные запросы.
+// Новый файл подменяет старый целиком, поэтому сбой во время сжатия ничего не теряет.
+func (j *FileDeleteJournal) compact() error {
+	tmpName := j.filename + ".tmp"
+	tmp, err := os.OpenFile(tmpName, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o666)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(writer)
+	for _, entry := range j.pendingLocked() {
+		err = encoder.Encode(deleteJournalRecord{
+			ID:        entry.ID,
+			UserID:    entry.Request.UserID,
+			ShortKeys: entry.Request.ShortKeys,
+		})
+		if err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpName, j.filename)
+	}
+	if err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+
+	j.file, err = os.OpenFile(j.filename, os.O_WRONLY|os.O_APPEND, 0o666)
+
+	return err
+}
+
+// pendingLocked невыполненные запросы по порядку, вызывается под блокировкой или при открытии журнала
+func (j *FileDeleteJournal) pendingLocked() []DeleteJournalEntry {
+	entries := make([]DeleteJournalEntry, 0, len(j.pending))
+	for id, req := range j.pending {
+		entries = append(entries, DeleteJournalEntry{ID: id, Request: req})
+	}
+
+	sort.Slice(entries, func(a, b int) bool {
+		return entries[a].ID < entries[b].ID
+	})
+
+	return entries
+}
+
+// write дописывает запись и сбрасывает ее на диск
+func (j *FileDeleteJournal) write(record deleteJournalRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	if _, err := j.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+
+	return j.file.Sync()
+}
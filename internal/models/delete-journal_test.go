@@ -0,0 +1,63 @@
+package models_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ShukinDmitriy/shortener/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileDeleteJournal(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "queue", "delete-queue.json")
+
+	journal, err := models.NewFileDeleteJournal(filename)
+	require.NoError(t, err)
+	assert.Empty(t, journal.Pending())
+
+	first, err := journal.Append(models.DeleteRequestBatch{UserID: "1", ShortKeys: []string{"a", "b"}})
+	require.NoError(t, err)
+	second, err := journal.Append(models.DeleteRequestBatch{UserID: "2", ShortKeys: []string{"c"}})
+	require.NoError(t, err)
+	third, err := journal.Append(models.DeleteRequestBatch{UserID: "3", ShortKeys: []string{"d"}})
+	require.NoError(t, err)
+
+	require.NoError(t, journal.Ack([]uint64{second}))
+
+	// Сбой посреди записи оставляет оборванную строку
+	require.NoError(t, journal.Close())
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, 0o666)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"id":4,"user_id":"4","short_`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	// После перезапуска остаются только неподтвержденные запросы
+	journal, err = models.NewFileDeleteJournal(filename)
+	require.NoError(t, err)
+	assert.Equal(t, []models.DeleteJournalEntry{
+		{ID: first, Request: models.DeleteRequestBatch{UserID: "1", ShortKeys: []string{"a", "b"}}},
+		{ID: third, Request: models.DeleteRequestBatch{UserID: "3", ShortKeys: []string{"d"}}},
+	}, journal.Pending())
+
+	// Идентификаторы не повторяются
+	next, err := journal.Append(models.DeleteRequestBatch{UserID: "5", ShortKeys: []string{"e"}})
+	require.NoError(t, err)
+	assert.Greater(t, next, third)
+
+	// Когда подтверждено все, журнал очищается
+	require.NoError(t, journal.Ack([]uint64{first, third, next}))
+	assert.Empty(t, journal.Pending())
+
+	info, err := os.Stat(filename)
+	require.NoError(t, err)
+	assert.Zero(t, info.Size())
+	require.NoError(t, journal.Close())
+
+	journal, err = models.NewFileDeleteJournal(filename)
+	require.NoError(t, err)
+	assert.Empty(t, journal.Pending())
+	require.NoError(t, journal.Close())
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrExpirationInvalid expiration is in the past or set twice
+var ErrExpirationInvalid = errors.New("expiration is invalid")
+
+// PrepareExpiresAt calculate link expiration from absolute time or TTL in seconds
+func PrepareExpiresAt(expiresAt *time.Time, ttl int64, now time.Time) (*time.Time, error) {
+	// Можно передать что-то одно
+	if expiresAt != nil && ttl != 0 {
+		return nil, ErrExpirationInvalid
+	}
+
+	if ttl < 0 {
+		return nil, ErrExpirationInvalid
+	}
+
+	if ttl > 0 {
+		result := now.Add(time.Duration(ttl) * time.Second).UTC()
+		return &result, nil
+	}
+
+	if expiresAt == nil {
+		return nil, nil
+	}
+
+	if !expiresAt.After(now) {
+		return nil, ErrExpirationInvalid
+	}
+
+	result := expiresAt.UTC()
+
+	return &result, nil
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrepareExpiresAt(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	future := now.Add(time.Hour)
+	past := now.Add(-time.Hour)
+
+	type args struct {
+		expiresAt *time.Time
+		ttl       int64
+	}
+	type want struct {
+		expiresAt *time.Time
+		err       error
+	}
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "positive test #1",
+		},
+		{
+			name: "positive test #2",
+			args: args{
+				ttl: 3600,
+			},
+			want: want{
+				expiresAt: &future,
+			},
+		},
+		{
+			name: "positive test #3",
+			args: args{
+				expiresAt: &future,
+			},
+			want: want{
+				expiresAt: &future,
+			},
+		},
+		{
+			name: "negative test #1",
+			args: args{
+				expiresAt: &past,
+			},
+			want: want{
+				err: ErrExpirationInvalid,
+			},
+		},
+		{
+			name: "negative test #2",
+			args: args{
+				expiresAt: &future,
+				ttl:       3600,
+			},
+			want: want{
+				err: ErrExpirationInvalid,
+			},
+		},
+		{
+			name: "negative test #3",
+			args: args{
+				ttl: -1,
+			},
+			want: want{
+				err: ErrExpirationInvalid,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PrepareExpiresAt(tt.args.expiresAt, tt.args.ttl, now)
+			require.ErrorIs(t, err, tt.want.err)
+
+			if tt.want.expiresAt == nil {
+				assert.Nil(t, got)
+				return
+			}
+
+			require.NotNil(t, got)
+			assert.True(t, tt.want.expiresAt.Equal(*got))
+		})
+	}
+}
+
+func TestEvent_IsExpired(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Second)
+	future := now.Add(time.Second)
+
+	assert.False(t, Event{}.IsExpired(now))
+	assert.True(t, Event{ExpiresAt: &past}.IsExpired(now))
+	assert.True(t, Event{ExpiresAt: &now}.IsExpired(now))
+	assert.False(t, Event{ExpiresAt: &future}.IsExpired(now))
+}
@@ -41,6 +41,9 @@ func (p *Producer) WriteEvent(event interface{}) error {
 		return err
 	}
 
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
 	// записываем событие в буфер
 	if _, err := p.writer.Write(data); err != nil {
 		return err
@@ -55,6 +58,11 @@ func (p *Producer) WriteEvent(event interface{}) error {
 	return p.writer.Flush()
 }
 
+// Close the file
+func (p *Producer) Close() error {
+	return p.file.Close()
+}
+
 // NewConsumer create consumer
 func NewConsumer(filename string) (*Consumer, error) {
 	file, err := os.OpenFile(filename, os.O_RDONLY|os.O_CREATE, 0o666)
@@ -87,7 +95,41 @@ func (c *Consumer) ReadEvent() (*Event, error) {
 	return &event, nil
 }
 
+// ReadLine next line of the file, nil at the end
+func (c *Consumer) ReadLine() ([]byte, error) {
+	if !c.scanner.Scan() {
+		return nil, c.scanner.Err()
+	}
+
+	return c.scanner.Bytes(), nil
+}
+
+// ReadClick from file
+func (c *Consumer) ReadClick() (*Click, error) {
+	// одиночное сканирование до следующей строки
+	if !c.scanner.Scan() {
+		return nil, c.scanner.Err()
+	}
+	// читаем данные из scanner
+	data := c.scanner.Bytes()
+
+	click := Click{}
+	err := json.Unmarshal(data, &click)
+	if err != nil {
+		return nil, err
+	}
+
+	return &click, nil
+}
+
 // Close the file
 func (c *Consumer) Close() error {
 	return c.file.Close()
 }
+
+// clickFileName файл с переходами хранится рядом с файлом ссылок
+func clickFileName(filename string) string {
+	ext := filepath.Ext(filename)
+
+	return strings.TrimSuffix(filename, ext) + "-clicks" + ext
+}
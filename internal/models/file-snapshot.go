@@ -0,0 +1,155 @@
+package models
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// snapshotVersion версия формата снимка в файле хранения
+const snapshotVersion = 1
+
+// ErrSnapshotCorrupted snapshot at the beginning of the storage file doesn't match its checksum
+var ErrSnapshotCorrupted = errors.New("snapshot corrupted")
+
+// Compactor storage that can replace its log of changes with a snapshot of the current state
+type Compactor interface {
+	Compact(ctx context.Context) error
+}
+
+// AsCompactor find the compactor behind the repository decorators
+func AsCompactor(repository URLRepository) (Compactor, bool) {
+	for {
+		if compactor, ok := repository.(Compactor); ok {
+			return compactor, true
+		}
+
+		switch r := repository.(type) {
+		case *InstrumentedURLRepository:
+			repository = r.URLRepository
+		case *CachedURLRepository:
+			repository = r.URLRepository
+		default:
+			return nil, false
+		}
+	}
+}
+
+// snapshotHeader первая строка файла после сжатия.
+// За ним следуют Count строк снимка, затем журнал изменений.
+type snapshotHeader struct {
+	Version  int    `json:"version"`
+	Count    int    `json:"count"`
+	Checksum string `json:"checksum"`
+}
+
+// readSnapshotHeader строка заголовка отличается от события наличием версии
+func readSnapshotHeader(line []byte) (snapshotHeader, bool) {
+	var header snapshotHeader
+	if err := json.Unmarshal(line, &header); err != nil || header.Version == 0 {
+		return snapshotHeader{}, false
+	}
+
+	return header, true
+}
+
+// readSnapshot читает строки снимка после заголовка и сверяет контрольную сумму
+func readSnapshot(consumer *Consumer, header snapshotHeader) ([]Event, error) {
+	if header.Version > snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", header.Version)
+	}
+
+	events := make([]Event, 0, header.Count)
+	hash := sha256.New()
+
+	for i := 0; i < header.Count; i++ {
+		line, err := consumer.ReadLine()
+		if err != nil {
+			return nil, err
+		}
+		if line == nil {
+			return nil, fmt.Errorf("%w: %d of %d events", ErrSnapshotCorrupted, i, header.Count)
+		}
+
+		hash.Write(line)
+		hash.Write([]byte{'\n'})
+
+		var event Event
+		if err := json.Unmarshal(line, &event); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrSnapshotCorrupted, err)
+		}
+		events = append(events, event)
+	}
+
+	if hex.EncodeToString(hash.Sum(nil)) != header.Checksum {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrSnapshotCorrupted)
+	}
+
+	return events, nil
+}
+
+// writeSnapshot атомарно заменяет файл снимком: пишем во временный файл рядом и переименовываем.
+// При сбое остается либо старый файл с журналом, либо новый снимок целиком.
+func writeSnapshot(filename string, events []Event) error {
+	var body bytes.Buffer
+	hash := sha256.New()
+
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		data = append(data, '\n')
+
+		body.Write(data)
+		hash.Write(data)
+	}
+
+	header, err := json.Marshal(snapshotHeader{
+		Version:  snapshotVersion,
+		Count:    len(events),
+		Checksum: hex.EncodeToString(hash.Sum(nil)),
+	})
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(filename)
+	tmp, err := os.CreateTemp(dir, filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(append(header, '\n'))
+	if err == nil {
+		_, err = body.WriteTo(tmp)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = os.Rename(tmp.Name(), filename); err != nil {
+		return err
+	}
+
+	// Сохраняем на диск и саму замену файла в каталоге
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+
+	return nil
+}
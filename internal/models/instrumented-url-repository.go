@@ -0,0 +1,150 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ShukinDmitriy/shortener/internal/environments"
+	"github.com/ShukinDmitriy/shortener/internal/metrics"
+)
+
+// InstrumentedURLRepository decorator collecting latency and errors of repository operations
+type InstrumentedURLRepository struct {
+	URLRepository
+}
+
+// NewInstrumentedURLRepository wrap repository with metrics
+func NewInstrumentedURLRepository(repository URLRepository) *InstrumentedURLRepository {
+	return &InstrumentedURLRepository{
+		URLRepository: repository,
+	}
+}
+
+// Initialize wrapped repository
+func (r *InstrumentedURLRepository) Initialize(ctx context.Context, configuration environments.Configuration) error {
+	start := time.Now()
+	err := r.URLRepository.Initialize(ctx, configuration)
+	metrics.ObserveRepositoryOperation("Initialize", start, err)
+
+	return err
+}
+
+// Get event by short key
+func (r *InstrumentedURLRepository) Get(ctx context.Context, shortKey string) (Event, error) {
+	start := time.Now()
+	event, err := r.URLRepository.Get(ctx, shortKey)
+	metrics.ObserveRepositoryOperation("Get", start, failure(err))
+
+	return event, err
+}
+
+// Save batch save events
+func (r *InstrumentedURLRepository) Save(ctx context.Context, events []*Event) error {
+	start := time.Now()
+	err := r.URLRepository.Save(ctx, events)
+	metrics.ObserveRepositoryOperation("Save", start, failure(err))
+
+	return err
+}
+
+// Delete batch delete event
+func (r *InstrumentedURLRepository) Delete(ctx context.Context, events []DeleteRequestBatch) error {
+	start := time.Now()
+	err := r.URLRepository.Delete(ctx, events)
+	metrics.ObserveRepositoryOperation("Delete", start, err)
+
+	return err
+}
+
+// GetShortKeyByOriginalURL get short link from full link
+func (r *InstrumentedURLRepository) GetShortKeyByOriginalURL(ctx context.Context, originalURL string) (string, error) {
+	start := time.Now()
+	shortKey, err := r.URLRepository.GetShortKeyByOriginalURL(ctx, originalURL)
+	metrics.ObserveRepositoryOperation("GetShortKeyByOriginalURL", start, failure(err))
+
+	return shortKey, err
+}
+
+// GetEventsByUserID get events by user ID
+func (r *InstrumentedURLRepository) GetEventsByUserID(ctx context.Context, userID string) ([]*Event, error) {
+	start := time.Now()
+	events, err := r.URLRepository.GetEventsByUserID(ctx, userID)
+	metrics.ObserveRepositoryOperation("GetEventsByUserID", start, err)
+
+	return events, err
+}
+
+// IterateEventsByUserID pass active links of the user to fn
+func (r *InstrumentedURLRepository) IterateEventsByUserID(ctx context.Context, userID string, fn EventIterator) error {
+	start := time.Now()
+	err := r.URLRepository.IterateEventsByUserID(ctx, userID, fn)
+	metrics.ObserveRepositoryOperation("IterateEventsByUserID", start, err)
+
+	return err
+}
+
+// IterateEvents pass active links of all users to fn
+func (r *InstrumentedURLRepository) IterateEvents(ctx context.Context, fn EventIterator) error {
+	start := time.Now()
+	err := r.URLRepository.IterateEvents(ctx, fn)
+	metrics.ObserveRepositoryOperation("IterateEvents", start, err)
+
+	return err
+}
+
+// IterateShortKeys pass short keys of all links, deleted and expired ones included
+func (r *InstrumentedURLRepository) IterateShortKeys(ctx context.Context, fn ShortKeyIterator) error {
+	start := time.Now()
+	err := r.URLRepository.IterateShortKeys(ctx, fn)
+	metrics.ObserveRepositoryOperation("IterateShortKeys", start, err)
+
+	return err
+}
+
+// GetStats get repository's stats
+func (r *InstrumentedURLRepository) GetStats(ctx context.Context) (int, int, error) {
+	start := time.Now()
+	countUser, countURL, err := r.URLRepository.GetStats(ctx)
+	metrics.ObserveRepositoryOperation("GetStats", start, err)
+
+	return countUser, countURL, err
+}
+
+// DeleteExpired soft delete links which expired at the moment
+func (r *InstrumentedURLRepository) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	start := time.Now()
+	count, err := r.URLRepository.DeleteExpired(ctx, now)
+	metrics.ObserveRepositoryOperation("DeleteExpired", start, err)
+
+	return count, err
+}
+
+// SaveClicks batch save clicks
+func (r *InstrumentedURLRepository) SaveClicks(ctx context.Context, clicks []*Click) error {
+	start := time.Now()
+	err := r.URLRepository.SaveClicks(ctx, clicks)
+	metrics.ObserveRepositoryOperation("SaveClicks", start, err)
+
+	return err
+}
+
+// GetClickStats get short link's clicks statistic
+func (r *InstrumentedURLRepository) GetClickStats(ctx context.Context, shortKey string) (*ClickStats, error) {
+	start := time.Now()
+	stats, err := r.URLRepository.GetClickStats(ctx, shortKey)
+	metrics.ObserveRepositoryOperation("GetClickStats", start, err)
+
+	return stats, err
+}
+
+// failure отбрасывает ожидаемые ответы хранилища, которые не являются его сбоем:
+// конфликты ключей, отсутствующие и удаленные ссылки
+func failure(err error) error {
+	if errors.Is(err, ErrURLExist) || errors.Is(err, ErrShortKeyExist) ||
+		errors.Is(err, ErrNotFound) || errors.Is(err, ErrDeleted) {
+		return nil
+	}
+
+	return err
+}
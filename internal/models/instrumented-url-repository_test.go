@@ -0,0 +1,53 @@
+package models_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ShukinDmitriy/shortener/internal/metrics"
+	"github.com/ShukinDmitriy/shortener/internal/models"
+	mocks "github.com/ShukinDmitriy/shortener/mocks/internal_/models"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestInstrumentedURLRepository_Save(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		errorsDelta float64
+	}{
+		{
+			name:        "positive test #1",
+			errorsDelta: 0,
+		},
+		{
+			name:        "conflict test #1",
+			err:         models.ErrURLExist,
+			errorsDelta: 0,
+		},
+		{
+			name:        "negative test #1",
+			err:         errors.New("connection refused"),
+			errorsDelta: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := mocks.NewURLRepository(t)
+			repository.EXPECT().Save(mock.Anything, mock.Anything).Return(tt.err).Once()
+
+			errorsCounter := metrics.RepositoryErrorsTotal.WithLabelValues("Save")
+			errorsBefore := testutil.ToFloat64(errorsCounter)
+
+			instrumented := models.NewInstrumentedURLRepository(repository)
+			err := instrumented.Save(context.TODO(), []*models.Event{})
+
+			assert.Equal(t, tt.err, err)
+			assert.Equal(t, errorsBefore+tt.errorsDelta, testutil.ToFloat64(errorsCounter))
+		})
+	}
+}
@@ -0,0 +1,402 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/ShukinDmitriy/shortener/internal/environments"
+)
+
+const (
+	// KeyGeneratorRandom криптографически случайные ключи
+	KeyGeneratorRandom = "random"
+	// KeyGeneratorSequential последовательные номера в системе счисления алфавита
+	KeyGeneratorSequential = "sequential"
+	// KeyGeneratorHash ключи на основе хеша оригинального URL
+	KeyGeneratorHash = "hash"
+
+	// DefaultKeyAlphabet алфавит коротких ключей по умолчанию (base62)
+	DefaultKeyAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	// DefaultKeyLength длина коротких ключей по умолчанию
+	DefaultKeyLength = 6
+
+	// maxShortKeyAttempts количество попыток подобрать свободный короткий ключ
+	maxShortKeyAttempts = 10
+)
+
+// ErrKeyGeneratorConfig wrong key generator configuration
+var ErrKeyGeneratorConfig = errors.New("key generator configuration is invalid")
+
+// ErrShortKeyGeneration free short key was not found
+var ErrShortKeyGeneration = errors.New("can't generate unique short key")
+
+// KeyGenerator short key generator
+type KeyGenerator interface {
+	// Generate short key for the original URL, attempt is the number of the retry after collision
+	Generate(originalURL string, attempt int) (string, error)
+}
+
+// NewKeyGenerator create key generator from configuration,
+// start is the first value for the sequential generator
+func NewKeyGenerator(configuration environments.Configuration, start uint64) (KeyGenerator, error) {
+	alphabet, length := keyFormat(configuration)
+
+	if err := validateKeyGeneratorConfig(alphabet, length); err != nil {
+		return nil, err
+	}
+
+	switch configuration.ShortKeyGenerator {
+	case "", KeyGeneratorRandom:
+		return NewRandomKeyGenerator(alphabet, length), nil
+	case KeyGeneratorSequential:
+		return NewSequentialKeyGenerator(alphabet, length, start), nil
+	case KeyGeneratorHash:
+		return NewHashKeyGenerator(alphabet, length), nil
+	}
+
+	return nil, fmt.Errorf("%w: unknown generator %q", ErrKeyGeneratorConfig, configuration.ShortKeyGenerator)
+}
+
+// keyFormat алфавит и длина ключей с учетом значений по умолчанию
+func keyFormat(configuration environments.Configuration) (string, int) {
+	alphabet := configuration.ShortKeyAlphabet
+	if alphabet == "" {
+		alphabet = DefaultKeyAlphabet
+	}
+
+	length := configuration.ShortKeyLength
+	if length == 0 {
+		length = DefaultKeyLength
+	}
+
+	return alphabet, length
+}
+
+// SequentialKeyStart the highest number encoded in the short keys of the repository,
+// deleted and expired links included, the sequential generator continues after it.
+// Keys of another length or with symbols outside of the alphabet are skipped.
+func SequentialKeyStart(ctx context.Context, configuration environments.Configuration, repository URLRepository) (uint64, error) {
+	alphabet, length := keyFormat(configuration)
+
+	var start uint64
+	err := repository.IterateShortKeys(ctx, func(shortKey string) error {
+		if len(shortKey) != length {
+			return nil
+		}
+
+		if value, ok := decodeKey(shortKey, alphabet); ok && value > start {
+			start = value
+		}
+
+		return nil
+	})
+
+	return start, err
+}
+
+// validateKeyGeneratorConfig проверяет, что ключи можно использовать в URL
+func validateKeyGeneratorConfig(alphabet string, length int) error {
+	if length < aliasMinLength || length > aliasMaxLength {
+		return fmt.Errorf("%w: length must be between %d and %d", ErrKeyGeneratorConfig, aliasMinLength, aliasMaxLength)
+	}
+
+	if len(alphabet) < 2 {
+		return fmt.Errorf("%w: alphabet is too short", ErrKeyGeneratorConfig)
+	}
+
+	for i, symbol := range alphabet {
+		if !strings.ContainsRune(aliasCharset, symbol) {
+			return fmt.Errorf("%w: symbol %q is not allowed", ErrKeyGeneratorConfig, symbol)
+		}
+
+		if strings.IndexRune(alphabet, symbol) != i {
+			return fmt.Errorf("%w: symbol %q is repeated", ErrKeyGeneratorConfig, symbol)
+		}
+	}
+
+	return nil
+}
+
+// GenerateUniqueShortKey generate short key which is not used in the repository
+func GenerateUniqueShortKey(ctx context.Context, generator KeyGenerator, repository URLRepository, originalURL string) (string, error) {
+	return generateUniqueShortKey(ctx, generator, repository, originalURL, nil)
+}
+
+// generateUniqueShortKey подбирает ключ, свободный в хранилище и не входящий в taken
+func generateUniqueShortKey(
+	ctx context.Context,
+	generator KeyGenerator,
+	repository URLRepository,
+	originalURL string,
+	taken map[string]struct{},
+) (string, error) {
+	for attempt := 0; attempt < maxShortKeyAttempts; attempt++ {
+		shortKey, err := generator.Generate(originalURL, attempt)
+		if err != nil {
+			return "", err
+		}
+
+		// Ключ не должен совпадать с маршрутами приложения
+		if _, reserved := reservedAliases[strings.ToLower(shortKey)]; reserved {
+			continue
+		}
+
+		// Ключ уже выдан другой ссылке того же пакета
+		if _, ok := taken[shortKey]; ok {
+			continue
+		}
+
+		_, err = repository.Get(ctx, shortKey)
+		if errors.Is(err, ErrNotFound) {
+			return shortKey, nil
+		}
+
+		// Без ответа хранилища нельзя гарантировать уникальность ключа
+		if errors.Is(err, ErrUnavailable) {
+			return "", err
+		}
+	}
+
+	return "", ErrShortKeyGeneration
+}
+
+// SaveWithShortKey save the event under the alias or under a generated short key.
+// Another link may take the generated key between the check and the insert,
+// then the key is generated again. A taken alias is returned as ErrShortKeyExist.
+func SaveWithShortKey(ctx context.Context, generator KeyGenerator, repository URLRepository, alias string, event *Event) error {
+	for attempt := 0; attempt < maxShortKeyAttempts; attempt++ {
+		shortKey, err := PrepareShortKey(ctx, generator, repository, alias, event.OriginalURL)
+		if err != nil {
+			return err
+		}
+		event.ShortKey = shortKey
+
+		err = repository.Save(ctx, []*Event{event})
+		if alias != "" || !errors.Is(err, ErrShortKeyExist) {
+			return err
+		}
+	}
+
+	return ErrShortKeyGeneration
+}
+
+// SaveBatchWithShortKeys save the events of a batch, an event without a short key gets a generated one.
+// Generated keys don't repeat inside the batch. A generated key taken by another link between
+// the check and the insert is generated again and only these events are saved once more.
+// The outcome of every event is returned as *SaveError like from URLRepository.Save:
+// ErrShortKeyExist is left only for aliases, a generated key which stays taken gives ErrShortKeyGeneration.
+func SaveBatchWithShortKeys(ctx context.Context, generator KeyGenerator, repository URLRepository, events []*Event) error {
+	errs := make([]error, len(events))
+	generated := make([]bool, len(events))
+	// ключи, занятые событиями пакета
+	taken := make(map[string]struct{}, len(events))
+
+	var pending, regenerate []int
+	for i, event := range events {
+		if event.ShortKey == "" {
+			generated[i] = true
+			regenerate = append(regenerate, i)
+			continue
+		}
+
+		taken[event.ShortKey] = struct{}{}
+		pending = append(pending, i)
+	}
+
+	for attempt := 0; attempt < maxShortKeyAttempts; attempt++ {
+		for _, i := range regenerate {
+			shortKey, err := generateUniqueShortKey(ctx, generator, repository, events[i].OriginalURL, taken)
+			// Без ответа хранилища нельзя гарантировать уникальность ключа
+			if errors.Is(err, ErrUnavailable) {
+				return err
+			}
+			if err != nil {
+				errs[i] = err
+				continue
+			}
+
+			events[i].ShortKey = shortKey
+			taken[shortKey] = struct{}{}
+			pending = append(pending, i)
+		}
+		regenerate = nil
+
+		// Пакет сохраняется в порядке запроса
+		slices.Sort(pending)
+		if len(pending) == 0 {
+			break
+		}
+
+		batch := make([]*Event, len(pending))
+		for j, i := range pending {
+			batch[j] = events[i]
+		}
+
+		err := repository.Save(ctx, batch)
+		var saveErr *SaveError
+		if err != nil && !errors.As(err, &saveErr) {
+			return err
+		}
+
+		for j, i := range pending {
+			errs[i] = SaveErrorAt(err, j)
+
+			if generated[i] && errors.Is(errs[i], ErrShortKeyExist) {
+				errs[i] = ErrShortKeyGeneration
+				regenerate = append(regenerate, i)
+			}
+		}
+		pending = nil
+	}
+
+	for _, err := range errs {
+		if err != nil {
+			return &SaveError{Errors: errs}
+		}
+	}
+
+	return nil
+}
+
+// RandomKeyGenerator generate keys with crypto/rand
+type RandomKeyGenerator struct {
+	alphabet string
+	length   int
+}
+
+// NewRandomKeyGenerator constructor for random key generator
+func NewRandomKeyGenerator(alphabet string, length int) *RandomKeyGenerator {
+	return &RandomKeyGenerator{
+		alphabet: alphabet,
+		length:   length,
+	}
+}
+
+// Generate random short key
+func (g *RandomKeyGenerator) Generate(_ string, _ int) (string, error) {
+	// Отбрасываем байты, которые дали бы неравномерное распределение символов
+	limit := 256 - 256%len(g.alphabet)
+	shortKey := make([]byte, 0, g.length)
+	buf := make([]byte, g.length*2)
+
+	for len(shortKey) < g.length {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+
+		for _, b := range buf {
+			if int(b) >= limit {
+				continue
+			}
+
+			shortKey = append(shortKey, g.alphabet[int(b)%len(g.alphabet)])
+			if len(shortKey) == g.length {
+				break
+			}
+		}
+	}
+
+	return string(shortKey), nil
+}
+
+// SequentialKeyGenerator encode incrementing counter with the alphabet
+type SequentialKeyGenerator struct {
+	alphabet string
+	length   int
+	counter  atomic.Uint64
+}
+
+// NewSequentialKeyGenerator constructor for sequential key generator
+func NewSequentialKeyGenerator(alphabet string, length int, start uint64) *SequentialKeyGenerator {
+	generator := &SequentialKeyGenerator{
+		alphabet: alphabet,
+		length:   length,
+	}
+	generator.counter.Store(start)
+
+	return generator
+}
+
+// Generate next short key
+func (g *SequentialKeyGenerator) Generate(_ string, _ int) (string, error) {
+	return encodeKey(new(big.Int).SetUint64(g.counter.Add(1)), g.alphabet, g.length), nil
+}
+
+// HashKeyGenerator generate keys from the original URL's hash
+type HashKeyGenerator struct {
+	alphabet string
+	length   int
+}
+
+// NewHashKeyGenerator constructor for hash key generator
+func NewHashKeyGenerator(alphabet string, length int) *HashKeyGenerator {
+	return &HashKeyGenerator{
+		alphabet: alphabet,
+		length:   length,
+	}
+}
+
+// Generate short key from the original URL, every attempt gives another key
+func (g *HashKeyGenerator) Generate(originalURL string, attempt int) (string, error) {
+	sum := sha256.Sum256([]byte(originalURL + "#" + strconv.Itoa(attempt)))
+
+	shortKey := encodeKey(new(big.Int).SetBytes(sum[:]), g.alphabet, g.length)
+
+	// Берем младшие разряды, чтобы длина ключа не зависела от значения хеша
+	return shortKey[len(shortKey)-g.length:], nil
+}
+
+// decodeKey переводит ключ из системы счисления алфавита в число,
+// ok ложно, если в ключе есть символ не из алфавита или число не помещается в uint64
+func decodeKey(shortKey string, alphabet string) (uint64, bool) {
+	base := big.NewInt(int64(len(alphabet)))
+	value := new(big.Int)
+
+	for _, symbol := range shortKey {
+		digit := strings.IndexRune(alphabet, symbol)
+		if digit < 0 {
+			return 0, false
+		}
+
+		value.Mul(value, base)
+		value.Add(value, big.NewInt(int64(digit)))
+	}
+
+	if !value.IsUint64() {
+		return 0, false
+	}
+
+	return value.Uint64(), true
+}
+
+// encodeKey переводит число в систему счисления алфавита,
+// дополняя результат первым символом алфавита до нужной длины
+func encodeKey(value *big.Int, alphabet string, length int) string {
+	base := big.NewInt(int64(len(alphabet)))
+	mod := new(big.Int)
+
+	var digits []byte
+	for value.Sign() > 0 {
+		value.DivMod(value, base, mod)
+		digits = append(digits, alphabet[mod.Int64()])
+	}
+
+	for len(digits) < length {
+		digits = append(digits, alphabet[0])
+	}
+
+	// Цифры получены от младших разрядов к старшим
+	for i, j := 0, len(digits)-1; i < j; i, j = i+1, j-1 {
+		digits[i], digits[j] = digits[j], digits[i]
+	}
+
+	return string(digits)
+}
@@ -0,0 +1,311 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ShukinDmitriy/shortener/internal/environments"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// constKeyGenerator всегда возвращает один и тот же ключ
+type constKeyGenerator string
+
+func (g constKeyGenerator) Generate(_ string, _ int) (string, error) {
+	return string(g), nil
+}
+
+// GenerateShortKey случайный ключ для тестов хранилищ
+func GenerateShortKey() string {
+	shortKey, err := NewRandomKeyGenerator(DefaultKeyAlphabet, DefaultKeyLength).Generate("", 0)
+	if err != nil {
+		panic(err)
+	}
+
+	return shortKey
+}
+
+// racyURLRepository хранилище, в котором ключ занимают между проверкой и сохранением
+type racyURLRepository struct {
+	MemoryURLRepository
+	collisions int
+}
+
+func (r *racyURLRepository) Save(ctx context.Context, events []*Event) error {
+	if r.collisions == 0 {
+		return r.MemoryURLRepository.Save(ctx, events)
+	}
+	r.collisions--
+
+	// Ключ первой ссылки заняли, остальные сохраняются
+	errs := []error{ErrShortKeyExist}
+	if len(events) > 1 {
+		err := r.MemoryURLRepository.Save(ctx, events[1:])
+		for i := range events[1:] {
+			errs = append(errs, SaveErrorAt(err, i))
+		}
+	}
+
+	return &SaveError{Errors: errs}
+}
+
+// unavailableURLRepository хранилище, которое всегда недоступно
+type unavailableURLRepository struct {
+	MemoryURLRepository
+}
+
+func (r *unavailableURLRepository) Get(_ context.Context, _ string) (Event, error) {
+	return Event{}, unavailable(errors.New("connection refused"))
+}
+
+func TestNewKeyGenerator(t *testing.T) {
+	tests := []struct {
+		name          string
+		configuration environments.Configuration
+		want          KeyGenerator
+		wantErr       bool
+	}{
+		{
+			name:          "positive test #1",
+			configuration: environments.Configuration{},
+			want:          &RandomKeyGenerator{},
+		},
+		{
+			name: "positive test #2",
+			configuration: environments.Configuration{
+				ShortKeyGenerator: KeyGeneratorSequential,
+				ShortKeyLength:    8,
+			},
+			want: &SequentialKeyGenerator{},
+		},
+		{
+			name: "positive test #3",
+			configuration: environments.Configuration{
+				ShortKeyGenerator: KeyGeneratorHash,
+				ShortKeyAlphabet:  "0123456789abcdef",
+			},
+			want: &HashKeyGenerator{},
+		},
+		{
+			name: "negative test #1",
+			configuration: environments.Configuration{
+				ShortKeyGenerator: "uuid",
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative test #2",
+			configuration: environments.Configuration{
+				ShortKeyLength: 2,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative test #3",
+			configuration: environments.Configuration{
+				ShortKeyAlphabet: "ab/",
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative test #4",
+			configuration: environments.Configuration{
+				ShortKeyAlphabet: "aab",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewKeyGenerator(tt.configuration, 0)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrKeyGeneratorConfig)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.IsType(t, tt.want, got)
+		})
+	}
+}
+
+func TestRandomKeyGenerator_Generate(t *testing.T) {
+	generator := NewRandomKeyGenerator("abc", 10)
+
+	keys := make(map[string]struct{})
+	for i := 0; i < 100; i++ {
+		key, err := generator.Generate("", 0)
+		require.NoError(t, err)
+		assert.Len(t, key, 10)
+		assert.Empty(t, strings.Trim(key, "abc"))
+
+		keys[key] = struct{}{}
+	}
+
+	assert.Greater(t, len(keys), 90)
+}
+
+func TestSequentialKeyGenerator_Generate(t *testing.T) {
+	generator := NewSequentialKeyGenerator(DefaultKeyAlphabet, 4, 60)
+
+	var keys []string
+	for i := 0; i < 3; i++ {
+		key, err := generator.Generate("", 0)
+		require.NoError(t, err)
+		keys = append(keys, key)
+	}
+
+	assert.Equal(t, []string{"aaa9", "aaba", "aabb"}, keys)
+}
+
+func TestSequentialKeyStart(t *testing.T) {
+	repository := &MemoryURLRepository{}
+	require.NoError(t, repository.Initialize(context.TODO(), environments.Configuration{}))
+	configuration := environments.Configuration{
+		ShortKeyGenerator: KeyGeneratorSequential,
+		ShortKeyLength:    4,
+	}
+
+	require.NoError(t, repository.Save(context.TODO(), []*Event{
+		{ShortKey: "aaab", OriginalURL: "https://example.com/1", UserID: "1"},
+		{ShortKey: "aaba", OriginalURL: "https://example.com/2", UserID: "1"},
+		{ShortKey: "aaac", OriginalURL: "https://example.com/3", UserID: "1"},
+		// Ключи другой длины и с символами не из алфавита генератор не выдает
+		{ShortKey: "custom", OriginalURL: "https://example.com/4", UserID: "1"},
+		{ShortKey: "zz-z", OriginalURL: "https://example.com/5", UserID: "1"},
+	}))
+	// Ключ удаленной ссылки тоже занят
+	require.NoError(t, repository.Delete(context.TODO(), []DeleteRequestBatch{
+		{ShortKeys: []string{"aaba"}, UserID: "1"},
+	}))
+
+	start, err := SequentialKeyStart(context.TODO(), configuration, repository)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(62), start)
+
+	generator, err := NewKeyGenerator(configuration, start)
+	require.NoError(t, err)
+	key, err := generator.Generate("", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "aabb", key)
+}
+
+func TestHashKeyGenerator_Generate(t *testing.T) {
+	generator := NewHashKeyGenerator(DefaultKeyAlphabet, 7)
+
+	first, err := generator.Generate("https://example.com", 0)
+	require.NoError(t, err)
+	assert.Len(t, first, 7)
+
+	same, err := generator.Generate("https://example.com", 0)
+	require.NoError(t, err)
+	assert.Equal(t, first, same)
+
+	retry, err := generator.Generate("https://example.com", 1)
+	require.NoError(t, err)
+	assert.NotEqual(t, first, retry)
+}
+
+func TestGenerateUniqueShortKey(t *testing.T) {
+	repository := &MemoryURLRepository{}
+	require.NoError(t, repository.Initialize(context.TODO(), environments.Configuration{}))
+
+	generator := NewHashKeyGenerator(DefaultKeyAlphabet, 6)
+	taken, err := generator.Generate("https://example.com", 0)
+	require.NoError(t, err)
+	expected, err := generator.Generate("https://example.com", 1)
+	require.NoError(t, err)
+
+	require.NoError(t, repository.Save(context.TODO(), []*Event{{
+		ShortKey:    taken,
+		OriginalURL: "https://other.example.com",
+	}}))
+
+	// Занятый ключ приводит к повторной генерации
+	shortKey, err := GenerateUniqueShortKey(context.TODO(), generator, repository, "https://example.com")
+	require.NoError(t, err)
+	assert.Equal(t, expected, shortKey)
+
+	// Попытки заканчиваются, если ключ всегда занят
+	_, err = GenerateUniqueShortKey(context.TODO(), constKeyGenerator(taken), repository, "https://example.com")
+	assert.ErrorIs(t, err, ErrShortKeyGeneration)
+
+	// Зарезервированные ключи не выдаются
+	_, err = GenerateUniqueShortKey(context.TODO(), constKeyGenerator("ping"), repository, "https://example.com")
+	assert.ErrorIs(t, err, ErrShortKeyGeneration)
+
+	// Недоступность хранилища не маскируется под занятый ключ
+	_, err = GenerateUniqueShortKey(context.TODO(), generator, &unavailableURLRepository{}, "https://example.com")
+	assert.ErrorIs(t, err, ErrUnavailable)
+}
+
+func TestSaveWithShortKey(t *testing.T) {
+	repository := &racyURLRepository{collisions: 2}
+	require.NoError(t, repository.Initialize(context.TODO(), environments.Configuration{}))
+	generator := NewSequentialKeyGenerator(DefaultKeyAlphabet, DefaultKeyLength, 0)
+
+	// Сгенерированный ключ заняли дважды, третий сохраняется
+	event := &Event{OriginalURL: "https://example.com"}
+	require.NoError(t, SaveWithShortKey(context.TODO(), generator, repository, "", event))
+	assert.Equal(t, "aaaaad", event.ShortKey)
+
+	saved, err := repository.Get(context.TODO(), event.ShortKey)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", saved.OriginalURL)
+
+	// Занятый пользовательский ключ не перегенерируется
+	repository.collisions = 1
+	err = SaveWithShortKey(context.TODO(), generator, repository, "spring-sale", &Event{OriginalURL: "https://example.com/sale"})
+	assert.ErrorIs(t, err, ErrShortKeyExist)
+
+	// Попытки заканчиваются, если ключ занимают каждый раз
+	repository.collisions = maxShortKeyAttempts
+	err = SaveWithShortKey(context.TODO(), generator, repository, "", &Event{OriginalURL: "https://example.com/other"})
+	assert.ErrorIs(t, err, ErrShortKeyGeneration)
+}
+
+func TestSaveBatchWithShortKeys(t *testing.T) {
+	repository := &racyURLRepository{collisions: 1}
+	require.NoError(t, repository.Initialize(context.TODO(), environments.Configuration{}))
+	require.NoError(t, repository.MemoryURLRepository.Save(context.TODO(), []*Event{
+		{ShortKey: "spring-sale", OriginalURL: "https://example.com/sale"},
+	}))
+	generator := NewSequentialKeyGenerator(DefaultKeyAlphabet, DefaultKeyLength, 0)
+
+	// Ключ первой ссылки заняли между проверкой и сохранением, сохраняется только она
+	events := []*Event{
+		{OriginalURL: "https://example.com/1"},
+		{OriginalURL: "https://example.com/2"},
+		{ShortKey: "spring-sale", OriginalURL: "https://example.com/3"},
+	}
+	err := SaveBatchWithShortKeys(context.TODO(), generator, repository, events)
+	require.NoError(t, SaveErrorAt(err, 0))
+	require.NoError(t, SaveErrorAt(err, 1))
+	assert.Equal(t, "aaaaad", events[0].ShortKey)
+	assert.Equal(t, "aaaaac", events[1].ShortKey)
+
+	// Занятый пользовательский ключ не перегенерируется
+	assert.ErrorIs(t, SaveErrorAt(err, 2), ErrShortKeyExist)
+
+	saved, err := repository.Get(context.TODO(), "aaaaad")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/1", saved.OriginalURL)
+
+	// Ключи внутри пакета не повторяются
+	events = []*Event{
+		{OriginalURL: "https://example.com/4"},
+		{OriginalURL: "https://example.com/5"},
+	}
+	err = SaveBatchWithShortKeys(context.TODO(), constKeyGenerator("const1"), repository, events)
+	require.NoError(t, SaveErrorAt(err, 0))
+	assert.ErrorIs(t, SaveErrorAt(err, 1), ErrShortKeyGeneration)
+
+	// Без ответа хранилища пакет не сохраняется
+	err = SaveBatchWithShortKeys(context.TODO(), generator, &unavailableURLRepository{}, []*Event{
+		{OriginalURL: "https://example.com/6"},
+	})
+	assert.ErrorIs(t, err, ErrUnavailable)
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// memoryShardCount на сколько частей делятся ссылки и индексы, чтобы запись не блокировала все хранилище
+const memoryShardCount = 64
+
+// memoryShard часть ссылок по хешу короткого ключа
+type memoryShard struct {
+	mutex sync.RWMutex
+	urls  map[string]Event
+}
+
+// memoryURLIndexShard часть индекса активных ссылок по исходному URL
+type memoryURLIndexShard struct {
+	mutex     sync.Mutex
+	shortKeys map[string]string
+}
+
+// memoryUserIndexShard часть индекса по пользователю
+type memoryUserIndexShard struct {
+	mutex sync.RWMutex
+	// активные ссылки пользователя
+	active map[string]map[string]struct{}
+	// сколько всего ссылок у пользователя, включая удаленные
+	total map[string]int
+}
+
+// add учитывает ссылку пользователя
+func (s *memoryUserIndexShard) add(event Event) {
+	s.total[event.UserID]++
+
+	if event.DeletedFlag {
+		return
+	}
+
+	keys, ok := s.active[event.UserID]
+	if !ok {
+		keys = make(map[string]struct{})
+		s.active[event.UserID] = keys
+	}
+	keys[event.ShortKey] = struct{}{}
+}
+
+// deactivate убирает удаленную ссылку из активных
+func (s *memoryUserIndexShard) deactivate(event Event) {
+	keys := s.active[event.UserID]
+	delete(keys, event.ShortKey)
+
+	if len(keys) == 0 {
+		delete(s.active, event.UserID)
+	}
+}
+
+// shardIndex номер части по ключу
+func shardIndex(key string) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+
+	return int(hash.Sum32() % memoryShardCount)
+}
@@ -2,25 +2,52 @@ package models
 
 import (
 	"context"
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/ShukinDmitriy/shortener/internal/environments"
+	"go.uber.org/zap"
 )
 
-// MemoryURLRepository repository for working with a memory
+// MemoryURLRepository repository for working with a memory.
+// Links are split into shards by short key, each with its own lock, and indexed
+// by original URL and by user. Locks are taken in the order: original URL index,
+// link shard, user index. Changes are appended to the file, compaction replaces
+// them with a snapshot of the links.
 type MemoryURLRepository struct {
-	DBConsumer *Consumer
-	DBProducer *Producer
-	urls       map[string]Event
+	DBConsumer    *Consumer
+	DBProducer    *Producer
+	ClickProducer *Producer
+
+	shards    [memoryShardCount]memoryShard
+	urlIndex  [memoryShardCount]memoryURLIndexShard
+	userIndex [memoryShardCount]memoryUserIndexShard
+	filename  string
+
+	// переходы по коротким ссылкам
+	clicks        map[string][]Click
+	clicksMutex   sync.RWMutex
+	clickFileName string
 }
 
 // Initialize repository
-func (r *MemoryURLRepository) Initialize(configuration environments.Configuration) error {
-	r.urls = make(map[string]Event)
+func (r *MemoryURLRepository) Initialize(ctx context.Context, configuration environments.Configuration) error {
+	for i := range r.shards {
+		r.shards[i].urls = make(map[string]Event)
+		r.urlIndex[i].shortKeys = make(map[string]string)
+		r.userIndex[i].active = make(map[string]map[string]struct{})
+		r.userIndex[i].total = make(map[string]int)
+	}
+	r.clicks = make(map[string][]Click)
 
 	filename := configuration.FileStoragePath
 	if filename == "" {
 		return nil
 	}
+	r.filename = filename
 
 	var err error
 
@@ -34,77 +61,327 @@ func (r *MemoryURLRepository) Initialize(configuration environments.Configuratio
 		return err
 	}
 
-	var event *Event
+	changes, err := r.restoreEvents()
+	if err != nil {
+		return err
+	}
+	r.buildIndexes()
+
+	// Вычитанный журнал сразу заменяем снимком
+	if changes > 0 {
+		if err = r.Compact(ctx); err != nil {
+			return err
+		}
+	}
+
+	if configuration.FileCompactInterval > 0 {
+		go r.compactPeriodically(ctx, configuration.FileCompactInterval)
+	}
+
+	// Файл переходов создается при первом переходе
+	r.clickFileName = clickFileName(filename)
+	if _, err = os.Stat(r.clickFileName); err != nil {
+		return nil
+	}
+
+	clickConsumer, err := NewConsumer(r.clickFileName)
+	if err != nil {
+		return err
+	}
+
+	return r.restoreClicks(clickConsumer)
+}
 
+// restoreEvents вычитывает снимок и журнал изменений после него, возвращает количество строк журнала.
+// Испорченные строки журнала, например оборванная при сбое последняя, пропускаются.
+func (r *MemoryURLRepository) restoreEvents() (int, error) {
 	defer r.DBConsumer.Close()
 
-	for {
-		event, err = r.DBConsumer.ReadEvent()
+	line, err := r.DBConsumer.ReadLine()
+	if line == nil || err != nil {
+		return 0, err
+	}
 
-		if event == nil || err != nil {
-			return err
+	if header, ok := readSnapshotHeader(line); ok {
+		events, err := readSnapshot(r.DBConsumer, header)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, event := range events {
+			r.shard(event.ShortKey).urls[event.ShortKey] = event
+		}
+
+		if line, err = r.DBConsumer.ReadLine(); err != nil {
+			return 0, err
+		}
+	}
+
+	changes, skipped := 0, 0
+	for ; line != nil; line, err = r.DBConsumer.ReadLine() {
+		changes++
+
+		var event Event
+		if err := json.Unmarshal(line, &event); err != nil || event.ShortKey == "" {
+			skipped++
+			continue
 		}
 
 		// Сохраняем значение в память, т.к. повторно файл не вычитывается
-		r.urls[event.ShortKey] = *event
+		r.shard(event.ShortKey).urls[event.ShortKey] = event
+	}
+	if err != nil {
+		return 0, err
 	}
-}
 
-// Get event by short key
-func (r *MemoryURLRepository) Get(shortKey string) (Event, bool) {
-	// Поиск в памяти
-	var event Event
-	found := false
+	if skipped > 0 {
+		zap.L().Warn("skipped corrupted lines of the storage file",
+			zap.String("file", r.filename),
+			zap.Int("count", skipped),
+		)
+	}
 
-	event, found = r.urls[shortKey]
+	return changes, nil
+}
 
-	return event, found
+// buildIndexes строит индексы по вычитанным из файла ссылкам
+func (r *MemoryURLRepository) buildIndexes() {
+	for i := range r.shards {
+		for shortKey, event := range r.shards[i].urls {
+			if !event.DeletedFlag {
+				r.urlIndexShard(event.OriginalURL).shortKeys[event.OriginalURL] = shortKey
+			}
+			r.userIndexShard(event.UserID).add(event)
+		}
+	}
 }
 
-// Save batch save events
-func (r *MemoryURLRepository) Save(_ context.Context, events []*Event) error {
-	for _, event := range events {
-		shortKey, found := r.GetShortKeyByOriginalURL(event.OriginalURL)
-		if found {
-			event.ShortKey = shortKey
-			continue
+// Compact replace the log of changes in the file with a snapshot of the current links
+func (r *MemoryURLRepository) Compact(_ context.Context) error {
+	if r.filename == "" {
+		return nil
+	}
+
+	// Блокируем все части, чтобы снимок и журнал не разошлись
+	for i := range r.shards {
+		r.shards[i].mutex.Lock()
+		defer r.shards[i].mutex.Unlock()
+	}
+
+	var events []Event
+	for i := range r.shards {
+		for _, event := range r.shards[i].urls {
+			events = append(events, event)
 		}
+	}
 
-		// Хранение в памяти
-		r.urls[event.ShortKey] = *event
+	// Порядок не важен для чтения, но делает снимок воспроизводимым
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].ShortKey < events[j].ShortKey
+	})
+
+	if err := writeSnapshot(r.filename, events); err != nil {
+		return err
+	}
+
+	// Старый дескриптор указывает на замененный файл
+	if err := r.DBProducer.Close(); err != nil {
+		return err
+	}
+
+	var err error
+	r.DBProducer, err = NewProducer(r.filename)
+
+	return err
+}
 
-		if r.DBProducer == nil {
+// compactPeriodically сжимает файл, пока не отменен контекст инициализации
+func (r *MemoryURLRepository) compactPeriodically(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Compact(ctx); err != nil {
+				zap.L().Error("cannot compact storage file", zap.String("file", r.filename), zap.Error(err))
+			}
+		}
+	}
+}
+
+// restoreClicks вычитывает переходы из файла.
+// Испорченные строки, например оборванная при сбое последняя, пропускаются, как и в журнале ссылок.
+func (r *MemoryURLRepository) restoreClicks(consumer *Consumer) error {
+	defer consumer.Close()
+
+	skipped := 0
+	line, err := consumer.ReadLine()
+	for ; line != nil; line, err = consumer.ReadLine() {
+		var click Click
+		if err := json.Unmarshal(line, &click); err != nil || click.ShortKey == "" {
+			skipped++
 			continue
 		}
 
-		// Хранение в файле
-		r.DBProducer.WriteEvent(event)
+		r.clicks[click.ShortKey] = append(r.clicks[click.ShortKey], click)
+	}
+	if err != nil {
+		return err
+	}
+
+	if skipped > 0 {
+		zap.L().Warn("skipped corrupted lines of the clicks file",
+			zap.String("file", r.clickFileName),
+			zap.Int("count", skipped),
+		)
 	}
 
 	return nil
 }
 
-// Delete batch delete event
-func (r *MemoryURLRepository) Delete(_ context.Context, events []DeleteRequestBatch) error {
-	for _, deleteEvent := range events {
-		for _, shortKey := range deleteEvent.ShortKeys {
-			event := r.urls[shortKey]
+// shard часть ссылок с коротким ключом
+func (r *MemoryURLRepository) shard(shortKey string) *memoryShard {
+	return &r.shards[shardIndex(shortKey)]
+}
 
-			if event.DeletedFlag || event.UserID != deleteEvent.UserID {
-				continue
-			}
+// urlIndexShard часть индекса с исходным URL
+func (r *MemoryURLRepository) urlIndexShard(originalURL string) *memoryURLIndexShard {
+	return &r.urlIndex[shardIndex(originalURL)]
+}
 
-			event.DeletedFlag = true
+// userIndexShard часть индекса с пользователем
+func (r *MemoryURLRepository) userIndexShard(userID string) *memoryUserIndexShard {
+	return &r.userIndex[shardIndex(userID)]
+}
 
-			// Хранение в памяти
-			r.urls[event.ShortKey] = event
+// writeEvent дописывает изменение в файл, вызывается под блокировкой части ссылок
+func (r *MemoryURLRepository) writeEvent(event *Event) error {
+	if r.DBProducer == nil {
+		return nil
+	}
 
-			if r.DBProducer == nil {
-				continue
-			}
+	return r.DBProducer.WriteEvent(event)
+}
+
+// Get event by short key
+func (r *MemoryURLRepository) Get(_ context.Context, shortKey string) (Event, error) {
+	shard := r.shard(shortKey)
+	shard.mutex.RLock()
+	defer shard.mutex.RUnlock()
+
+	// Поиск в памяти
+	event, found := shard.urls[shortKey]
+	if !found {
+		return Event{}, ErrNotFound
+	}
+
+	return event, eventError(event)
+}
+
+// Save batch save events, the outcome of every event is reported with *SaveError
+func (r *MemoryURLRepository) Save(_ context.Context, events []*Event) error {
+	errs := make([]error, len(events))
+
+	for i, event := range events {
+		errs[i] = r.save(event)
+	}
 
-			// Хранение в файле
-			r.DBProducer.WriteEvent(&event)
+	return saveErrors(errs)
+}
+
+// save проверка исходного URL и запись ссылки выполняются под блокировкой индекса этого URL
+func (r *MemoryURLRepository) save(event *Event) error {
+	urlIndex := r.urlIndexShard(event.OriginalURL)
+	urlIndex.mutex.Lock()
+	defer urlIndex.mutex.Unlock()
+
+	if shortKey, ok := urlIndex.shortKeys[event.OriginalURL]; ok {
+		event.ShortKey = shortKey
+		return ErrURLExist
+	}
+
+	shard := r.shard(event.ShortKey)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	// Короткий ключ не переиспользуется, даже если ссылка удалена или истекла:
+	// опубликованная ссылка не должна начать вести на другой адрес
+	if _, exist := shard.urls[event.ShortKey]; exist {
+		return ErrShortKeyExist
+	}
+
+	// Хранение в памяти
+	shard.urls[event.ShortKey] = *event
+	urlIndex.shortKeys[event.OriginalURL] = event.ShortKey
+
+	r.updateUserIndex(*event, (*memoryUserIndexShard).add)
+
+	// Хранение в файле
+	r.writeEvent(event)
+
+	return nil
+}
+
+// updateUserIndex изменяет индекс пользователя ссылки под его блокировкой
+func (r *MemoryURLRepository) updateUserIndex(event Event, update func(s *memoryUserIndexShard, event Event)) {
+	userIndex := r.userIndexShard(event.UserID)
+	userIndex.mutex.Lock()
+	defer userIndex.mutex.Unlock()
+
+	update(userIndex, event)
+}
+
+// markDeleted помечает ссылку удаленной, если она подходит под условие.
+// Исходный URL ссылки читается заранее, чтобы взять блокировки в общем порядке,
+// и под ними условие проверяется повторно.
+func (r *MemoryURLRepository) markDeleted(shortKey string, match func(event Event) bool) bool {
+	shard := r.shard(shortKey)
+
+	shard.mutex.RLock()
+	event, ok := shard.urls[shortKey]
+	shard.mutex.RUnlock()
+
+	if !ok || event.DeletedFlag || !match(event) {
+		return false
+	}
+
+	urlIndex := r.urlIndexShard(event.OriginalURL)
+	urlIndex.mutex.Lock()
+	defer urlIndex.mutex.Unlock()
+
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	event, ok = shard.urls[shortKey]
+	if !ok || event.DeletedFlag || !match(event) {
+		return false
+	}
+
+	event.DeletedFlag = true
+
+	// Хранение в памяти
+	shard.urls[shortKey] = event
+	if urlIndex.shortKeys[event.OriginalURL] == shortKey {
+		delete(urlIndex.shortKeys, event.OriginalURL)
+	}
+	r.updateUserIndex(event, (*memoryUserIndexShard).deactivate)
+
+	// Хранение в файле
+	r.writeEvent(&event)
+
+	return true
+}
+
+// Delete batch delete event
+func (r *MemoryURLRepository) Delete(_ context.Context, events []DeleteRequestBatch) error {
+	for _, deleteEvent := range events {
+		for _, shortKey := range deleteEvent.ShortKeys {
+			r.markDeleted(shortKey, func(event Event) bool {
+				return event.UserID == deleteEvent.UserID
+			})
 		}
 	}
 
@@ -112,38 +389,180 @@ func (r *MemoryURLRepository) Delete(_ context.Context, events []DeleteRequestBa
 }
 
 // GetShortKeyByOriginalURL get short link from full link
-func (r *MemoryURLRepository) GetShortKeyByOriginalURL(originalURL string) (string, bool) {
-	for _, event := range r.urls {
-		if event.OriginalURL == originalURL && !event.DeletedFlag {
-			return event.ShortKey, true
-		}
+func (r *MemoryURLRepository) GetShortKeyByOriginalURL(_ context.Context, originalURL string) (string, error) {
+	urlIndex := r.urlIndexShard(originalURL)
+	urlIndex.mutex.Lock()
+	defer urlIndex.mutex.Unlock()
+
+	shortKey, ok := urlIndex.shortKeys[originalURL]
+	if !ok {
+		return "", ErrNotFound
 	}
 
-	return "", false
+	return shortKey, nil
 }
 
 // GetEventsByUserID get events by user ID
-func (r *MemoryURLRepository) GetEventsByUserID(_ context.Context, userID string) []*Event {
+func (r *MemoryURLRepository) GetEventsByUserID(_ context.Context, userID string) ([]*Event, error) {
 	var events []*Event
-	for _, event := range r.urls {
-		if event.UserID == userID && !event.DeletedFlag {
-			events = append(events, &event)
+	for _, event := range r.userSnapshot(userID) {
+		events = append(events, &event)
+	}
+
+	return events, nil
+}
+
+// userSnapshot копирует активные ссылки пользователя по индексу
+func (r *MemoryURLRepository) userSnapshot(userID string) []Event {
+	userIndex := r.userIndexShard(userID)
+	userIndex.mutex.RLock()
+	shortKeys := make([]string, 0, len(userIndex.active[userID]))
+	for shortKey := range userIndex.active[userID] {
+		shortKeys = append(shortKeys, shortKey)
+	}
+	userIndex.mutex.RUnlock()
+
+	events := make([]Event, 0, len(shortKeys))
+	for _, shortKey := range shortKeys {
+		shard := r.shard(shortKey)
+		shard.mutex.RLock()
+		event, ok := shard.urls[shortKey]
+		shard.mutex.RUnlock()
+
+		// Ссылку могли удалить после чтения индекса
+		if ok && !event.DeletedFlag && event.UserID == userID {
+			events = append(events, event)
 		}
 	}
 
 	return events
 }
 
+// IterateEventsByUserID pass active links of the user to fn from a snapshot of the index
+func (r *MemoryURLRepository) IterateEventsByUserID(ctx context.Context, userID string, fn EventIterator) error {
+	return iterateSnapshot(ctx, r.userSnapshot(userID), fn)
+}
+
+// IterateEvents pass active links of all users to fn from a snapshot of every shard
+func (r *MemoryURLRepository) IterateEvents(ctx context.Context, fn EventIterator) error {
+	var events []Event
+	for i := range r.shards {
+		shard := &r.shards[i]
+		shard.mutex.RLock()
+		for _, event := range shard.urls {
+			if !event.DeletedFlag {
+				events = append(events, event)
+			}
+		}
+		shard.mutex.RUnlock()
+	}
+
+	return iterateSnapshot(ctx, events, fn)
+}
+
+// IterateShortKeys pass short keys of all links, deleted and expired ones included
+func (r *MemoryURLRepository) IterateShortKeys(ctx context.Context, fn ShortKeyIterator) error {
+	var shortKeys []string
+	for i := range r.shards {
+		shard := &r.shards[i]
+		shard.mutex.RLock()
+		for shortKey := range shard.urls {
+			shortKeys = append(shortKeys, shortKey)
+		}
+		shard.mutex.RUnlock()
+	}
+
+	for _, shortKey := range shortKeys {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := fn(shortKey); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // GetStats get repository's stats
 func (r *MemoryURLRepository) GetStats(_ context.Context) (countUser int, countURL int, err error) {
-	users := make(map[string]interface{})
+	for i := range r.shards {
+		r.shards[i].mutex.RLock()
+		countURL += len(r.shards[i].urls)
+		r.shards[i].mutex.RUnlock()
+
+		r.userIndex[i].mutex.RLock()
+		countUser += len(r.userIndex[i].total)
+		r.userIndex[i].mutex.RUnlock()
+	}
+
+	return countUser, countURL, err
+}
 
-	for _, event := range r.urls {
-		if _, ok := users[event.UserID]; !ok {
-			countUser++
+// DeleteExpired soft delete links which expired at the moment
+func (r *MemoryURLRepository) DeleteExpired(_ context.Context, now time.Time) (int, error) {
+	count := 0
+
+	for i := range r.shards {
+		shard := &r.shards[i]
+
+		var expired []string
+		shard.mutex.RLock()
+		for shortKey, event := range shard.urls {
+			if !event.DeletedFlag && event.IsExpired(now) {
+				expired = append(expired, shortKey)
+			}
+		}
+		shard.mutex.RUnlock()
+
+		for _, shortKey := range expired {
+			if r.markDeleted(shortKey, func(event Event) bool {
+				return event.IsExpired(now)
+			}) {
+				count++
+			}
 		}
 	}
-	countURL = len(r.urls)
 
-	return countUser, countURL, err
+	return count, nil
+}
+
+// SaveClicks batch save clicks
+func (r *MemoryURLRepository) SaveClicks(_ context.Context, clicks []*Click) error {
+	r.clicksMutex.Lock()
+	defer r.clicksMutex.Unlock()
+
+	if r.ClickProducer == nil && r.clickFileName != "" {
+		var err error
+
+		r.ClickProducer, err = NewProducer(r.clickFileName)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, click := range clicks {
+		// Хранение в памяти
+		r.clicks[click.ShortKey] = append(r.clicks[click.ShortKey], *click)
+
+		if r.ClickProducer == nil {
+			continue
+		}
+
+		// Хранение в файле
+		if err := r.ClickProducer.WriteEvent(click); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetClickStats get short link's clicks statistic
+func (r *MemoryURLRepository) GetClickStats(_ context.Context, shortKey string) (*ClickStats, error) {
+	r.clicksMutex.RLock()
+	defer r.clicksMutex.RUnlock()
+
+	return NewClickStats(r.clicks[shortKey]), nil
 }
@@ -5,10 +5,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/ShukinDmitriy/shortener/internal/environments"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/ShukinDmitriy/shortener/internal/models"
 )
@@ -19,7 +25,7 @@ func BenchmarkMemoryURLRepository_Initialize(b *testing.B) {
 
 	b.Run("initialize", func(b *testing.B) {
 		for i := 0; i < b.N; i++ {
-			_ = repository.Initialize(configuration)
+			_ = repository.Initialize(context.TODO(), configuration)
 		}
 	})
 }
@@ -48,7 +54,7 @@ func TestMemoryURLRepository_Initialize(t *testing.T) {
 			}
 
 			repository := &models.MemoryURLRepository{}
-			assert.NoError(t, repository.Initialize(configuration))
+			assert.NoError(t, repository.Initialize(context.TODO(), configuration))
 			if tt.args.filename != "" {
 				assert.FileExists(t, tt.args.filename)
 				_ = os.Remove(tt.args.filename)
@@ -85,7 +91,7 @@ func TestMemoryURLRepository_CRUD(t *testing.T) {
 					},
 					{
 						OriginalURL: "https://example.com",
-						ShortKey:    "short1",
+						ShortKey:    "short3",
 						UserID:      "2",
 					},
 					{
@@ -95,9 +101,11 @@ func TestMemoryURLRepository_CRUD(t *testing.T) {
 					},
 				},
 			},
+			// Пользователи считаются без повторов, как в остальных хранилищах:
+			// "0" из файла, "1", "2" сократил тот же URL после удаления и "3"
 			want: want{
 				countUsers: 4,
-				countURLs:  4,
+				countURLs:  5,
 			},
 		},
 	}
@@ -124,8 +132,10 @@ func TestMemoryURLRepository_CRUD(t *testing.T) {
 			}
 
 			repository := &models.MemoryURLRepository{}
-			assert.NoError(t, repository.Initialize(configuration))
-			assert.Equal(t, tt.args.length, len(repository.GetEventsByUserID(context.TODO(), "0")))
+			assert.NoError(t, repository.Initialize(context.TODO(), configuration))
+			userEvents, err := repository.GetEventsByUserID(context.TODO(), "0")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.args.length, len(userEvents))
 
 			for _, event := range tt.args.events {
 				assert.NoError(t, repository.Save(context.TODO(), []*models.Event{
@@ -138,11 +148,12 @@ func TestMemoryURLRepository_CRUD(t *testing.T) {
 					},
 				}))
 
-				getEvent, found := repository.Get(event.ShortKey)
-				assert.True(t, found)
+				getEvent, err := repository.Get(context.TODO(), event.ShortKey)
+				assert.NoError(t, err)
 				assert.Equal(t, event.OriginalURL, getEvent.OriginalURL)
 
-				userEvents := repository.GetEventsByUserID(context.TODO(), event.UserID)
+				userEvents, err := repository.GetEventsByUserID(context.TODO(), event.UserID)
+				assert.NoError(t, err)
 				assert.Len(t, userEvents, 1)
 
 				assert.NoError(t, repository.Delete(context.TODO(), []models.DeleteRequestBatch{
@@ -152,8 +163,8 @@ func TestMemoryURLRepository_CRUD(t *testing.T) {
 					},
 				}))
 
-				getEvent, found = repository.Get(event.ShortKey)
-				assert.True(t, found)
+				getEvent, err = repository.Get(context.TODO(), event.ShortKey)
+				assert.ErrorIs(t, err, models.ErrDeleted)
 				assert.True(t, getEvent.DeletedFlag)
 			}
 
@@ -164,3 +175,432 @@ func TestMemoryURLRepository_CRUD(t *testing.T) {
 		})
 	}
 }
+
+func TestMemoryURLRepository_DeleteExpired(t *testing.T) {
+	filename := "./expired-events.json"
+	defer os.Remove(filename)
+
+	repository := &models.MemoryURLRepository{}
+	assert.NoError(t, repository.Initialize(context.TODO(), environments.Configuration{
+		FileStoragePath: filename,
+	}))
+
+	now := time.Now()
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Minute)
+
+	assert.NoError(t, repository.Save(context.TODO(), []*models.Event{
+		{
+			ShortKey:    "expired",
+			OriginalURL: "https://expired.example.com",
+			UserID:      "1",
+			ExpiresAt:   &past,
+		},
+		{
+			ShortKey:    "actual",
+			OriginalURL: "https://actual.example.com",
+			UserID:      "1",
+			ExpiresAt:   &future,
+		},
+		{
+			ShortKey:    "forever",
+			OriginalURL: "https://forever.example.com",
+			UserID:      "1",
+		},
+	}))
+
+	count, err := repository.DeleteExpired(context.TODO(), now)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	event, err := repository.Get(context.TODO(), "expired")
+	assert.ErrorIs(t, err, models.ErrDeleted)
+	assert.True(t, event.DeletedFlag)
+
+	event, err = repository.Get(context.TODO(), "actual")
+	assert.NoError(t, err)
+	assert.False(t, event.DeletedFlag)
+
+	// Повторная очистка ничего не находит
+	count, err = repository.DeleteExpired(context.TODO(), now)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	// Ключ истекшей ссылки не занимается заново
+	err = repository.Save(context.TODO(), []*models.Event{{
+		ShortKey:    "expired",
+		OriginalURL: "https://hijack.example.com",
+		UserID:      "2",
+	}})
+	assert.ErrorIs(t, err, models.ErrShortKeyExist)
+	event, err = repository.Get(context.TODO(), "expired")
+	assert.ErrorIs(t, err, models.ErrDeleted)
+	assert.Equal(t, "https://expired.example.com", event.OriginalURL)
+
+	// Удаление сохраняется в файле
+	restored := &models.MemoryURLRepository{}
+	assert.NoError(t, restored.Initialize(context.TODO(), environments.Configuration{
+		FileStoragePath: filename,
+	}))
+	event, err = restored.Get(context.TODO(), "expired")
+	assert.ErrorIs(t, err, models.ErrDeleted)
+	assert.True(t, event.DeletedFlag)
+}
+
+func TestMemoryURLRepository_DeleteExpiredConcurrent(t *testing.T) {
+	repository := &models.MemoryURLRepository{}
+	assert.NoError(t, repository.Initialize(context.TODO(), environments.Configuration{}))
+
+	past := time.Now().Add(-time.Minute)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+
+		// Обработчики сохраняют ссылки, пока reaper их удаляет
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				key := fmt.Sprintf("key-%d-%d", i, j)
+				assert.NoError(t, repository.Save(context.TODO(), []*models.Event{{
+					ShortKey:    key,
+					OriginalURL: "https://" + key + ".example.com",
+					UserID:      "1",
+					ExpiresAt:   &past,
+				}}))
+				_, _ = repository.Get(context.TODO(), key)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_, err := repository.DeleteExpired(context.TODO(), time.Now())
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	_, err := repository.DeleteExpired(context.TODO(), time.Now())
+	assert.NoError(t, err)
+	events, err := repository.GetEventsByUserID(context.TODO(), "1")
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestMemoryURLRepository_Clicks(t *testing.T) {
+	filename := "./clicks-events.json"
+	defer os.Remove(filename)
+	defer os.Remove("./clicks-events-clicks.json")
+
+	repository := &models.MemoryURLRepository{}
+	assert.NoError(t, repository.Initialize(context.TODO(), environments.Configuration{
+		FileStoragePath: filename,
+	}))
+
+	now := time.Now()
+	assert.NoError(t, repository.SaveClicks(context.TODO(), []*models.Click{
+		models.NewClick("short1", now, "https://google.com", "agent", "10.0.0.1"),
+		models.NewClick("short1", now, "", "agent", "10.0.0.2"),
+		models.NewClick("short2", now, "", "agent", "10.0.0.3"),
+	}))
+
+	stats, err := repository.GetClickStats(context.TODO(), "short1")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, stats.Total)
+
+	// Испорченная строка и оборванная при сбое последняя пропускаются
+	file, err := os.OpenFile("./clicks-events-clicks.json", os.O_APPEND|os.O_WRONLY, 0666)
+	require.NoError(t, err)
+	_, err = file.WriteString("not a json\n{\"short_key\":\"short1\",\"ref")
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	// Переходы восстанавливаются из файла
+	restored := &models.MemoryURLRepository{}
+	assert.NoError(t, restored.Initialize(context.TODO(), environments.Configuration{
+		FileStoragePath: filename,
+	}))
+
+	stats, err = restored.GetClickStats(context.TODO(), "short1")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, stats.Total)
+	assert.Len(t, stats.ByReferrer, 2)
+
+	stats, err = restored.GetClickStats(context.TODO(), "unknown")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, stats.Total)
+}
+
+func TestMemoryURLRepository_Compact(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "events.json")
+	configuration := environments.Configuration{FileStoragePath: filename}
+
+	repository := &models.MemoryURLRepository{}
+	require.NoError(t, repository.Initialize(context.TODO(), configuration))
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, repository.Save(context.TODO(), []*models.Event{
+			{
+				ShortKey:    fmt.Sprintf("short%d", i),
+				OriginalURL: fmt.Sprintf("https://example%d.com", i),
+				UserID:      "1",
+			},
+		}))
+	}
+	require.NoError(t, repository.Delete(context.TODO(), []models.DeleteRequestBatch{
+		{
+			ShortKeys: []string{"short0"},
+			UserID:    "1",
+		},
+	}))
+
+	// Снимок заменяет четыре строки журнала тремя ссылками после заголовка
+	require.NoError(t, repository.Compact(context.TODO()))
+	lines := readLines(t, filename)
+	require.Len(t, lines, 4)
+	assert.Contains(t, lines[0], `"version":1`)
+
+	// Изменения после снимка дописываются в журнал
+	require.NoError(t, repository.Save(context.TODO(), []*models.Event{
+		{
+			ShortKey:    "short3",
+			OriginalURL: "https://example3.com",
+			UserID:      "1",
+		},
+	}))
+	assert.Len(t, readLines(t, filename), 5)
+
+	// Оборванная при сбое последняя строка не мешает запуску
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, 0o666)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"short_key":"short4","original_`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	restored := &models.MemoryURLRepository{}
+	require.NoError(t, restored.Initialize(context.TODO(), configuration))
+
+	_, err = restored.Get(context.TODO(), "short0")
+	assert.ErrorIs(t, err, models.ErrDeleted)
+	for _, shortKey := range []string{"short1", "short2", "short3"} {
+		_, err = restored.Get(context.TODO(), shortKey)
+		assert.NoError(t, err, shortKey)
+	}
+	_, err = restored.Get(context.TODO(), "short4")
+	assert.ErrorIs(t, err, models.ErrNotFound)
+
+	// При запуске журнал сразу заменяется снимком
+	lines = readLines(t, filename)
+	assert.Len(t, lines, 5)
+
+	// Испорченный снимок не принимается
+	lines[2] = strings.Replace(lines[2], "example", "exampl3", 1)
+	require.NoError(t, os.WriteFile(filename, []byte(strings.Join(lines, "\n")+"\n"), 0o644))
+
+	corrupted := &models.MemoryURLRepository{}
+	assert.ErrorIs(t, corrupted.Initialize(context.TODO(), configuration), models.ErrSnapshotCorrupted)
+}
+
+func TestAsCompactor(t *testing.T) {
+	repository := &models.MemoryURLRepository{}
+
+	tests := []struct {
+		name       string
+		repository models.URLRepository
+		want       bool
+	}{
+		{
+			name:       "positive test #1",
+			repository: repository,
+			want:       true,
+		},
+		{
+			name: "positive test #2",
+			repository: models.NewCachedURLRepository(
+				models.NewInstrumentedURLRepository(repository),
+				10,
+				time.Minute,
+			),
+			want: true,
+		},
+		{
+			name:       "negative test #1",
+			repository: models.NewInstrumentedURLRepository(&models.SQLiteURLRepository{}),
+			want:       false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compactor, ok := models.AsCompactor(tt.repository)
+			assert.Equal(t, tt.want, ok)
+			if tt.want {
+				assert.Same(t, repository, compactor)
+			}
+		})
+	}
+}
+
+// readLines строки файла без переноса в конце
+func readLines(t *testing.T, filename string) []string {
+	data, err := os.ReadFile(filename)
+	require.NoError(t, err)
+
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func TestMemoryURLRepository_Concurrent(t *testing.T) {
+	const (
+		workers = 8
+		count   = 200
+	)
+
+	filename := filepath.Join(t.TempDir(), "events.json")
+	configuration := environments.Configuration{FileStoragePath: filename}
+
+	repository := &models.MemoryURLRepository{}
+	require.NoError(t, repository.Initialize(context.TODO(), configuration))
+
+	var created atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+
+			userID := fmt.Sprintf("user%d", w)
+			for i := 0; i < count; i++ {
+				shortKey := fmt.Sprintf("%s-%d", userID, i)
+				assert.NoError(t, repository.Save(context.TODO(), []*models.Event{
+					{
+						ShortKey:    shortKey,
+						OriginalURL: "https://example.com/" + shortKey,
+						UserID:      userID,
+					},
+				}))
+
+				// Один и тот же URL сокращается только один раз
+				if repository.Save(context.TODO(), []*models.Event{
+					{
+						ShortKey:    shortKey + "-shared",
+						OriginalURL: "https://example.com/shared",
+						UserID:      userID,
+					},
+				}) == nil {
+					created.Add(1)
+				}
+
+				_, err := repository.Get(context.TODO(), shortKey)
+				assert.NoError(t, err)
+				_, err = repository.GetShortKeyByOriginalURL(context.TODO(), "https://example.com/"+shortKey)
+				assert.NoError(t, err)
+				_, err = repository.GetEventsByUserID(context.TODO(), userID)
+				assert.NoError(t, err)
+
+				if i%2 == 0 {
+					assert.NoError(t, repository.Delete(context.TODO(), []models.DeleteRequestBatch{
+						{
+							ShortKeys: []string{shortKey},
+							UserID:    userID,
+						},
+					}))
+				}
+			}
+		}(w)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < 10; i++ {
+			assert.NoError(t, repository.Compact(context.TODO()))
+			_, _, err := repository.GetStats(context.TODO())
+			assert.NoError(t, err)
+		}
+	}()
+
+	wg.Wait()
+
+	assert.Equal(t, int64(1), created.Load())
+	for w := 0; w < workers; w++ {
+		events, err := repository.GetEventsByUserID(context.TODO(), fmt.Sprintf("user%d", w))
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, len(events), count/2)
+	}
+
+	// Файл после сжатий содержит то же, что и память
+	restored := &models.MemoryURLRepository{}
+	require.NoError(t, restored.Initialize(context.TODO(), configuration))
+
+	countUser, countURL, err := repository.GetStats(context.TODO())
+	require.NoError(t, err)
+	restoredUser, restoredURL, err := restored.GetStats(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, countUser, restoredUser)
+	assert.Equal(t, countURL, restoredURL)
+}
+
+// newMemoryRepositoryWithLinks хранилище с count ссылками, по 100 у каждого пользователя
+func newMemoryRepositoryWithLinks(b *testing.B, count int) *models.MemoryURLRepository {
+	repository := &models.MemoryURLRepository{}
+	require.NoError(b, repository.Initialize(context.TODO(), environments.Configuration{}))
+
+	events := make([]*models.Event, 0, 1000)
+	for i := 0; i < count; i++ {
+		events = append(events, &models.Event{
+			ShortKey:    fmt.Sprintf("short%d", i),
+			OriginalURL: fmt.Sprintf("https://example.com/%d", i),
+			UserID:      fmt.Sprintf("user%d", i/100),
+		})
+
+		if len(events) == cap(events) || i == count-1 {
+			require.NoError(b, repository.Save(context.TODO(), events))
+			events = events[:0]
+		}
+	}
+
+	return repository
+}
+
+// BenchmarkMemoryURLRepository_Lookups время поиска не должно расти вместе с количеством ссылок
+func BenchmarkMemoryURLRepository_Lookups(b *testing.B) {
+	for _, count := range []int{1_000, 1_000_000} {
+		repository := newMemoryRepositoryWithLinks(b, count)
+
+		b.Run(fmt.Sprintf("GetShortKeyByOriginalURL/%d", count), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, err := repository.GetShortKeyByOriginalURL(context.TODO(), fmt.Sprintf("https://example.com/%d", i%count))
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("GetEventsByUserID/%d", count), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				events, err := repository.GetEventsByUserID(context.TODO(), fmt.Sprintf("user%d", i%(count/100)))
+				if err != nil || len(events) != 100 {
+					b.Fatal(len(events), err)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("GetStats/%d", count), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, _, err := repository.GetStats(context.TODO()); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("Get/%d", count), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := repository.Get(context.TODO(), fmt.Sprintf("short%d", i%count)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ShukinDmitriy/shortener/internal/environments"
+)
+
+// MemoryUserRepository accounts kept in memory, they are lost on restart
+type MemoryUserRepository struct {
+	mutex   sync.RWMutex
+	byID    map[string]User
+	byEmail map[string]string
+}
+
+// NewMemoryUserRepository initialized repository's constructor
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{
+		byID:    make(map[string]User),
+		byEmail: make(map[string]string),
+	}
+}
+
+// Initialize repository
+func (r *MemoryUserRepository) Initialize(_ context.Context, _ environments.Configuration) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.byID = make(map[string]User)
+	r.byEmail = make(map[string]string)
+
+	return nil
+}
+
+// Create save the account
+func (r *MemoryUserRepository) Create(_ context.Context, user *User) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.byEmail[user.Email]; ok {
+		return ErrUserExist
+	}
+	if _, ok := r.byID[user.ID]; ok {
+		return ErrUserExist
+	}
+
+	r.byID[user.ID] = *user
+	r.byEmail[user.Email] = user.ID
+
+	return nil
+}
+
+// GetByEmail get the account by email
+func (r *MemoryUserRepository) GetByEmail(ctx context.Context, email string) (User, error) {
+	r.mutex.RLock()
+	id, ok := r.byEmail[email]
+	r.mutex.RUnlock()
+
+	if !ok {
+		return User{}, ErrNotFound
+	}
+
+	return r.GetByID(ctx, id)
+}
+
+// GetByID get the account by id
+func (r *MemoryUserRepository) GetByID(_ context.Context, id string) (User, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	user, ok := r.byID[id]
+	if !ok {
+		return User{}, ErrNotFound
+	}
+
+	return user, nil
+}
@@ -4,17 +4,31 @@ package models
 import (
 	"bufio"
 	"os"
+	"sync"
+	"time"
 )
 
 // CreateRequest request to link creation
 type CreateRequest struct {
-	URL string `json:"url"`
+	URL       string     `json:"url"`
+	Alias     string     `json:"alias,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	TTL       int64      `json:"ttl,omitempty"`
+}
+
+// AuthRequest request to registration and login
+type AuthRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
 }
 
 // CreateRequestBatch request to link creation
 type CreateRequestBatch struct {
-	CorrelationID string `json:"correlation_id"`
-	OriginalURL   string `json:"original_url"`
+	CorrelationID string     `json:"correlation_id"`
+	OriginalURL   string     `json:"original_url"`
+	Alias         string     `json:"alias,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	TTL           int64      `json:"ttl,omitempty"`
 }
 
 // DeleteRequestBatch request to link delete
@@ -28,10 +42,33 @@ type CreateResponse struct {
 	Result string `json:"result"`
 }
 
-// CreateResponseBatch response to link creation
+// CreateResponseBatch response to link creation, status is one of the link statuses
 type CreateResponseBatch struct {
 	CorrelationID string `json:"correlation_id"`
-	ShortURL      string `json:"short_url"`
+	ShortURL      string `json:"short_url,omitempty"`
+	Status        string `json:"status"`
+	Error         string `json:"error,omitempty"`
+}
+
+// Statuses of a link in batch create and import responses
+const (
+	// LinkStatusCreated new short link was created
+	LinkStatusCreated = "created"
+	// LinkStatusExisting original URL was already shortened, the existing link is returned
+	LinkStatusExisting = "existing"
+	// LinkStatusInvalid item was rejected, the reason is in the error field
+	LinkStatusInvalid = "invalid"
+	// ImportStatusAborted import was stopped, rows without a result weren't imported
+	ImportStatusAborted = "aborted"
+)
+
+// ImportResponse result of importing one row
+type ImportResponse struct {
+	Line          int    `json:"line"`
+	CorrelationID string `json:"correlation_id"`
+	ShortURL      string `json:"short_url,omitempty"`
+	Status        string `json:"status"`
+	Error         string `json:"error,omitempty"`
 }
 
 // GetUserURLsResponse response to receiving user links
@@ -40,6 +77,13 @@ type GetUserURLsResponse struct {
 	OriginalURL string `json:"original_url"`
 }
 
+// GetURLStatsResponse response to receiving short link's clicks statistic
+type GetURLStatsResponse struct {
+	ShortURL    string `json:"short_url"`
+	OriginalURL string `json:"original_url"`
+	*ClickStats
+}
+
 // Consumer for read file
 type Consumer struct {
 	file *os.File
@@ -47,9 +91,11 @@ type Consumer struct {
 	scanner *bufio.Scanner
 }
 
-// Producer for write file
+// Producer for write file, safe for concurrent use
 type Producer struct {
 	file *os.File
 	// добавляем Writer в Producer
 	writer *bufio.Writer
+	// строки разных записей не должны перемешиваться
+	mutex sync.Mutex
 }
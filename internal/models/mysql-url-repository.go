@@ -0,0 +1,445 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ShukinDmitriy/shortener/internal/environments"
+	"github.com/go-sql-driver/mysql"
+	"github.com/golang-migrate/migrate/v4"
+	migratemysql "github.com/golang-migrate/migrate/v4/database/mysql"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"go.uber.org/zap"
+)
+
+const (
+	// MySQLScheme схема DSN для подключения к MySQL
+	MySQLScheme = "mysql://"
+
+	// mysqlDuplicateEntry код ошибки нарушения уникальности (ER_DUP_ENTRY)
+	mysqlDuplicateEntry = 1062
+
+	// mysqlOriginalURLConstraint уникальный индекс по хешу оригинального URL:
+	// индекс по самому URL в MySQL был бы только по префиксу
+	mysqlOriginalURLConstraint = "url_original_url_hash_uindex"
+	// mysqlExistingURLQuery ссылка по оригинальному URL, поиск идет по индексу хеша
+	mysqlExistingURLQuery = `SELECT short_key, is_deleted FROM url WHERE original_url_hash = sha2(?, 256);`
+)
+
+// MySQLURLRepository repository for working with a MySQL database
+type MySQLURLRepository struct {
+	db *sql.DB
+}
+
+// Initialize repository
+func (r *MySQLURLRepository) Initialize(ctx context.Context, configuration environments.Configuration) error {
+	db, err := openMySQL(ctx, configuration.DatabaseDSN)
+	if err != nil {
+		return err
+	}
+	r.db = db
+
+	return nil
+}
+
+// openMySQL подключается к MySQL и применяет миграции, общие для хранилищ ссылок и аккаунтов
+func openMySQL(ctx context.Context, dsn string) (*sql.DB, error) {
+	// Драйвер ожидает DSN без схемы: user:password@tcp(host:port)/dbname
+	config, err := mysql.ParseDSN(strings.TrimPrefix(dsn, MySQLScheme))
+	if err != nil {
+		zap.L().Error("can't parse dsn", zap.String("err", err.Error()))
+		return nil, err
+	}
+	config.ParseTime = true
+	config.Loc = time.UTC
+
+	db, err := sql.Open("mysql", config.FormatDSN())
+	if err != nil {
+		zap.L().Error("can't connect to db", zap.String("err", err.Error()))
+		return nil, err
+	}
+
+	if err = db.PingContext(ctx); err != nil {
+		zap.L().Error("can't ping db", zap.String("err", err.Error()))
+		db.Close()
+		return nil, err
+	}
+
+	driver, err := migratemysql.WithInstance(db, &migratemysql.Config{})
+	if err != nil {
+		zap.L().Error("can't create driver", zap.String("err", err.Error()))
+		db.Close()
+		return nil, err
+	}
+
+	currentDir, _ := os.Getwd()
+	currentDir = strings.TrimSuffix(currentDir, "/internal/models")
+	m, err := migrate.NewWithDatabaseInstance(
+		"file:///"+path.Join(currentDir, "db", "migrations", "mysql"),
+		"mysql", driver)
+	if err != nil {
+		zap.L().Error("can't create new migrate", zap.String("err", err.Error()))
+		db.Close()
+		return nil, err
+	}
+
+	err = m.Up()
+	if err != nil {
+		zap.L().Info("can't migrate up", zap.String("err", err.Error()))
+	}
+
+	zap.L().Info("migrate runned")
+
+	return db, nil
+}
+
+// Get event by short key
+func (r *MySQLURLRepository) Get(ctx context.Context, shortKey string) (Event, error) {
+	var originalURL string
+	var userID sql.NullString
+	var isDeleted bool
+	var expiresAt sql.NullTime
+
+	row := r.db.QueryRowContext(
+		ctx,
+		`SELECT original_url, user_id, is_deleted, expires_at FROM url WHERE short_key = ?;`,
+		shortKey,
+	)
+
+	err := row.Scan(&originalURL, &userID, &isDeleted, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Event{}, ErrNotFound
+	}
+	if err != nil {
+		zap.L().Error(err.Error())
+		return Event{}, unavailable(err)
+	}
+
+	event := Event{
+		ShortKey:    shortKey,
+		OriginalURL: originalURL,
+		UserID:      userID.String,
+		DeletedFlag: isDeleted,
+	}
+	if expiresAt.Valid {
+		event.ExpiresAt = &expiresAt.Time
+	}
+
+	return event, eventError(event)
+}
+
+// Save batch save events in a single transaction, the outcome of every event is reported with *SaveError
+func (r *MySQLURLRepository) Save(ctx context.Context, events []*Event) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		zap.L().Error(err.Error())
+		return unavailable(err)
+	}
+	// После Commit откат ничего не делает
+	defer tx.Rollback()
+
+	errs := make([]error, len(events))
+	for i, event := range events {
+		_, err = tx.ExecContext(
+			ctx,
+			`INSERT INTO url (short_key, original_url, correlation_id, user_id, expires_at)
+VALUES (?, ?, ?, ?, ?);`,
+			event.ShortKey, event.OriginalURL, event.CorrelationID, event.UserID, event.ExpiresAt,
+		)
+		if err == nil {
+			continue
+		}
+
+		// проверяем, что ошибка сигнализирует о наличие данных в БД,
+		// такая ошибка откатывает только саму вставку, а не транзакцию
+		var mysqlErr *mysql.MySQLError
+		if !errors.As(err, &mysqlErr) || mysqlErr.Number != mysqlDuplicateEntry {
+			zap.L().Error(err.Error())
+			return unavailable(err)
+		}
+
+		// MySQL сообщает имя нарушенного индекса только в тексте ошибки
+		if !strings.Contains(mysqlErr.Message, mysqlOriginalURLConstraint) {
+			errs[i] = ErrShortKeyExist
+			continue
+		}
+
+		if errs[i] = existingShortKey(ctx, tx, mysqlExistingURLQuery, event); errors.Is(errs[i], ErrUnavailable) {
+			return errs[i]
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		zap.L().Error(err.Error())
+		return unavailable(err)
+	}
+
+	return saveErrors(errs)
+}
+
+// Delete batch delete event
+func (r *MySQLURLRepository) Delete(ctx context.Context, events []DeleteRequestBatch) error {
+	var errs []error
+
+	for _, deletedEvent := range events {
+		if len(deletedEvent.ShortKeys) == 0 {
+			continue
+		}
+
+		args := make([]any, 0, len(deletedEvent.ShortKeys)+1)
+		args = append(args, deletedEvent.UserID)
+		for _, shortKey := range deletedEvent.ShortKeys {
+			args = append(args, shortKey)
+		}
+
+		_, err := r.db.ExecContext(
+			ctx,
+			`UPDATE url SET is_deleted = true WHERE user_id = ? AND short_key IN (`+
+				placeholders(len(deletedEvent.ShortKeys))+`);`,
+			args...,
+		)
+		if err != nil {
+			zap.L().Error(err.Error())
+			errs = append(errs, unavailable(err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// GetShortKeyByOriginalURL get short link from full link
+func (r *MySQLURLRepository) GetShortKeyByOriginalURL(ctx context.Context, originalURL string) (string, error) {
+	var shortKey string
+
+	row := r.db.QueryRowContext(
+		ctx,
+		`SELECT short_key FROM url WHERE original_url_hash = sha2(?, 256) AND is_deleted IS FALSE;`,
+		originalURL,
+	)
+
+	err := row.Scan(&shortKey)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		zap.L().Error(err.Error())
+		return "", unavailable(err)
+	}
+
+	return shortKey, nil
+}
+
+// GetEventsByUserID get events by user ID
+func (r *MySQLURLRepository) GetEventsByUserID(ctx context.Context, userID string) ([]*Event, error) {
+	var events []*Event
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT short_key, original_url FROM url WHERE user_id = ? AND is_deleted IS FALSE;`,
+		userID,
+	)
+	if err != nil {
+		zap.L().Error(err.Error())
+		return nil, unavailable(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var shortKey string
+		var originalURL string
+
+		err := rows.Scan(&shortKey, &originalURL)
+		if err != nil {
+			zap.L().Error(err.Error())
+			return nil, unavailable(err)
+		}
+
+		events = append(events, &Event{
+			ShortKey:    shortKey,
+			OriginalURL: originalURL,
+		})
+	}
+
+	if err = rows.Err(); err != nil {
+		zap.L().Error(err.Error())
+		return nil, unavailable(err)
+	}
+
+	return events, nil
+}
+
+// IterateEventsByUserID pass active links of the user to fn page by page
+func (r *MySQLURLRepository) IterateEventsByUserID(ctx context.Context, userID string, fn EventIterator) error {
+	return iterateSQLPages(ctx, r.db, "user_id = ?", []any{userID}, fn)
+}
+
+// IterateEvents pass active links of all users to fn page by page
+func (r *MySQLURLRepository) IterateEvents(ctx context.Context, fn EventIterator) error {
+	return iterateSQLPages(ctx, r.db, "TRUE", nil, fn)
+}
+
+// IterateShortKeys pass short keys of all links, deleted and expired ones included
+func (r *MySQLURLRepository) IterateShortKeys(ctx context.Context, fn ShortKeyIterator) error {
+	return iterateShortKeyPages(func(lastShortKey string) ([]string, error) {
+		return readSQLShortKeyPage(ctx, r.db, lastShortKey)
+	}, fn)
+}
+
+// GetStats get repository's stats
+func (r *MySQLURLRepository) GetStats(ctx context.Context) (countUser int, countURL int, err error) {
+	row := r.db.QueryRowContext(
+		ctx,
+		`SELECT COUNT(DISTINCT user_id), COUNT(DISTINCT original_url) FROM url;`,
+	)
+
+	err = row.Scan(&countUser, &countURL)
+
+	return countUser, countURL, unavailable(err)
+}
+
+// DeleteExpired soft delete links which expired at the moment
+func (r *MySQLURLRepository) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	result, err := r.db.ExecContext(
+		ctx,
+		`UPDATE url SET is_deleted = true WHERE is_deleted IS FALSE AND expires_at <= ?;`,
+		now,
+	)
+	if err != nil {
+		zap.L().Error(err.Error())
+		return 0, unavailable(err)
+	}
+
+	count, err := result.RowsAffected()
+
+	return int(count), unavailable(err)
+}
+
+// SaveClicks batch save clicks
+func (r *MySQLURLRepository) SaveClicks(ctx context.Context, clicks []*Click) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+
+	values := make([]string, 0, len(clicks))
+	args := make([]any, 0, len(clicks)*6)
+	for _, click := range clicks {
+		values = append(values, "("+placeholders(6)+")")
+		args = append(args, click.ShortKey, click.CreatedAt, click.Referrer, click.UserAgent, click.IP, click.IPPrefix)
+	}
+
+	_, err := r.db.ExecContext(
+		ctx,
+		`INSERT INTO click (short_key, created_at, referrer, user_agent, ip, ip_prefix) VALUES `+
+			strings.Join(values, ", ")+`;`,
+		args...,
+	)
+	if err != nil {
+		zap.L().Error(err.Error())
+	}
+
+	return unavailable(err)
+}
+
+// GetClickStats get short link's clicks statistic
+func (r *MySQLURLRepository) GetClickStats(ctx context.Context, shortKey string) (*ClickStats, error) {
+	stats := &ClickStats{}
+
+	row := r.db.QueryRowContext(
+		ctx,
+		`SELECT COUNT(*) FROM click WHERE short_key = ?;`,
+		shortKey,
+	)
+	if err := row.Scan(&stats.Total); err != nil {
+		zap.L().Error(err.Error())
+		return nil, unavailable(err)
+	}
+
+	var err error
+
+	stats.ByDay, err = r.getClickCounts(
+		ctx,
+		`SELECT DATE_FORMAT(created_at, '%Y-%m-%d') AS click_key, COUNT(*) FROM click
+WHERE short_key = ? GROUP BY click_key ORDER BY click_key;`,
+		shortKey,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.ByReferrer, err = r.getClickCounts(
+		ctx,
+		`SELECT COALESCE(referrer, '') AS click_key, COUNT(*) AS click_count FROM click
+WHERE short_key = ? GROUP BY click_key ORDER BY click_count DESC, click_key;`,
+		shortKey,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.ByIPPrefix, err = r.getClickCounts(
+		ctx,
+		`SELECT COALESCE(ip_prefix, '') AS click_key, COUNT(*) AS click_count FROM click
+WHERE short_key = ? GROUP BY click_key ORDER BY click_count DESC, click_key;`,
+		shortKey,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// getClickCounts выполняет запрос с группировкой переходов
+func (r *MySQLURLRepository) getClickCounts(ctx context.Context, query string, shortKey string) ([]ClickCount, error) {
+	rows, err := r.db.QueryContext(ctx, query, shortKey)
+	if err != nil {
+		zap.L().Error(err.Error())
+		return nil, unavailable(err)
+	}
+	defer rows.Close()
+
+	counts := []ClickCount{}
+	for rows.Next() {
+		var count ClickCount
+
+		if err = rows.Scan(&count.Key, &count.Count); err != nil {
+			zap.L().Error(err.Error())
+			return nil, unavailable(err)
+		}
+
+		counts = append(counts, count)
+	}
+
+	return counts, unavailable(rows.Err())
+}
+
+// existingShortKey подставляет в событие короткий ключ уже сокращенного URL,
+// query выбирает short_key и is_deleted по оригинальному URL.
+// Возвращает результат сохранения события: ErrURLExist или ErrDeleted для удаленной ссылки.
+func existingShortKey(ctx context.Context, tx *sql.Tx, query string, event *Event) error {
+	var shortKey string
+	var isDeleted bool
+
+	row := tx.QueryRowContext(ctx, query, event.OriginalURL)
+	if err := row.Scan(&shortKey, &isDeleted); err != nil {
+		zap.L().Error(err.Error())
+		return unavailable(err)
+	}
+
+	if isDeleted {
+		return ErrDeleted
+	}
+	event.ShortKey = shortKey
+
+	return ErrURLExist
+}
+
+// placeholders возвращает список параметров запроса вида ?, ?, ?
+func placeholders(count int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", count), ", ")
+}
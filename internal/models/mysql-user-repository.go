@@ -0,0 +1,74 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/ShukinDmitriy/shortener/internal/environments"
+	"github.com/go-sql-driver/mysql"
+	"go.uber.org/zap"
+)
+
+// MySQLUserRepository accounts in the users table of a MySQL database
+type MySQLUserRepository struct {
+	db *sql.DB
+}
+
+// Initialize repository, the users table is created by the migrations
+func (r *MySQLUserRepository) Initialize(ctx context.Context, configuration environments.Configuration) error {
+	db, err := openMySQL(ctx, configuration.DatabaseDSN)
+	if err != nil {
+		return err
+	}
+	r.db = db
+
+	return nil
+}
+
+// Create save the account
+func (r *MySQLUserRepository) Create(ctx context.Context, user *User) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`INSERT INTO users (id, email, password_hash, created_at) VALUES (?, ?, ?, ?);`,
+		user.ID, user.Email, user.PasswordHash, user.CreatedAt,
+	)
+
+	// Конфликт по первичному ключу или индексу email
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+		return ErrUserExist
+	}
+	if err != nil {
+		zap.L().Error(err.Error())
+		return unavailable(err)
+	}
+
+	return nil
+}
+
+// GetByEmail get the account by email
+func (r *MySQLUserRepository) GetByEmail(ctx context.Context, email string) (User, error) {
+	return getSQLUser(ctx, r.db, `SELECT id, email, password_hash, created_at FROM users WHERE email = ?;`, email)
+}
+
+// GetByID get the account by id
+func (r *MySQLUserRepository) GetByID(ctx context.Context, id string) (User, error) {
+	return getSQLUser(ctx, r.db, `SELECT id, email, password_hash, created_at FROM users WHERE id = ?;`, id)
+}
+
+// getSQLUser аккаунт по запросу к таблице users, общий для MySQL и SQLite
+func getSQLUser(ctx context.Context, db *sql.DB, query string, arg string) (User, error) {
+	var user User
+
+	err := db.QueryRowContext(ctx, query, arg).Scan(&user.ID, &user.Email, &user.PasswordHash, &user.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return User{}, ErrNotFound
+	}
+	if err != nil {
+		zap.L().Error(err.Error())
+		return User{}, unavailable(err)
+	}
+
+	return user, nil
+}
@@ -6,14 +6,16 @@ import (
 	"errors"
 	"os"
 	"path"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/ShukinDmitriy/shortener/internal/environments"
+	"github.com/ShukinDmitriy/shortener/internal/tracing"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
-	"github.com/jackc/pgerrcode"
-	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 )
@@ -21,24 +23,41 @@ import (
 // ErrURLExist default error
 var ErrURLExist = errors.New("URL exist")
 
+// ErrShortKeyExist short key (alias) is already taken
+var ErrShortKeyExist = errors.New("short key exist")
+
+// originalURLConstraint уникальный индекс по оригинальному URL
+const originalURLConstraint = "url_original_url_uindex"
+
 // PGURLRepository repository for working with a database
 type PGURLRepository struct {
 	pool *pgxpool.Pool
 }
 
 // Initialize repository
-func (r *PGURLRepository) Initialize(configuration environments.Configuration) error {
-	cont := context.Background()
+func (r *PGURLRepository) Initialize(ctx context.Context, configuration environments.Configuration) error {
 	var pool *pgxpool.Pool
 	var err error
 
-	pool, err = pgxpool.New(cont, configuration.DatabaseDSN)
+	config, err := pgxpool.ParseConfig(configuration.DatabaseDSN)
+	if err != nil {
+		return err
+	}
+	// Каждый запрос к БД попадает в трассировку вызвавшего его запроса
+	config.ConnConfig.Tracer = tracing.PGTracer{}
+
+	pool, err = pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
 		return err
 	}
 	r.pool = pool
 
-	db, err := sql.Open("postgres", configuration.DatabaseDSN)
+	return migratePostgres(configuration.DatabaseDSN)
+}
+
+// migratePostgres применяет миграции из db/migrations, уже примененные пропускаются
+func migratePostgres(dsn string) error {
+	db, err := sql.Open("postgres", dsn)
 	if err != nil {
 		zap.L().Error("can't connect to db", zap.String("err", err.Error()))
 		return err
@@ -74,55 +93,144 @@ func (r *PGURLRepository) Initialize(configuration environments.Configuration) e
 }
 
 // Get event by short key
-func (r *PGURLRepository) Get(shortKey string) (Event, bool) {
+func (r *PGURLRepository) Get(ctx context.Context, shortKey string) (Event, error) {
 	var originalURL string
+	var userID *string
 	var isDeleted bool
+	var expiresAt *time.Time
 
 	row := r.pool.QueryRow(
-		context.Background(),
-		`SELECT original_url, is_deleted from public.url WHERE short_key = $1;`,
+		ctx,
+		`SELECT original_url, user_id, is_deleted, expires_at from public.url WHERE short_key = $1;`,
 		shortKey,
 	)
 
-	err := row.Scan(&originalURL, &isDeleted)
+	err := row.Scan(&originalURL, &userID, &isDeleted, &expiresAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Event{}, ErrNotFound
+	}
 	if err != nil {
 		zap.L().Error(err.Error())
+		return Event{}, unavailable(err)
 	}
 
-	return Event{
+	event := Event{
 		ShortKey:    shortKey,
 		OriginalURL: originalURL,
 		DeletedFlag: isDeleted,
-	}, err == nil && originalURL != ""
+		ExpiresAt:   expiresAt,
+	}
+	if userID != nil {
+		event.UserID = *userID
+	}
+
+	return event, eventError(event)
 }
 
-// Save batch save events
+// Save batch save events in a single transaction, the outcome of every event is reported with *SaveError.
+// The inserts are sent in one pgx.Batch, conflicting rows are skipped and resolved afterwards.
 func (r *PGURLRepository) Save(ctx context.Context, events []*Event) error {
-	var errs []error
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		zap.L().Error(err.Error())
+		return unavailable(err)
+	}
+	// После Commit откат ничего не делает
+	defer tx.Rollback(ctx)
 
+	conflicts, err := r.insertEvents(ctx, tx, events)
+	if err != nil {
+		return err
+	}
+
+	errs := make([]error, len(events))
+	if len(conflicts) > 0 {
+		if err = r.resolveConflicts(ctx, tx, events, conflicts, errs); err != nil {
+			return err
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		zap.L().Error(err.Error())
+		return unavailable(err)
+	}
+
+	return saveErrors(errs)
+}
+
+// insertEvents вставляет события одним пакетом и возвращает индексы событий, не вставленных из-за конфликта
+func (r *PGURLRepository) insertEvents(ctx context.Context, tx pgx.Tx, events []*Event) ([]int, error) {
+	batch := &pgx.Batch{}
 	for _, event := range events {
-		_, err := r.pool.Exec(
-			ctx,
-			`INSERT INTO public.url (short_key, original_url, correlation_id, user_id)
-VALUES ($1, $2, $3, $4);`,
-			event.ShortKey, event.OriginalURL, event.CorrelationID, event.UserID,
+		batch.Queue(
+			`INSERT INTO public.url (short_key, original_url, correlation_id, user_id, expires_at)
+VALUES ($1, $2, $3, $4, $5) ON CONFLICT DO NOTHING RETURNING short_key;`,
+			event.ShortKey, event.OriginalURL, event.CorrelationID, event.UserID, event.ExpiresAt,
 		)
+	}
+
+	results := tx.SendBatch(ctx, batch)
+	defer results.Close()
+
+	var conflicts []int
+	for i := range events {
+		var shortKey string
+
+		err := results.QueryRow().Scan(&shortKey)
+		if errors.Is(err, pgx.ErrNoRows) {
+			conflicts = append(conflicts, i)
+			continue
+		}
 		if err != nil {
 			zap.L().Error(err.Error())
+			return nil, unavailable(err)
+		}
+	}
 
-			// проверяем, что ошибка сигнализирует о наличие данных в БД
-			var pgErr *pgconn.PgError
-			if errors.As(err, &pgErr) && pgerrcode.IsIntegrityConstraintViolation(pgErr.Code) {
-				errs = append(errs, ErrURLExist)
-				shortKey, _ := r.GetShortKeyByOriginalURL(event.OriginalURL)
-				event.ShortKey = shortKey
-			} else {
-				return err
-			}
+	if err := results.Close(); err != nil {
+		zap.L().Error(err.Error())
+		return nil, unavailable(err)
+	}
+
+	return conflicts, nil
+}
+
+// resolveConflicts определяет причину конфликта: если оригинальный URL уже сокращен,
+// событие получает существующий короткий ключ, иначе занят сам короткий ключ
+func (r *PGURLRepository) resolveConflicts(ctx context.Context, tx pgx.Tx, events []*Event, conflicts []int, errs []error) error {
+	batch := &pgx.Batch{}
+	for _, i := range conflicts {
+		batch.Queue(`SELECT short_key, is_deleted FROM public.url WHERE original_url = $1;`, events[i].OriginalURL)
+	}
+
+	results := tx.SendBatch(ctx, batch)
+	defer results.Close()
+
+	for _, i := range conflicts {
+		var shortKey string
+		var isDeleted bool
+
+		err := results.QueryRow().Scan(&shortKey, &isDeleted)
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			errs[i] = ErrShortKeyExist
+		case err != nil:
+			zap.L().Error(err.Error())
+			return unavailable(err)
+		case isDeleted:
+			errs[i] = ErrDeleted
+		default:
+			errs[i] = ErrURLExist
+			events[i].ShortKey = shortKey
 		}
 	}
 
-	return errors.Join(errs...)
+	if err := results.Close(); err != nil {
+		zap.L().Error(err.Error())
+		return unavailable(err)
+	}
+
+	return nil
 }
 
 // Delete batch delete event
@@ -141,7 +249,7 @@ func (r *PGURLRepository) Delete(ctx context.Context, events []DeleteRequestBatc
 		)
 		if err != nil {
 			zap.L().Error(err.Error())
-			errs = append(errs, err)
+			errs = append(errs, unavailable(err))
 		}
 	}
 
@@ -149,25 +257,29 @@ func (r *PGURLRepository) Delete(ctx context.Context, events []DeleteRequestBatc
 }
 
 // GetShortKeyByOriginalURL get short link from full link
-func (r *PGURLRepository) GetShortKeyByOriginalURL(originalURL string) (string, bool) {
+func (r *PGURLRepository) GetShortKeyByOriginalURL(ctx context.Context, originalURL string) (string, error) {
 	var shortKey string
 
 	row := r.pool.QueryRow(
-		context.Background(),
+		ctx,
 		`SELECT short_key from public.url WHERE original_url = $1 and is_deleted is false;`,
 		originalURL,
 	)
 
 	err := row.Scan(&shortKey)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrNotFound
+	}
 	if err != nil {
 		zap.L().Error(err.Error())
+		return "", unavailable(err)
 	}
 
-	return shortKey, err == nil && shortKey != ""
+	return shortKey, nil
 }
 
 // GetEventsByUserID get events by user ID
-func (r *PGURLRepository) GetEventsByUserID(ctx context.Context, userID string) []*Event {
+func (r *PGURLRepository) GetEventsByUserID(ctx context.Context, userID string) ([]*Event, error) {
 	var events []*Event
 
 	rows, err := r.pool.Query(
@@ -177,8 +289,9 @@ func (r *PGURLRepository) GetEventsByUserID(ctx context.Context, userID string)
 	)
 	if err != nil {
 		zap.L().Error(err.Error())
-		return events
+		return nil, unavailable(err)
 	}
+	defer rows.Close()
 
 	for rows.Next() {
 		var shortKey string
@@ -187,7 +300,7 @@ func (r *PGURLRepository) GetEventsByUserID(ctx context.Context, userID string)
 		err := rows.Scan(&shortKey, &originalURL)
 		if err != nil {
 			zap.L().Error(err.Error())
-			return events
+			return nil, unavailable(err)
 		}
 
 		events = append(events, &Event{
@@ -196,11 +309,125 @@ func (r *PGURLRepository) GetEventsByUserID(ctx context.Context, userID string)
 		})
 	}
 
-	if rows.Err() != nil {
-		zap.L().Error(rows.Err().Error())
+	if err = rows.Err(); err != nil {
+		zap.L().Error(err.Error())
+		return nil, unavailable(err)
 	}
 
-	return events
+	return events, nil
+}
+
+// IterateEventsByUserID pass active links of the user to fn reading them with a server-side cursor
+func (r *PGURLRepository) IterateEventsByUserID(ctx context.Context, userID string, fn EventIterator) error {
+	return r.iterateCursor(
+		ctx,
+		fn,
+		`SELECT short_key, original_url, user_id, expires_at FROM public.url
+WHERE user_id = $1 AND is_deleted IS FALSE`,
+		userID,
+	)
+}
+
+// IterateEvents pass active links of all users to fn reading them with a server-side cursor
+func (r *PGURLRepository) IterateEvents(ctx context.Context, fn EventIterator) error {
+	return r.iterateCursor(
+		ctx,
+		fn,
+		`SELECT short_key, original_url, user_id, expires_at FROM public.url WHERE is_deleted IS FALSE`,
+	)
+}
+
+// IterateShortKeys pass short keys of all links, deleted and expired ones included
+func (r *PGURLRepository) IterateShortKeys(ctx context.Context, fn ShortKeyIterator) error {
+	return iterateShortKeyPages(func(lastShortKey string) ([]string, error) {
+		rows, err := r.pool.Query(
+			ctx,
+			`SELECT short_key FROM public.url WHERE short_key > $1 ORDER BY short_key LIMIT $2;`,
+			lastShortKey, iterationPageSize,
+		)
+		if err != nil {
+			zap.L().Error(err.Error())
+			return nil, unavailable(err)
+		}
+		defer rows.Close()
+
+		return scanShortKeys(rows)
+	}, fn)
+}
+
+// iterateCursor читает результат запроса порциями через курсор, который живет до конца транзакции
+func (r *PGURLRepository) iterateCursor(ctx context.Context, fn EventIterator, query string, args ...any) error {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		zap.L().Error(err.Error())
+		return unavailable(err)
+	}
+	// После Commit откат ничего не делает
+	defer tx.Rollback(ctx)
+
+	if _, err = tx.Exec(ctx, `DECLARE url_cursor NO SCROLL CURSOR FOR `+query+`;`, args...); err != nil {
+		zap.L().Error(err.Error())
+		return unavailable(err)
+	}
+
+	fetch := `FETCH FORWARD ` + strconv.Itoa(iterationPageSize) + ` FROM url_cursor;`
+	for {
+		page, err := r.fetchPage(ctx, tx, fetch)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range page {
+			if err = fn(event); err != nil {
+				return err
+			}
+		}
+
+		if len(page) < iterationPageSize {
+			break
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		zap.L().Error(err.Error())
+		return unavailable(err)
+	}
+
+	return nil
+}
+
+// fetchPage читает очередную порцию ссылок из курсора
+func (r *PGURLRepository) fetchPage(ctx context.Context, tx pgx.Tx, fetch string) ([]*Event, error) {
+	rows, err := tx.Query(ctx, fetch)
+	if err != nil {
+		zap.L().Error(err.Error())
+		return nil, unavailable(err)
+	}
+	defer rows.Close()
+
+	page := make([]*Event, 0, iterationPageSize)
+	for rows.Next() {
+		var userID *string
+		event := &Event{}
+
+		if err = rows.Scan(&event.ShortKey, &event.OriginalURL, &userID, &event.ExpiresAt); err != nil {
+			zap.L().Error(err.Error())
+			return nil, unavailable(err)
+		}
+
+		if userID != nil {
+			event.UserID = *userID
+		}
+
+		page = append(page, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		zap.L().Error(err.Error())
+		return nil, unavailable(err)
+	}
+
+	return page, nil
 }
 
 // GetStats get repository's stats
@@ -212,5 +439,117 @@ func (r *PGURLRepository) GetStats(ctx context.Context) (countUser int, countURL
 
 	err = row.Scan(&countUser, &countURL)
 
-	return countUser, countURL, err
+	return countUser, countURL, unavailable(err)
+}
+
+// DeleteExpired soft delete links which expired at the moment
+func (r *PGURLRepository) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	tag, err := r.pool.Exec(
+		ctx,
+		`UPDATE public.url SET is_deleted = true WHERE is_deleted is false AND expires_at <= $1;`,
+		now,
+	)
+	if err != nil {
+		zap.L().Error(err.Error())
+		return 0, unavailable(err)
+	}
+
+	return int(tag.RowsAffected()), nil
+}
+
+// SaveClicks batch save clicks
+func (r *PGURLRepository) SaveClicks(ctx context.Context, clicks []*Click) error {
+	_, err := r.pool.CopyFrom(
+		ctx,
+		pgx.Identifier{"public", "click"},
+		[]string{"short_key", "created_at", "referrer", "user_agent", "ip", "ip_prefix"},
+		pgx.CopyFromSlice(len(clicks), func(i int) ([]any, error) {
+			return []any{
+				clicks[i].ShortKey,
+				clicks[i].CreatedAt,
+				clicks[i].Referrer,
+				clicks[i].UserAgent,
+				clicks[i].IP,
+				clicks[i].IPPrefix,
+			}, nil
+		}),
+	)
+	if err != nil {
+		zap.L().Error(err.Error())
+	}
+
+	return unavailable(err)
+}
+
+// GetClickStats get short link's clicks statistic
+func (r *PGURLRepository) GetClickStats(ctx context.Context, shortKey string) (*ClickStats, error) {
+	stats := &ClickStats{}
+
+	row := r.pool.QueryRow(
+		ctx,
+		`SELECT COUNT(*) FROM public.click WHERE short_key = $1;`,
+		shortKey,
+	)
+	if err := row.Scan(&stats.Total); err != nil {
+		zap.L().Error(err.Error())
+		return nil, unavailable(err)
+	}
+
+	var err error
+
+	stats.ByDay, err = r.getClickCounts(
+		ctx,
+		`SELECT to_char(created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD') AS key, COUNT(*) FROM public.click
+WHERE short_key = $1 GROUP BY key ORDER BY key;`,
+		shortKey,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.ByReferrer, err = r.getClickCounts(
+		ctx,
+		`SELECT COALESCE(referrer, '') AS key, COUNT(*) AS count FROM public.click
+WHERE short_key = $1 GROUP BY key ORDER BY count DESC, key;`,
+		shortKey,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.ByIPPrefix, err = r.getClickCounts(
+		ctx,
+		`SELECT COALESCE(ip_prefix, '') AS key, COUNT(*) AS count FROM public.click
+WHERE short_key = $1 GROUP BY key ORDER BY count DESC, key;`,
+		shortKey,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// getClickCounts выполняет запрос с группировкой переходов
+func (r *PGURLRepository) getClickCounts(ctx context.Context, query string, shortKey string) ([]ClickCount, error) {
+	rows, err := r.pool.Query(ctx, query, shortKey)
+	if err != nil {
+		zap.L().Error(err.Error())
+		return nil, unavailable(err)
+	}
+	defer rows.Close()
+
+	counts := []ClickCount{}
+	for rows.Next() {
+		var count ClickCount
+
+		if err = rows.Scan(&count.Key, &count.Count); err != nil {
+			zap.L().Error(err.Error())
+			return nil, unavailable(err)
+		}
+
+		counts = append(counts, count)
+	}
+
+	return counts, unavailable(rows.Err())
 }
@@ -42,7 +42,7 @@ func TestPGURLRepository_Initialize(t *testing.T) {
 			}
 
 			repository := &models.PGURLRepository{}
-			assert.NoError(t, repository.Initialize(configuration))
+			assert.NoError(t, repository.Initialize(context.TODO(), configuration))
 		})
 	}
 }
@@ -107,7 +107,7 @@ func TestPGURLRepository_CRUD(t *testing.T) {
 			}
 
 			repository := &models.PGURLRepository{}
-			assert.NoError(t, repository.Initialize(configuration))
+			assert.NoError(t, repository.Initialize(context.TODO(), configuration))
 
 			originalURLs := make(map[string]bool, 3)
 
@@ -134,17 +134,18 @@ func TestPGURLRepository_CRUD(t *testing.T) {
 					assert.NoError(t, err)
 				}
 
-				getEvent, found := repository.Get(event.ShortKey)
+				getEvent, err := repository.Get(context.TODO(), event.ShortKey)
 
-				assert.Equal(t, found, !notExpectedFound)
+				assert.Equal(t, !notExpectedFound, err == nil)
 				if !notExpectedFound {
 					assert.Equal(t, event.OriginalURL, getEvent.OriginalURL)
 
-					userEvents := repository.GetEventsByUserID(context.TODO(), event.UserID)
+					userEvents, err := repository.GetEventsByUserID(context.TODO(), event.UserID)
+					assert.NoError(t, err)
 					assert.Len(t, userEvents, 1)
 
-					shortKey, found := repository.GetShortKeyByOriginalURL(event.OriginalURL)
-					assert.True(t, found)
+					shortKey, err := repository.GetShortKeyByOriginalURL(context.TODO(), event.OriginalURL)
+					assert.NoError(t, err)
 					assert.Equal(t, shortKey, event.ShortKey)
 
 					assert.NoError(t, repository.Delete(context.TODO(), []models.DeleteRequestBatch{
@@ -154,8 +155,8 @@ func TestPGURLRepository_CRUD(t *testing.T) {
 						},
 					}))
 
-					getEvent, found = repository.Get(event.ShortKey)
-					assert.True(t, found)
+					getEvent, err = repository.Get(context.TODO(), event.ShortKey)
+					assert.ErrorIs(t, err, models.ErrDeleted)
 					assert.True(t, getEvent.DeletedFlag)
 				}
 			}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ShukinDmitriy/shortener/internal/environments"
+	"github.com/ShukinDmitriy/shortener/internal/tracing"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// PGUserRepository accounts in the users table of the database
+type PGUserRepository struct {
+	pool *pgxpool.Pool
+}
+
+// Initialize repository, the users table is created by the migrations
+func (r *PGUserRepository) Initialize(ctx context.Context, configuration environments.Configuration) error {
+	config, err := pgxpool.ParseConfig(configuration.DatabaseDSN)
+	if err != nil {
+		return err
+	}
+	config.ConnConfig.Tracer = tracing.PGTracer{}
+
+	r.pool, err = pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		return err
+	}
+
+	return migratePostgres(configuration.DatabaseDSN)
+}
+
+// Create save the account
+func (r *PGUserRepository) Create(ctx context.Context, user *User) error {
+	// Конфликт по id или email не прерывает запрос, а оставляет таблицу без изменений
+	tag, err := r.pool.Exec(
+		ctx,
+		`INSERT INTO public.users (id, email, password_hash, created_at) VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING;`,
+		user.ID, user.Email, user.PasswordHash, user.CreatedAt,
+	)
+	if err != nil {
+		zap.L().Error(err.Error())
+		return unavailable(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUserExist
+	}
+
+	return nil
+}
+
+// GetByEmail get the account by email
+func (r *PGUserRepository) GetByEmail(ctx context.Context, email string) (User, error) {
+	return r.get(ctx, `SELECT id, email, password_hash, created_at FROM public.users WHERE email = $1;`, email)
+}
+
+// GetByID get the account by id
+func (r *PGUserRepository) GetByID(ctx context.Context, id string) (User, error) {
+	return r.get(ctx, `SELECT id, email, password_hash, created_at FROM public.users WHERE id = $1;`, id)
+}
+
+func (r *PGUserRepository) get(ctx context.Context, query string, arg string) (User, error) {
+	var user User
+
+	err := r.pool.QueryRow(ctx, query, arg).Scan(&user.ID, &user.Email, &user.PasswordHash, &user.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return User{}, ErrNotFound
+	}
+	if err != nil {
+		zap.L().Error(err.Error())
+		return User{}, unavailable(err)
+	}
+
+	return user, nil
+}
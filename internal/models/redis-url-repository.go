@@ -0,0 +1,418 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/ShukinDmitriy/shortener/internal/environments"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	// redisKeyPrefix префикс всех ключей приложения в redis
+	redisKeyPrefix = "shortener:"
+	// redisURLKey hash с данными ссылки по короткому ключу
+	redisURLKey = redisKeyPrefix + "url:"
+	// redisOriginalURLKey индекс коротких ключей по оригинальному URL
+	redisOriginalURLKey = redisKeyPrefix + "original:"
+	// redisUserKey индекс коротких ключей пользователя
+	redisUserKey = redisKeyPrefix + "user:"
+	// redisClicksKey список переходов по короткому ключу
+	redisClicksKey = redisKeyPrefix + "clicks:"
+	// redisUsersKey множество пользователей
+	redisUsersKey = redisKeyPrefix + "users"
+	// redisURLsKey множество коротких ключей
+	redisURLsKey = redisKeyPrefix + "urls"
+	// redisExpiresKey сортированное множество коротких ключей по времени истечения
+	redisExpiresKey = redisKeyPrefix + "expires"
+)
+
+// redisSaveScript атомарно проверяет уникальность и сохраняет ссылку.
+// Ключ удаленной или истекшей ссылки не переиспользуется, как и в остальных хранилищах.
+var redisSaveScript = redis.NewScript(`
+local existing = redis.call('GET', KEYS[2])
+if existing then
+	return {'url_exist', existing}
+end
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	return {'short_key_exist', ''}
+end
+redis.call('HSET', KEYS[1],
+	'original_url', ARGV[2],
+	'correlation_id', ARGV[3],
+	'user_id', ARGV[4],
+	'is_deleted', '0',
+	'expires_at', ARGV[5])
+redis.call('SET', KEYS[2], ARGV[1])
+redis.call('SADD', KEYS[3], ARGV[1])
+redis.call('SADD', KEYS[4], ARGV[4])
+redis.call('SADD', KEYS[5], ARGV[1])
+if ARGV[6] ~= '' then
+	redis.call('ZADD', KEYS[6], ARGV[6], ARGV[1])
+end
+return {'created', ARGV[1]}
+`)
+
+// redisDeleteScript атомарно помечает ссылку удаленной и чистит индексы
+var redisDeleteScript = redis.NewScript(`
+if ARGV[2] == '1' and redis.call('HGET', KEYS[1], 'user_id') ~= ARGV[3] then
+	return 0
+end
+if redis.call('HGET', KEYS[1], 'is_deleted') ~= '0' then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'is_deleted', '1')
+local originalKey = ARGV[4] .. redis.call('HGET', KEYS[1], 'original_url')
+if redis.call('GET', originalKey) == ARGV[1] then
+	redis.call('DEL', originalKey)
+end
+redis.call('ZREM', KEYS[2], ARGV[1])
+return 1
+`)
+
+// RedisURLRepository repository for working with a redis
+type RedisURLRepository struct {
+	client *redis.Client
+}
+
+// Initialize repository
+func (r *RedisURLRepository) Initialize(ctx context.Context, configuration environments.Configuration) error {
+	options, err := redis.ParseURL(configuration.RedisURL)
+	if err != nil {
+		zap.L().Error("can't parse redis url", zap.String("err", err.Error()))
+		return err
+	}
+
+	r.client = redis.NewClient(options)
+
+	return r.client.Ping(ctx).Err()
+}
+
+// Get event by short key
+func (r *RedisURLRepository) Get(ctx context.Context, shortKey string) (Event, error) {
+	values, err := r.client.HGetAll(ctx, redisURLKey+shortKey).Result()
+	if err != nil {
+		zap.L().Error(err.Error())
+		return Event{}, unavailable(err)
+	}
+
+	if len(values) == 0 {
+		return Event{}, ErrNotFound
+	}
+
+	event := redisValuesToEvent(shortKey, values)
+
+	return event, eventError(event)
+}
+
+// Save batch save events in a MULTI transaction, the outcome of every event is reported with *SaveError
+func (r *RedisURLRepository) Save(ctx context.Context, events []*Event) error {
+	commands := make([]*redis.Cmd, len(events))
+
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, event := range events {
+			expiresAt := ""
+			expiresScore := ""
+			if event.ExpiresAt != nil {
+				expiresAt = event.ExpiresAt.UTC().Format(time.RFC3339Nano)
+				expiresScore = strconv.FormatInt(event.ExpiresAt.UnixMilli(), 10)
+			}
+
+			// Внутри транзакции нельзя повторить EVALSHA после NOSCRIPT, поэтому передаем скрипт целиком
+			commands[i] = redisSaveScript.Eval(
+				ctx,
+				pipe,
+				[]string{
+					redisURLKey + event.ShortKey,
+					redisOriginalURLKey + event.OriginalURL,
+					redisUserKey + event.UserID,
+					redisUsersKey,
+					redisURLsKey,
+					redisExpiresKey,
+				},
+				event.ShortKey, event.OriginalURL, event.CorrelationID, event.UserID, expiresAt, expiresScore,
+			)
+		}
+
+		return nil
+	})
+	if err != nil {
+		zap.L().Error(err.Error())
+		return unavailable(err)
+	}
+
+	errs := make([]error, len(events))
+	for i, command := range commands {
+		result, err := command.StringSlice()
+		if err != nil {
+			zap.L().Error(err.Error())
+			return unavailable(err)
+		}
+
+		switch result[0] {
+		case "url_exist":
+			errs[i] = ErrURLExist
+			events[i].ShortKey = result[1]
+		case "short_key_exist":
+			errs[i] = ErrShortKeyExist
+		}
+	}
+
+	return saveErrors(errs)
+}
+
+// Delete batch delete event
+func (r *RedisURLRepository) Delete(ctx context.Context, events []DeleteRequestBatch) error {
+	var errs []error
+
+	for _, deletedEvent := range events {
+		for _, shortKey := range deletedEvent.ShortKeys {
+			err := r.delete(ctx, shortKey, deletedEvent.UserID, true)
+			if err != nil {
+				zap.L().Error(err.Error())
+				errs = append(errs, unavailable(err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// delete помечает ссылку удаленной, checkUser проверяет владельца ссылки
+func (r *RedisURLRepository) delete(ctx context.Context, shortKey string, userID string, checkUser bool) error {
+	checkUserFlag := "0"
+	if checkUser {
+		checkUserFlag = "1"
+	}
+
+	return redisDeleteScript.Run(
+		ctx,
+		r.client,
+		[]string{redisURLKey + shortKey, redisExpiresKey},
+		shortKey, checkUserFlag, userID, redisOriginalURLKey,
+	).Err()
+}
+
+// GetShortKeyByOriginalURL get short link from full link
+func (r *RedisURLRepository) GetShortKeyByOriginalURL(ctx context.Context, originalURL string) (string, error) {
+	shortKey, err := r.client.Get(ctx, redisOriginalURLKey+originalURL).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		zap.L().Error(err.Error())
+		return "", unavailable(err)
+	}
+
+	return shortKey, nil
+}
+
+// GetEventsByUserID get events by user ID
+func (r *RedisURLRepository) GetEventsByUserID(ctx context.Context, userID string) ([]*Event, error) {
+	var events []*Event
+
+	shortKeys, err := r.client.SMembers(ctx, redisUserKey+userID).Result()
+	if err != nil {
+		zap.L().Error(err.Error())
+		return nil, unavailable(err)
+	}
+
+	pipe := r.client.Pipeline()
+	commands := make([]*redis.MapStringStringCmd, len(shortKeys))
+	for i, shortKey := range shortKeys {
+		commands[i] = pipe.HGetAll(ctx, redisURLKey+shortKey)
+	}
+
+	if _, err = pipe.Exec(ctx); err != nil {
+		zap.L().Error(err.Error())
+		return nil, unavailable(err)
+	}
+
+	for i, command := range commands {
+		event := redisValuesToEvent(shortKeys[i], command.Val())
+
+		// Короткий ключ мог быть переиспользован после удаления
+		if event.UserID != userID || event.DeletedFlag {
+			continue
+		}
+
+		events = append(events, &event)
+	}
+
+	return events, nil
+}
+
+// IterateEventsByUserID pass active links of the user to fn, the index is read with SSCAN
+func (r *RedisURLRepository) IterateEventsByUserID(ctx context.Context, userID string, fn EventIterator) error {
+	return r.iterateSet(ctx, redisUserKey+userID, func(event Event) bool {
+		// Короткий ключ мог быть переиспользован после удаления
+		return event.UserID == userID
+	}, fn)
+}
+
+// IterateEvents pass active links of all users to fn, the index is read with SSCAN
+func (r *RedisURLRepository) IterateEvents(ctx context.Context, fn EventIterator) error {
+	return r.iterateSet(ctx, redisURLsKey, func(_ Event) bool {
+		return true
+	}, fn)
+}
+
+// IterateShortKeys pass short keys of all links, deleted and expired ones included
+func (r *RedisURLRepository) IterateShortKeys(ctx context.Context, fn ShortKeyIterator) error {
+	var cursor uint64
+
+	for {
+		shortKeys, next, err := r.client.SScan(ctx, redisURLsKey, cursor, "", iterationPageSize).Result()
+		if err != nil {
+			zap.L().Error(err.Error())
+			return unavailable(err)
+		}
+
+		for _, shortKey := range shortKeys {
+			if err = fn(shortKey); err != nil {
+				return err
+			}
+		}
+
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// iterateSet обходит множество коротких ключей курсором и читает ссылки порциями
+func (r *RedisURLRepository) iterateSet(ctx context.Context, key string, filter func(event Event) bool, fn EventIterator) error {
+	var cursor uint64
+
+	for {
+		shortKeys, next, err := r.client.SScan(ctx, key, cursor, "", iterationPageSize).Result()
+		if err != nil {
+			zap.L().Error(err.Error())
+			return unavailable(err)
+		}
+
+		pipe := r.client.Pipeline()
+		commands := make([]*redis.MapStringStringCmd, len(shortKeys))
+		for i, shortKey := range shortKeys {
+			commands[i] = pipe.HGetAll(ctx, redisURLKey+shortKey)
+		}
+
+		if len(commands) > 0 {
+			if _, err = pipe.Exec(ctx); err != nil {
+				zap.L().Error(err.Error())
+				return unavailable(err)
+			}
+		}
+
+		for i, command := range commands {
+			event := redisValuesToEvent(shortKeys[i], command.Val())
+			if event.OriginalURL == "" || event.DeletedFlag || !filter(event) {
+				continue
+			}
+
+			if err = fn(&event); err != nil {
+				return err
+			}
+		}
+
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// GetStats get repository's stats
+func (r *RedisURLRepository) GetStats(ctx context.Context) (countUser int, countURL int, err error) {
+	pipe := r.client.Pipeline()
+	usersCommand := pipe.SCard(ctx, redisUsersKey)
+	urlsCommand := pipe.SCard(ctx, redisURLsKey)
+
+	if _, err = pipe.Exec(ctx); err != nil {
+		return 0, 0, unavailable(err)
+	}
+
+	return int(usersCommand.Val()), int(urlsCommand.Val()), nil
+}
+
+// DeleteExpired soft delete links which expired at the moment
+func (r *RedisURLRepository) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	shortKeys, err := r.client.ZRangeByScore(ctx, redisExpiresKey, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(now.UnixMilli(), 10),
+	}).Result()
+	if err != nil {
+		zap.L().Error(err.Error())
+		return 0, unavailable(err)
+	}
+
+	for _, shortKey := range shortKeys {
+		if err = r.delete(ctx, shortKey, "", false); err != nil {
+			zap.L().Error(err.Error())
+			return 0, unavailable(err)
+		}
+	}
+
+	return len(shortKeys), nil
+}
+
+// SaveClicks batch save clicks
+func (r *RedisURLRepository) SaveClicks(ctx context.Context, clicks []*Click) error {
+	pipe := r.client.Pipeline()
+
+	for _, click := range clicks {
+		data, err := json.Marshal(click)
+		if err != nil {
+			return err
+		}
+
+		pipe.RPush(ctx, redisClicksKey+click.ShortKey, data)
+	}
+
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		zap.L().Error(err.Error())
+	}
+
+	return unavailable(err)
+}
+
+// GetClickStats get short link's clicks statistic
+func (r *RedisURLRepository) GetClickStats(ctx context.Context, shortKey string) (*ClickStats, error) {
+	values, err := r.client.LRange(ctx, redisClicksKey+shortKey, 0, -1).Result()
+	if err != nil {
+		zap.L().Error(err.Error())
+		return nil, unavailable(err)
+	}
+
+	clicks := make([]Click, len(values))
+	for i, value := range values {
+		if err = json.Unmarshal([]byte(value), &clicks[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return NewClickStats(clicks), nil
+}
+
+// redisValuesToEvent собирает событие из hash
+func redisValuesToEvent(shortKey string, values map[string]string) Event {
+	event := Event{
+		ShortKey:      shortKey,
+		OriginalURL:   values["original_url"],
+		CorrelationID: values["correlation_id"],
+		UserID:        values["user_id"],
+		DeletedFlag:   values["is_deleted"] == "1",
+	}
+
+	if expiresAt, err := time.Parse(time.RFC3339Nano, values["expires_at"]); err == nil {
+		event.ExpiresAt = &expiresAt
+	}
+
+	return event
+}
@@ -0,0 +1,288 @@
+package models_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ShukinDmitriy/shortener/internal/environments"
+	"github.com/ShukinDmitriy/shortener/internal/models"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRedisURLRepository(t *testing.T) *models.RedisURLRepository {
+	server := miniredis.RunT(t)
+
+	repository := &models.RedisURLRepository{}
+	assert.NoError(t, repository.Initialize(context.TODO(), environments.Configuration{
+		RedisURL: fmt.Sprintf("redis://%s/0", server.Addr()),
+	}))
+
+	return repository
+}
+
+func TestRedisURLRepository_Initialize(t *testing.T) {
+	tests := []struct {
+		name     string
+		redisURL string
+		wantErr  bool
+	}{
+		{
+			name:     "negative test #1",
+			redisURL: "wrong://url",
+			wantErr:  true,
+		},
+		{
+			name:     "negative test #2",
+			redisURL: "redis://127.0.0.1:1/0",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := &models.RedisURLRepository{}
+			err := repository.Initialize(context.TODO(), environments.Configuration{
+				RedisURL: tt.redisURL,
+			})
+			assert.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+}
+
+func TestRedisURLRepository_CRUD(t *testing.T) {
+	type args struct {
+		length int
+		events []models.Event
+	}
+	type want struct {
+		countUsers int
+		countURLs  int
+	}
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "positive test #1",
+			args: args{
+				length: 2,
+				events: []models.Event{
+					{
+						OriginalURL: "https://example.com",
+						ShortKey:    "short1",
+						UserID:      "1",
+					},
+					{
+						OriginalURL: "https://example.com",
+						ShortKey:    "short3",
+						UserID:      "2",
+					},
+					{
+						OriginalURL: "https://example1.com",
+						ShortKey:    "short2",
+						UserID:      "3",
+					},
+				},
+			},
+			want: want{
+				countUsers: 4,
+				countURLs:  5,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := newRedisURLRepository(t)
+
+			for i := 0; i < tt.args.length; i++ {
+				assert.NoError(t, repository.Save(context.TODO(), []*models.Event{
+					{
+						OriginalURL: fmt.Sprintf("https://test%d.data", i),
+						ShortKey:    fmt.Sprintf("test%d", i),
+						UserID:      "0",
+					},
+				}))
+			}
+			userEvents, err := repository.GetEventsByUserID(context.TODO(), "0")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.args.length, len(userEvents))
+
+			for _, event := range tt.args.events {
+				assert.NoError(t, repository.Save(context.TODO(), []*models.Event{
+					{
+						ShortKey:      event.ShortKey,
+						OriginalURL:   event.OriginalURL,
+						CorrelationID: "testCorrelationID",
+						UserID:        event.UserID,
+						DeletedFlag:   false,
+					},
+				}))
+
+				getEvent, err := repository.Get(context.TODO(), event.ShortKey)
+				assert.NoError(t, err)
+				assert.Equal(t, event.OriginalURL, getEvent.OriginalURL)
+
+				shortKey, err := repository.GetShortKeyByOriginalURL(context.TODO(), event.OriginalURL)
+				assert.NoError(t, err)
+				assert.Equal(t, event.ShortKey, shortKey)
+
+				userEvents, err := repository.GetEventsByUserID(context.TODO(), event.UserID)
+				assert.NoError(t, err)
+				assert.Len(t, userEvents, 1)
+
+				assert.NoError(t, repository.Delete(context.TODO(), []models.DeleteRequestBatch{
+					{
+						ShortKeys: []string{event.ShortKey},
+						UserID:    event.UserID,
+					},
+				}))
+
+				getEvent, err = repository.Get(context.TODO(), event.ShortKey)
+				assert.ErrorIs(t, err, models.ErrDeleted)
+				assert.True(t, getEvent.DeletedFlag)
+
+				_, err = repository.GetShortKeyByOriginalURL(context.TODO(), event.OriginalURL)
+				assert.ErrorIs(t, err, models.ErrNotFound)
+			}
+
+			countUsers, countURLs, err := repository.GetStats(context.TODO())
+			assert.Equal(t, tt.want.countUsers, countUsers)
+			assert.Equal(t, tt.want.countURLs, countURLs)
+			assert.Nil(t, err)
+		})
+	}
+}
+
+func TestRedisURLRepository_SaveConflicts(t *testing.T) {
+	repository := newRedisURLRepository(t)
+
+	assert.NoError(t, repository.Save(context.TODO(), []*models.Event{
+		{
+			ShortKey:    "short1",
+			OriginalURL: "https://example.com",
+			UserID:      "1",
+		},
+	}))
+
+	// Повторное сокращение возвращает существующий ключ
+	event := &models.Event{
+		ShortKey:    "short2",
+		OriginalURL: "https://example.com",
+		UserID:      "1",
+	}
+	err := repository.Save(context.TODO(), []*models.Event{event})
+	assert.True(t, errors.Is(err, models.ErrURLExist))
+	assert.Equal(t, "short1", event.ShortKey)
+
+	// Занятый ключ нельзя использовать для другого URL
+	err = repository.Save(context.TODO(), []*models.Event{
+		{
+			ShortKey:    "short1",
+			OriginalURL: "https://example1.com",
+			UserID:      "1",
+		},
+	})
+	assert.True(t, errors.Is(err, models.ErrShortKeyExist))
+
+	// Чужую ссылку удалить нельзя
+	assert.NoError(t, repository.Delete(context.TODO(), []models.DeleteRequestBatch{
+		{
+			ShortKeys: []string{"short1"},
+			UserID:    "2",
+		},
+	}))
+	getEvent, err := repository.Get(context.TODO(), "short1")
+	assert.NoError(t, err)
+	assert.False(t, getEvent.DeletedFlag)
+
+	// Ключ удаленной ссылки не занимается заново
+	assert.NoError(t, repository.Delete(context.TODO(), []models.DeleteRequestBatch{
+		{
+			ShortKeys: []string{"short1"},
+			UserID:    "1",
+		},
+	}))
+	err = repository.Save(context.TODO(), []*models.Event{
+		{
+			ShortKey:    "short1",
+			OriginalURL: "https://hijack.example.com",
+			UserID:      "2",
+		},
+	})
+	assert.True(t, errors.Is(err, models.ErrShortKeyExist))
+	getEvent, err = repository.Get(context.TODO(), "short1")
+	assert.ErrorIs(t, err, models.ErrDeleted)
+	assert.Equal(t, "https://example.com", getEvent.OriginalURL)
+}
+
+func TestRedisURLRepository_DeleteExpired(t *testing.T) {
+	repository := newRedisURLRepository(t)
+
+	now := time.Now()
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Minute)
+
+	assert.NoError(t, repository.Save(context.TODO(), []*models.Event{
+		{
+			ShortKey:    "expired",
+			OriginalURL: "https://expired.example.com",
+			UserID:      "1",
+			ExpiresAt:   &past,
+		},
+		{
+			ShortKey:    "actual",
+			OriginalURL: "https://actual.example.com",
+			UserID:      "1",
+			ExpiresAt:   &future,
+		},
+		{
+			ShortKey:    "forever",
+			OriginalURL: "https://forever.example.com",
+			UserID:      "1",
+		},
+	}))
+
+	count, err := repository.DeleteExpired(context.TODO(), now)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	event, err := repository.Get(context.TODO(), "expired")
+	assert.ErrorIs(t, err, models.ErrDeleted)
+	assert.True(t, event.DeletedFlag)
+
+	event, err = repository.Get(context.TODO(), "actual")
+	assert.NoError(t, err)
+	assert.False(t, event.DeletedFlag)
+	assert.True(t, future.Equal(*event.ExpiresAt))
+
+	// Повторная очистка ничего не находит
+	count, err = repository.DeleteExpired(context.TODO(), now)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestRedisURLRepository_Clicks(t *testing.T) {
+	repository := newRedisURLRepository(t)
+
+	now := time.Now()
+	assert.NoError(t, repository.SaveClicks(context.TODO(), []*models.Click{
+		models.NewClick("short1", now, "https://google.com", "agent", "10.0.0.1"),
+		models.NewClick("short1", now, "", "agent", "10.0.0.2"),
+		models.NewClick("short2", now, "", "agent", "10.0.0.3"),
+	}))
+
+	stats, err := repository.GetClickStats(context.TODO(), "short1")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, stats.Total)
+	assert.Len(t, stats.ByReferrer, 2)
+
+	stats, err = repository.GetClickStats(context.TODO(), "unknown")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, stats.Total)
+}
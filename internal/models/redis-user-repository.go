@@ -0,0 +1,105 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ShukinDmitriy/shortener/internal/environments"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	// redisAccountKey hash с данными аккаунта по id
+	redisAccountKey = redisKeyPrefix + "account:"
+	// redisAccountEmailKey id аккаунта по email
+	redisAccountEmailKey = redisKeyPrefix + "account-email:"
+)
+
+// redisCreateUserScript атомарно проверяет, что id и email свободны, и сохраняет аккаунт
+var redisCreateUserScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 or redis.call('EXISTS', KEYS[2]) == 1 then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'email', ARGV[2], 'password_hash', ARGV[3], 'created_at', ARGV[4])
+redis.call('SET', KEYS[2], ARGV[1])
+return 1
+`)
+
+// RedisUserRepository accounts kept in a redis
+type RedisUserRepository struct {
+	client *redis.Client
+}
+
+// Initialize repository
+func (r *RedisUserRepository) Initialize(ctx context.Context, configuration environments.Configuration) error {
+	options, err := redis.ParseURL(configuration.RedisURL)
+	if err != nil {
+		zap.L().Error("can't parse redis url", zap.String("err", err.Error()))
+		return err
+	}
+
+	r.client = redis.NewClient(options)
+
+	return r.client.Ping(ctx).Err()
+}
+
+// Create save the account
+func (r *RedisUserRepository) Create(ctx context.Context, user *User) error {
+	created, err := redisCreateUserScript.Run(
+		ctx,
+		r.client,
+		[]string{redisAccountKey + user.ID, redisAccountEmailKey + user.Email},
+		user.ID, user.Email, user.PasswordHash, user.CreatedAt.UTC().Format(time.RFC3339Nano),
+	).Int()
+	if err != nil {
+		zap.L().Error(err.Error())
+		return unavailable(err)
+	}
+	if created == 0 {
+		return ErrUserExist
+	}
+
+	return nil
+}
+
+// GetByEmail get the account by email
+func (r *RedisUserRepository) GetByEmail(ctx context.Context, email string) (User, error) {
+	id, err := r.client.Get(ctx, redisAccountEmailKey+email).Result()
+	if errors.Is(err, redis.Nil) {
+		return User{}, ErrNotFound
+	}
+	if err != nil {
+		zap.L().Error(err.Error())
+		return User{}, unavailable(err)
+	}
+
+	return r.GetByID(ctx, id)
+}
+
+// GetByID get the account by id
+func (r *RedisUserRepository) GetByID(ctx context.Context, id string) (User, error) {
+	values, err := r.client.HGetAll(ctx, redisAccountKey+id).Result()
+	if err != nil {
+		zap.L().Error(err.Error())
+		return User{}, unavailable(err)
+	}
+
+	if len(values) == 0 {
+		return User{}, ErrNotFound
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, values["created_at"])
+	if err != nil {
+		zap.L().Error(err.Error())
+		return User{}, unavailable(err)
+	}
+
+	return User{
+		ID:           id,
+		Email:        values["email"],
+		PasswordHash: values["password_hash"],
+		CreatedAt:    createdAt,
+	}, nil
+}
@@ -0,0 +1,434 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	sqlitemigrations "github.com/ShukinDmitriy/shortener/db/migrations/sqlite"
+	"github.com/ShukinDmitriy/shortener/internal/environments"
+	"github.com/golang-migrate/migrate/v4"
+	migratesqlite "github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"go.uber.org/zap"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const (
+	// SQLiteScheme схема DSN для файла SQLite
+	SQLiteScheme = "sqlite://"
+
+	// sqlitePragmas ожидание блокировки и журнал, не блокирующий чтение во время записи
+	sqlitePragmas = "_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	// sqliteOriginalURLColumn колонка уникального индекса по оригинальному URL
+	sqliteOriginalURLColumn = "url.original_url"
+	// sqliteExistingURLQuery ссылка по оригинальному URL
+	sqliteExistingURLQuery = `SELECT short_key, is_deleted FROM url WHERE original_url = ?;`
+)
+
+// SQLiteURLRepository repository for working with an embedded SQLite database
+type SQLiteURLRepository struct {
+	db *sql.DB
+}
+
+// Initialize repository
+func (r *SQLiteURLRepository) Initialize(_ context.Context, configuration environments.Configuration) error {
+	db, err := openSQLite(configuration.DatabaseDSN)
+	if err != nil {
+		return err
+	}
+	r.db = db
+
+	return nil
+}
+
+// openSQLite открывает файл SQLite и применяет миграции, общие для хранилищ ссылок и аккаунтов
+func openSQLite(dsn string) (*sql.DB, error) {
+	dsn = strings.TrimPrefix(dsn, SQLiteScheme)
+	if strings.Contains(dsn, "?") {
+		dsn += "&" + sqlitePragmas
+	} else {
+		dsn += "?" + sqlitePragmas
+	}
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		zap.L().Error("can't connect to db", zap.String("err", err.Error()))
+		return nil, err
+	}
+	// SQLite допускает только одного писателя
+	db.SetMaxOpenConns(1)
+
+	driver, err := migratesqlite.WithInstance(db, &migratesqlite.Config{})
+	if err != nil {
+		zap.L().Error("can't create driver", zap.String("err", err.Error()))
+		db.Close()
+		return nil, err
+	}
+
+	source, err := iofs.New(sqlitemigrations.Migrations, ".")
+	if err != nil {
+		zap.L().Error("can't open migrations", zap.String("err", err.Error()))
+		db.Close()
+		return nil, err
+	}
+
+	m, err := migrate.NewWithInstance("iofs", source, "sqlite", driver)
+	if err != nil {
+		zap.L().Error("can't create new migrate", zap.String("err", err.Error()))
+		db.Close()
+		return nil, err
+	}
+
+	err = m.Up()
+	if err != nil {
+		zap.L().Info("can't migrate up", zap.String("err", err.Error()))
+	}
+
+	zap.L().Info("migrate runned")
+
+	return db, nil
+}
+
+// Get event by short key
+func (r *SQLiteURLRepository) Get(ctx context.Context, shortKey string) (Event, error) {
+	var originalURL string
+	var userID sql.NullString
+	var isDeleted bool
+	var expiresAt sql.NullTime
+
+	row := r.db.QueryRowContext(
+		ctx,
+		`SELECT original_url, user_id, is_deleted, expires_at FROM url WHERE short_key = ?;`,
+		shortKey,
+	)
+
+	err := row.Scan(&originalURL, &userID, &isDeleted, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Event{}, ErrNotFound
+	}
+	if err != nil {
+		zap.L().Error(err.Error())
+		return Event{}, unavailable(err)
+	}
+
+	event := Event{
+		ShortKey:    shortKey,
+		OriginalURL: originalURL,
+		UserID:      userID.String,
+		DeletedFlag: isDeleted,
+	}
+	if expiresAt.Valid {
+		event.ExpiresAt = &expiresAt.Time
+	}
+
+	return event, eventError(event)
+}
+
+// Save batch save events in a single transaction, the outcome of every event is reported with *SaveError
+func (r *SQLiteURLRepository) Save(ctx context.Context, events []*Event) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		zap.L().Error(err.Error())
+		return unavailable(err)
+	}
+	// После Commit откат ничего не делает
+	defer tx.Rollback()
+
+	errs := make([]error, len(events))
+	for i, event := range events {
+		// Время хранится строкой, поэтому приводим его к UTC для корректного сравнения
+		var expiresAt *time.Time
+		if event.ExpiresAt != nil {
+			utc := event.ExpiresAt.UTC()
+			expiresAt = &utc
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			`INSERT INTO url (short_key, original_url, correlation_id, user_id, expires_at)
+VALUES (?, ?, ?, ?, ?);`,
+			event.ShortKey, event.OriginalURL, event.CorrelationID, event.UserID, expiresAt,
+		)
+		if err == nil {
+			continue
+		}
+
+		// проверяем, что ошибка сигнализирует о наличие данных в БД,
+		// такая ошибка откатывает только саму вставку, а не транзакцию
+		var sqliteErr *sqlite.Error
+		if !errors.As(err, &sqliteErr) || !isSQLiteConstraintViolation(sqliteErr.Code()) {
+			zap.L().Error(err.Error())
+			return unavailable(err)
+		}
+
+		// SQLite сообщает колонку нарушенного индекса только в тексте ошибки
+		if !strings.Contains(sqliteErr.Error(), sqliteOriginalURLColumn) {
+			errs[i] = ErrShortKeyExist
+			continue
+		}
+
+		if errs[i] = existingShortKey(ctx, tx, sqliteExistingURLQuery, event); errors.Is(errs[i], ErrUnavailable) {
+			return errs[i]
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		zap.L().Error(err.Error())
+		return unavailable(err)
+	}
+
+	return saveErrors(errs)
+}
+
+// Delete batch delete event
+func (r *SQLiteURLRepository) Delete(ctx context.Context, events []DeleteRequestBatch) error {
+	var errs []error
+
+	for _, deletedEvent := range events {
+		if len(deletedEvent.ShortKeys) == 0 {
+			continue
+		}
+
+		args := make([]any, 0, len(deletedEvent.ShortKeys)+1)
+		args = append(args, deletedEvent.UserID)
+		for _, shortKey := range deletedEvent.ShortKeys {
+			args = append(args, shortKey)
+		}
+
+		_, err := r.db.ExecContext(
+			ctx,
+			`UPDATE url SET is_deleted = true WHERE user_id = ? AND short_key IN (`+
+				placeholders(len(deletedEvent.ShortKeys))+`);`,
+			args...,
+		)
+		if err != nil {
+			zap.L().Error(err.Error())
+			errs = append(errs, unavailable(err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// GetShortKeyByOriginalURL get short link from full link
+func (r *SQLiteURLRepository) GetShortKeyByOriginalURL(ctx context.Context, originalURL string) (string, error) {
+	var shortKey string
+
+	row := r.db.QueryRowContext(
+		ctx,
+		`SELECT short_key FROM url WHERE original_url = ? AND is_deleted IS FALSE;`,
+		originalURL,
+	)
+
+	err := row.Scan(&shortKey)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		zap.L().Error(err.Error())
+		return "", unavailable(err)
+	}
+
+	return shortKey, nil
+}
+
+// GetEventsByUserID get events by user ID
+func (r *SQLiteURLRepository) GetEventsByUserID(ctx context.Context, userID string) ([]*Event, error) {
+	var events []*Event
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT short_key, original_url FROM url WHERE user_id = ? AND is_deleted IS FALSE;`,
+		userID,
+	)
+	if err != nil {
+		zap.L().Error(err.Error())
+		return nil, unavailable(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var shortKey string
+		var originalURL string
+
+		err := rows.Scan(&shortKey, &originalURL)
+		if err != nil {
+			zap.L().Error(err.Error())
+			return nil, unavailable(err)
+		}
+
+		events = append(events, &Event{
+			ShortKey:    shortKey,
+			OriginalURL: originalURL,
+		})
+	}
+
+	if err = rows.Err(); err != nil {
+		zap.L().Error(err.Error())
+		return nil, unavailable(err)
+	}
+
+	return events, nil
+}
+
+// IterateEventsByUserID pass active links of the user to fn page by page
+func (r *SQLiteURLRepository) IterateEventsByUserID(ctx context.Context, userID string, fn EventIterator) error {
+	return iterateSQLPages(ctx, r.db, "user_id = ?", []any{userID}, fn)
+}
+
+// IterateEvents pass active links of all users to fn page by page
+func (r *SQLiteURLRepository) IterateEvents(ctx context.Context, fn EventIterator) error {
+	return iterateSQLPages(ctx, r.db, "TRUE", nil, fn)
+}
+
+// IterateShortKeys pass short keys of all links, deleted and expired ones included
+func (r *SQLiteURLRepository) IterateShortKeys(ctx context.Context, fn ShortKeyIterator) error {
+	return iterateShortKeyPages(func(lastShortKey string) ([]string, error) {
+		return readSQLShortKeyPage(ctx, r.db, lastShortKey)
+	}, fn)
+}
+
+// GetStats get repository's stats
+func (r *SQLiteURLRepository) GetStats(ctx context.Context) (countUser int, countURL int, err error) {
+	row := r.db.QueryRowContext(
+		ctx,
+		`SELECT COUNT(DISTINCT user_id), COUNT(DISTINCT original_url) FROM url;`,
+	)
+
+	err = row.Scan(&countUser, &countURL)
+
+	return countUser, countURL, unavailable(err)
+}
+
+// DeleteExpired soft delete links which expired at the moment
+func (r *SQLiteURLRepository) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	result, err := r.db.ExecContext(
+		ctx,
+		`UPDATE url SET is_deleted = true WHERE is_deleted IS FALSE AND expires_at <= ?;`,
+		now.UTC(),
+	)
+	if err != nil {
+		zap.L().Error(err.Error())
+		return 0, unavailable(err)
+	}
+
+	count, err := result.RowsAffected()
+
+	return int(count), unavailable(err)
+}
+
+// SaveClicks batch save clicks
+func (r *SQLiteURLRepository) SaveClicks(ctx context.Context, clicks []*Click) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		zap.L().Error(err.Error())
+		return unavailable(err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(
+		ctx,
+		`INSERT INTO click (short_key, created_at, referrer, user_agent, ip, ip_prefix) VALUES (?, ?, ?, ?, ?, ?);`,
+	)
+	if err != nil {
+		zap.L().Error(err.Error())
+		return unavailable(err)
+	}
+	defer stmt.Close()
+
+	for _, click := range clicks {
+		_, err = stmt.ExecContext(
+			ctx,
+			click.ShortKey, click.CreatedAt.UTC(), click.Referrer, click.UserAgent, click.IP, click.IPPrefix,
+		)
+		if err != nil {
+			zap.L().Error(err.Error())
+			return unavailable(err)
+		}
+	}
+
+	return unavailable(tx.Commit())
+}
+
+// GetClickStats get short link's clicks statistic
+func (r *SQLiteURLRepository) GetClickStats(ctx context.Context, shortKey string) (*ClickStats, error) {
+	stats := &ClickStats{}
+
+	row := r.db.QueryRowContext(
+		ctx,
+		`SELECT COUNT(*) FROM click WHERE short_key = ?;`,
+		shortKey,
+	)
+	if err := row.Scan(&stats.Total); err != nil {
+		zap.L().Error(err.Error())
+		return nil, unavailable(err)
+	}
+
+	var err error
+
+	// Время переходов хранится в UTC, дата - первые 10 символов
+	stats.ByDay, err = r.getClickCounts(
+		ctx,
+		`SELECT substr(created_at, 1, 10) AS key, COUNT(*) FROM click
+WHERE short_key = ? GROUP BY key ORDER BY key;`,
+		shortKey,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.ByReferrer, err = r.getClickCounts(
+		ctx,
+		`SELECT COALESCE(referrer, '') AS key, COUNT(*) AS count FROM click
+WHERE short_key = ? GROUP BY key ORDER BY count DESC, key;`,
+		shortKey,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.ByIPPrefix, err = r.getClickCounts(
+		ctx,
+		`SELECT COALESCE(ip_prefix, '') AS key, COUNT(*) AS count FROM click
+WHERE short_key = ? GROUP BY key ORDER BY count DESC, key;`,
+		shortKey,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// getClickCounts выполняет запрос с группировкой переходов
+func (r *SQLiteURLRepository) getClickCounts(ctx context.Context, query string, shortKey string) ([]ClickCount, error) {
+	rows, err := r.db.QueryContext(ctx, query, shortKey)
+	if err != nil {
+		zap.L().Error(err.Error())
+		return nil, unavailable(err)
+	}
+	defer rows.Close()
+
+	counts := []ClickCount{}
+	for rows.Next() {
+		var count ClickCount
+
+		if err = rows.Scan(&count.Key, &count.Count); err != nil {
+			zap.L().Error(err.Error())
+			return nil, unavailable(err)
+		}
+
+		counts = append(counts, count)
+	}
+
+	return counts, unavailable(rows.Err())
+}
+
+// isSQLiteConstraintViolation нарушение первичного ключа или уникального индекса
+func isSQLiteConstraintViolation(code int) bool {
+	return code == sqlite3.SQLITE_CONSTRAINT_UNIQUE || code == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/ShukinDmitriy/shortener/internal/environments"
+	"go.uber.org/zap"
+	"modernc.org/sqlite"
+)
+
+// SQLiteUserRepository accounts in the users table of an embedded SQLite database
+type SQLiteUserRepository struct {
+	db *sql.DB
+}
+
+// Initialize repository, the users table is created by the migrations
+func (r *SQLiteUserRepository) Initialize(_ context.Context, configuration environments.Configuration) error {
+	db, err := openSQLite(configuration.DatabaseDSN)
+	if err != nil {
+		return err
+	}
+	r.db = db
+
+	return nil
+}
+
+// Create save the account
+func (r *SQLiteUserRepository) Create(ctx context.Context, user *User) error {
+	// Время хранится строкой, поэтому приводим его к UTC для корректного сравнения
+	_, err := r.db.ExecContext(
+		ctx,
+		`INSERT INTO users (id, email, password_hash, created_at) VALUES (?, ?, ?, ?);`,
+		user.ID, user.Email, user.PasswordHash, user.CreatedAt.UTC(),
+	)
+
+	// Конфликт по первичному ключу или индексу email
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) && isSQLiteConstraintViolation(sqliteErr.Code()) {
+		return ErrUserExist
+	}
+	if err != nil {
+		zap.L().Error(err.Error())
+		return unavailable(err)
+	}
+
+	return nil
+}
+
+// GetByEmail get the account by email
+func (r *SQLiteUserRepository) GetByEmail(ctx context.Context, email string) (User, error) {
+	return getSQLUser(ctx, r.db, `SELECT id, email, password_hash, created_at FROM users WHERE email = ?;`, email)
+}
+
+// GetByID get the account by id
+func (r *SQLiteUserRepository) GetByID(ctx context.Context, id string) (User, error) {
+	return getSQLUser(ctx, r.db, `SELECT id, email, password_hash, created_at FROM users WHERE id = ?;`, id)
+}
@@ -1,7 +1,6 @@
 package models
 
 import (
-	"math/rand"
 	"time"
 
 	"github.com/ShukinDmitriy/shortener/internal/environments"
@@ -9,25 +8,17 @@ import (
 
 // Event short link generation event structure
 type Event struct {
-	ShortKey      string `json:"short_key,omitempty"`
-	OriginalURL   string `json:"original_url,omitempty"`
-	CorrelationID string `json:"correlation_id,omitempty"`
-	UserID        string `json:"user_id,omitempty"`
-	DeletedFlag   bool   `json:"is_deleted,omitempty"`
+	ShortKey      string     `json:"short_key,omitempty"`
+	OriginalURL   string     `json:"original_url,omitempty"`
+	CorrelationID string     `json:"correlation_id,omitempty"`
+	UserID        string     `json:"user_id,omitempty"`
+	DeletedFlag   bool       `json:"is_deleted,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
 }
 
-// GenerateShortKey generate random string
-func GenerateShortKey() string {
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	const keyLength = 6
-
-	source := rand.NewSource(time.Now().UnixNano())
-	rng := rand.New(source)
-	shortKey := make([]byte, keyLength)
-	for i := range shortKey {
-		shortKey[i] = charset[rng.Intn(len(charset))]
-	}
-	return string(shortKey)
+// IsExpired check that the link expired at the moment
+func (e Event) IsExpired(now time.Time) bool {
+	return e.ExpiresAt != nil && !now.Before(*e.ExpiresAt)
 }
 
 // PrepareFullURL prepare full link
@@ -0,0 +1,169 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+
+	"go.uber.org/zap"
+)
+
+// iterationPageSize сколько ссылок читаем из хранилища за одно обращение
+const iterationPageSize = 1000
+
+// EventIterator receives links one by one.
+// Returning an error stops the iteration, the repository returns this error as is.
+type EventIterator func(event *Event) error
+
+// ShortKeyIterator receives short keys one by one, an error stops the iteration
+type ShortKeyIterator func(shortKey string) error
+
+// iterateSnapshot передает ссылки из снимка, прерываясь при отмене контекста
+func iterateSnapshot(ctx context.Context, events []Event, fn EventIterator) error {
+	for i := range events {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := fn(&events[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// iterateSQLPages читает активные ссылки порциями по возрастанию короткого ключа.
+// Последний прочитанный ключ служит курсором следующей порции.
+func iterateSQLPages(ctx context.Context, db *sql.DB, condition string, args []any, fn EventIterator) error {
+	lastShortKey := ""
+
+	for {
+		page, err := readSQLPage(ctx, db, condition, append(args, lastShortKey, iterationPageSize))
+		if err != nil {
+			return err
+		}
+
+		// Соединение освобождено до передачи ссылок получателю
+		for _, event := range page {
+			if err = fn(event); err != nil {
+				return err
+			}
+		}
+
+		if len(page) < iterationPageSize {
+			return nil
+		}
+
+		lastShortKey = page[len(page)-1].ShortKey
+	}
+}
+
+// readSQLPage читает одну порцию ссылок
+func readSQLPage(ctx context.Context, db *sql.DB, condition string, args []any) ([]*Event, error) {
+	rows, err := db.QueryContext(
+		ctx,
+		`SELECT short_key, original_url, user_id, expires_at FROM url
+WHERE is_deleted IS FALSE AND `+condition+` AND short_key > ? ORDER BY short_key LIMIT ?;`,
+		args...,
+	)
+	if err != nil {
+		zap.L().Error(err.Error())
+		return nil, unavailable(err)
+	}
+	defer rows.Close()
+
+	page := make([]*Event, 0, iterationPageSize)
+	for rows.Next() {
+		var userID sql.NullString
+		var expiresAt sql.NullTime
+		event := &Event{}
+
+		if err = rows.Scan(&event.ShortKey, &event.OriginalURL, &userID, &expiresAt); err != nil {
+			zap.L().Error(err.Error())
+			return nil, unavailable(err)
+		}
+
+		event.UserID = userID.String
+		if expiresAt.Valid {
+			event.ExpiresAt = &expiresAt.Time
+		}
+
+		page = append(page, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		zap.L().Error(err.Error())
+		return nil, unavailable(err)
+	}
+
+	return page, nil
+}
+
+// iterateShortKeyPages передает короткие ключи порциями по возрастанию,
+// readPage читает порцию после последнего прочитанного ключа
+func iterateShortKeyPages(readPage func(lastShortKey string) ([]string, error), fn ShortKeyIterator) error {
+	lastShortKey := ""
+
+	for {
+		page, err := readPage(lastShortKey)
+		if err != nil {
+			return err
+		}
+
+		for _, shortKey := range page {
+			if err = fn(shortKey); err != nil {
+				return err
+			}
+		}
+
+		if len(page) < iterationPageSize {
+			return nil
+		}
+
+		lastShortKey = page[len(page)-1]
+	}
+}
+
+// readSQLShortKeyPage читает одну порцию коротких ключей всех ссылок
+func readSQLShortKeyPage(ctx context.Context, db *sql.DB, lastShortKey string) ([]string, error) {
+	rows, err := db.QueryContext(
+		ctx,
+		`SELECT short_key FROM url WHERE short_key > ? ORDER BY short_key LIMIT ?;`,
+		lastShortKey, iterationPageSize,
+	)
+	if err != nil {
+		zap.L().Error(err.Error())
+		return nil, unavailable(err)
+	}
+	defer rows.Close()
+
+	return scanShortKeys(rows)
+}
+
+// shortKeyRows строки с одним коротким ключом
+type shortKeyRows interface {
+	Next() bool
+	Scan(dest ...any) error
+	Err() error
+}
+
+// scanShortKeys читает короткие ключи из строк результата
+func scanShortKeys(rows shortKeyRows) ([]string, error) {
+	page := make([]string, 0, iterationPageSize)
+	for rows.Next() {
+		var shortKey string
+		if err := rows.Scan(&shortKey); err != nil {
+			zap.L().Error(err.Error())
+			return nil, unavailable(err)
+		}
+
+		page = append(page, shortKey)
+	}
+
+	if err := rows.Err(); err != nil {
+		zap.L().Error(err.Error())
+		return nil, unavailable(err)
+	}
+
+	return page, nil
+}
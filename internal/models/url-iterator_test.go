@@ -0,0 +1,133 @@
+package models_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ShukinDmitriy/shortener/internal/environments"
+	"github.com/ShukinDmitriy/shortener/internal/models"
+	"github.com/joho/godotenv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testIterateEvents проверяет обход ссылок хранилища.
+// Ссылок больше одной порции, чтобы курсор переходил на следующую.
+func testIterateEvents(t *testing.T, repository models.URLRepository) {
+	const count = 1001
+	prefix := models.GenerateShortKey()
+	userID := prefix + "user"
+
+	events := make([]*models.Event, 0, count+1)
+	for i := 0; i < count; i++ {
+		events = append(events, &models.Event{
+			ShortKey:    fmt.Sprintf("%s%04d", prefix, i),
+			OriginalURL: fmt.Sprintf("https://%s.com/%d", prefix, i),
+			UserID:      userID,
+		})
+	}
+	events = append(events, &models.Event{
+		ShortKey:    prefix + "other",
+		OriginalURL: "https://" + prefix + ".com/other",
+		UserID:      prefix + "other",
+	})
+	require.NoError(t, repository.Save(context.TODO(), events))
+	require.NoError(t, repository.Delete(context.TODO(), []models.DeleteRequestBatch{{
+		ShortKeys: []string{prefix + "0000"},
+		UserID:    userID,
+	}}))
+
+	// Ссылки пользователя без удаленной
+	userKeys := make(map[string]struct{})
+	require.NoError(t, repository.IterateEventsByUserID(context.TODO(), userID, func(event *models.Event) error {
+		assert.Equal(t, userID, event.UserID)
+		userKeys[event.ShortKey] = struct{}{}
+		return nil
+	}))
+	assert.Len(t, userKeys, count-1)
+	assert.NotContains(t, userKeys, prefix+"0000")
+
+	// Ссылки всех пользователей, в общем хранилище могут быть и чужие
+	allKeys := make(map[string]struct{})
+	require.NoError(t, repository.IterateEvents(context.TODO(), func(event *models.Event) error {
+		allKeys[event.ShortKey] = struct{}{}
+		return nil
+	}))
+	assert.Contains(t, allKeys, prefix+"other")
+	assert.Contains(t, allKeys, prefix+"1000")
+	assert.NotContains(t, allKeys, prefix+"0000")
+
+	// Короткие ключи всех ссылок вместе с удаленной
+	shortKeys := make(map[string]struct{})
+	require.NoError(t, repository.IterateShortKeys(context.TODO(), func(shortKey string) error {
+		shortKeys[shortKey] = struct{}{}
+		return nil
+	}))
+	assert.Contains(t, shortKeys, prefix+"0000")
+	assert.Contains(t, shortKeys, prefix+"1000")
+	assert.Contains(t, shortKeys, prefix+"other")
+
+	// Ошибка получателя прерывает обход
+	errStop := errors.New("stop")
+	calls := 0
+	err := repository.IterateEventsByUserID(context.TODO(), userID, func(_ *models.Event) error {
+		calls++
+		return errStop
+	})
+	assert.ErrorIs(t, err, errStop)
+	assert.Equal(t, 1, calls)
+}
+
+func TestMemoryURLRepository_IterateEvents(t *testing.T) {
+	repository := &models.MemoryURLRepository{}
+	require.NoError(t, repository.Initialize(context.TODO(), environments.Configuration{}))
+
+	testIterateEvents(t, repository)
+
+	// Отмена контекста прерывает обход снимка
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, repository.IterateEvents(ctx, func(_ *models.Event) error {
+		return nil
+	}), context.Canceled)
+}
+
+func TestSQLiteURLRepository_IterateEvents(t *testing.T) {
+	testIterateEvents(t, newSQLiteURLRepository(t, filepath.Join(t.TempDir(), "iterate.db")))
+}
+
+func TestRedisURLRepository_IterateEvents(t *testing.T) {
+	testIterateEvents(t, newRedisURLRepository(t))
+}
+
+func TestPGURLRepository_IterateEvents(t *testing.T) {
+	// Будем скипать тест если нет переменных в test.env
+	godotenv.Load("../../test.env")
+	databaseDSN := os.Getenv("DATABASE_DSN")
+	if databaseDSN == "" {
+		t.Skip("Skipping testing")
+	}
+
+	repository := &models.PGURLRepository{}
+	require.NoError(t, repository.Initialize(context.TODO(), environments.Configuration{DatabaseDSN: databaseDSN}))
+
+	testIterateEvents(t, repository)
+}
+
+func TestMySQLURLRepository_IterateEvents(t *testing.T) {
+	// Будем скипать тест если нет переменных в test.env
+	godotenv.Load("../../test.env")
+	databaseDSN := os.Getenv("MYSQL_DSN")
+	if databaseDSN == "" {
+		t.Skip("Skipping testing")
+	}
+
+	repository := &models.MySQLURLRepository{}
+	require.NoError(t, repository.Initialize(context.TODO(), environments.Configuration{DatabaseDSN: databaseDSN}))
+
+	testIterateEvents(t, repository)
+}
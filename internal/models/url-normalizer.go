@@ -0,0 +1,139 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// DefaultURLMaxLength max length of the original URL when it isn't configured
+const DefaultURLMaxLength = 2048
+
+// ErrURLInvalid original URL can't be shortened, the wrapping error contains the reason
+var ErrURLInvalid = errors.New("url is invalid")
+
+// defaultPorts порты, которые не нужно указывать для схемы
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// URLNormalizer validates original URLs and brings them to the canonical form,
+// so semantically equal URLs are stored as the same string.
+type URLNormalizer struct {
+	// MaxLength max length of the URL before and after normalization
+	MaxLength int
+	// KeepFragment keep the part after #, by default it is dropped
+	KeepFragment bool
+	// SortQuery sort query parameters by name
+	SortQuery bool
+}
+
+// NewURLNormalizer normalizer's constructor, non-positive maxLength means the default one
+func NewURLNormalizer(maxLength int, keepFragment bool, sortQuery bool) *URLNormalizer {
+	if maxLength <= 0 {
+		maxLength = DefaultURLMaxLength
+	}
+
+	return &URLNormalizer{
+		MaxLength:    maxLength,
+		KeepFragment: keepFragment,
+		SortQuery:    sortQuery,
+	}
+}
+
+// Normalize check the URL and return its canonical form:
+// http or https scheme, no credentials, lowercase ASCII host, no default port,
+// at least the root path and no fragment
+func (n *URLNormalizer) Normalize(rawURL string) (string, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return "", fmt.Errorf("%w: empty url", ErrURLInvalid)
+	}
+	if len(rawURL) > n.MaxLength {
+		return "", fmt.Errorf("%w: longer than %d", ErrURLInvalid, n.MaxLength)
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("%w: cannot parse", ErrURLInvalid)
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme == "" {
+		return "", fmt.Errorf("%w: scheme is missing", ErrURLInvalid)
+	}
+	defaultPort, ok := defaultPorts[u.Scheme]
+	if !ok {
+		return "", fmt.Errorf("%w: scheme %q is not allowed", ErrURLInvalid, u.Scheme)
+	}
+
+	// Логин и пароль в ссылке раскрываются всем, кто ее откроет,
+	// а https://bank.com@evil.com выдает чужой хост за настоящий
+	if u.User != nil {
+		return "", fmt.Errorf("%w: credentials are not allowed", ErrURLInvalid)
+	}
+
+	host, err := normalizeHost(u.Hostname())
+	if err != nil {
+		return "", err
+	}
+
+	port := u.Port()
+	if port == defaultPort {
+		port = ""
+	}
+	if port != "" {
+		host = net.JoinHostPort(strings.Trim(host, "[]"), port)
+	}
+	u.Host = host
+
+	// https://example.com и https://example.com/ один и тот же адрес
+	if u.Path == "" {
+		u.Path = "/"
+		u.RawPath = ""
+	}
+
+	if !n.KeepFragment {
+		u.Fragment = ""
+		u.RawFragment = ""
+	}
+
+	// Encode сортирует параметры по имени, порядок значений одного параметра сохраняется
+	if n.SortQuery && u.RawQuery != "" {
+		u.RawQuery = u.Query().Encode()
+	}
+
+	normalized := u.String()
+	if len(normalized) > n.MaxLength {
+		return "", fmt.Errorf("%w: longer than %d", ErrURLInvalid, n.MaxLength)
+	}
+
+	return normalized, nil
+}
+
+// normalizeHost переводит имя хоста в нижний регистр и punycode, IPv6 возвращается в скобках
+func normalizeHost(hostname string) (string, error) {
+	if hostname == "" {
+		return "", fmt.Errorf("%w: host is missing", ErrURLInvalid)
+	}
+
+	if ip := net.ParseIP(hostname); ip != nil {
+		if ip.To4() == nil {
+			return "[" + ip.String() + "]", nil
+		}
+
+		return ip.String(), nil
+	}
+
+	host, err := idna.Lookup.ToASCII(strings.ToLower(strings.TrimSuffix(hostname, ".")))
+	if err != nil || host == "" {
+		return "", fmt.Errorf("%w: host %q is invalid", ErrURLInvalid, hostname)
+	}
+
+	return host, nil
+}
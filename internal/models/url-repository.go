@@ -2,23 +2,118 @@ package models
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/ShukinDmitriy/shortener/internal/environments"
 )
 
-// URLRepository repository interface for working with URL
+// ErrNotFound short link or original URL doesn't exist in the repository
+var ErrNotFound = errors.New("not found")
+
+// ErrDeleted short link was deleted, Get returns the event along with this error
+var ErrDeleted = errors.New("deleted")
+
+// ErrUnavailable repository can't serve the request, e.g. the database is down
+var ErrUnavailable = errors.New("repository unavailable")
+
+// URLRepository repository interface for working with URL.
+// Every method takes a context and reports failures with a typed error:
+// ErrNotFound, ErrDeleted or ErrUnavailable.
+// Save stores the events in a single transaction and reports the outcome
+// of every event with *SaveError, ErrUnavailable means nothing was saved.
 type URLRepository interface {
-	Initialize(configuration environments.Configuration) error
+	Initialize(ctx context.Context, configuration environments.Configuration) error
 
-	Get(shortKey string) (Event, bool)
+	Get(ctx context.Context, shortKey string) (Event, error)
 
 	Save(ctx context.Context, events []*Event) error
 
 	Delete(ctx context.Context, events []DeleteRequestBatch) error
 
-	GetShortKeyByOriginalURL(originalURL string) (string, bool)
+	GetShortKeyByOriginalURL(ctx context.Context, originalURL string) (string, error)
+
+	GetEventsByUserID(ctx context.Context, userID string) ([]*Event, error)
 
-	GetEventsByUserID(ctx context.Context, userID string) []*Event
+	// IterateEventsByUserID pass active links of the user to fn without loading them all at once
+	IterateEventsByUserID(ctx context.Context, userID string, fn EventIterator) error
+
+	// IterateEvents pass active links of all users to fn without loading them all at once
+	IterateEvents(ctx context.Context, fn EventIterator) error
+
+	// IterateShortKeys pass short keys of all links to fn, deleted and expired ones included
+	IterateShortKeys(ctx context.Context, fn ShortKeyIterator) error
 
 	GetStats(ctx context.Context) (countUser int, countURL int, err error)
+
+	DeleteExpired(ctx context.Context, now time.Time) (int, error)
+
+	SaveClicks(ctx context.Context, clicks []*Click) error
+
+	GetClickStats(ctx context.Context, shortKey string) (*ClickStats, error)
+}
+
+// SaveError outcome of every event passed to Save, a nil error means the event was created.
+// ErrURLExist means the original URL is already shortened and the event got the existing short key,
+// ErrShortKeyExist means the short key (alias) is taken,
+// ErrDeleted means the original URL belongs to a deleted link.
+type SaveError struct {
+	Errors []error
+}
+
+// Error all errors of the events
+func (e *SaveError) Error() string {
+	return errors.Join(e.Errors...).Error()
+}
+
+// Unwrap errors of the events which weren't created
+func (e *SaveError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+// SaveErrorAt error of the i-th event passed to Save, other failures apply to every event
+func SaveErrorAt(err error, i int) error {
+	var saveErr *SaveError
+	if errors.As(err, &saveErr) && i < len(saveErr.Errors) {
+		return saveErr.Errors[i]
+	}
+
+	return err
+}
+
+// saveErrors возвращает SaveError, только если какое-то событие не создано
+func saveErrors(errs []error) error {
+	for _, err := range errs {
+		if err != nil {
+			return &SaveError{Errors: errs}
+		}
+	}
+
+	return nil
+}
+
+// unavailable помечает сбой хранилища, сохраняя исходную ошибку
+func unavailable(err error) error {
+	if err == nil || errors.Is(err, ErrUnavailable) {
+		return err
+	}
+
+	return fmt.Errorf("%w: %w", ErrUnavailable, err)
+}
+
+// eventError возвращает ErrDeleted для удаленной ссылки
+func eventError(event Event) error {
+	if event.DeletedFlag {
+		return ErrDeleted
+	}
+
+	return nil
 }
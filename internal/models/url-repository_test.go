@@ -0,0 +1,128 @@
+package models_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/ShukinDmitriy/shortener/internal/environments"
+	"github.com/ShukinDmitriy/shortener/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSaveBatch проверяет, что конфликт одной ссылки не прерывает сохранение пакета
+func testSaveBatch(t *testing.T, repository models.URLRepository) {
+	prefix := models.GenerateShortKey()
+
+	require.NoError(t, repository.Save(context.TODO(), []*models.Event{
+		{
+			ShortKey:    prefix + "taken",
+			OriginalURL: "https://" + prefix + ".com/taken",
+			UserID:      "1",
+		},
+	}))
+
+	events := []*models.Event{
+		{
+			ShortKey:    prefix + "first",
+			OriginalURL: "https://" + prefix + ".com/first",
+			UserID:      "1",
+		},
+		{
+			ShortKey:    prefix + "taken",
+			OriginalURL: "https://" + prefix + ".com/other",
+			UserID:      "1",
+		},
+		{
+			ShortKey:    prefix + "again",
+			OriginalURL: "https://" + prefix + ".com/taken",
+			UserID:      "1",
+		},
+		{
+			ShortKey:    prefix + "last",
+			OriginalURL: "https://" + prefix + ".com/last",
+			UserID:      "1",
+		},
+	}
+	err := repository.Save(context.TODO(), events)
+
+	var saveErr *models.SaveError
+	require.True(t, errors.As(err, &saveErr))
+	assert.True(t, errors.Is(err, models.ErrShortKeyExist))
+
+	assert.NoError(t, models.SaveErrorAt(err, 0))
+	assert.ErrorIs(t, models.SaveErrorAt(err, 1), models.ErrShortKeyExist)
+	assert.ErrorIs(t, models.SaveErrorAt(err, 2), models.ErrURLExist)
+	assert.NoError(t, models.SaveErrorAt(err, 3))
+
+	// Для повторного URL возвращается существующий ключ
+	assert.Equal(t, prefix+"taken", events[2].ShortKey)
+
+	// Ссылки после конфликта тоже сохранены
+	for _, shortKey := range []string{prefix + "first", prefix + "last"} {
+		_, err := repository.Get(context.TODO(), shortKey)
+		assert.NoError(t, err, shortKey)
+	}
+}
+
+func TestMemoryURLRepository_SaveBatch(t *testing.T) {
+	repository := &models.MemoryURLRepository{}
+	require.NoError(t, repository.Initialize(context.TODO(), environments.Configuration{}))
+
+	testSaveBatch(t, repository)
+}
+
+func TestSQLiteURLRepository_SaveBatch(t *testing.T) {
+	testSaveBatch(t, newSQLiteURLRepository(t, filepath.Join(t.TempDir(), "batch.db")))
+}
+
+func TestRedisURLRepository_SaveBatch(t *testing.T) {
+	testSaveBatch(t, newRedisURLRepository(t))
+}
+
+func TestSaveErrorAt(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		i    int
+		want error
+	}{
+		{
+			name: "positive test #1",
+			err:  nil,
+			i:    0,
+			want: nil,
+		},
+		{
+			name: "positive test #2",
+			err:  &models.SaveError{Errors: []error{nil, models.ErrURLExist}},
+			i:    1,
+			want: models.ErrURLExist,
+		},
+		{
+			name: "positive test #3",
+			err:  &models.SaveError{Errors: []error{nil, models.ErrURLExist}},
+			i:    0,
+			want: nil,
+		},
+		{
+			name: "negative test #1",
+			err:  models.ErrUnavailable,
+			i:    0,
+			want: models.ErrUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := models.SaveErrorAt(tt.err, tt.i)
+			if tt.want == nil {
+				assert.NoError(t, got)
+				return
+			}
+			assert.ErrorIs(t, got, tt.want)
+		})
+	}
+}
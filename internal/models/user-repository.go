@@ -0,0 +1,32 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ShukinDmitriy/shortener/internal/environments"
+)
+
+// ErrUserExist user with the email or the id is already registered
+var ErrUserExist = errors.New("user exist")
+
+// User registered account, its id is the user id of the links
+type User struct {
+	ID           string    `json:"id"`
+	Email        string    `json:"email"`
+	PasswordHash string    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// UserRepository repository interface for working with accounts.
+// Get methods report a missing account with ErrNotFound, Create reports a taken email or id with ErrUserExist.
+type UserRepository interface {
+	Initialize(ctx context.Context, configuration environments.Configuration) error
+
+	Create(ctx context.Context, user *User) error
+
+	GetByEmail(ctx context.Context, email string) (User, error)
+
+	GetByID(ctx context.Context, id string) (User, error)
+}
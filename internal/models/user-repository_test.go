@@ -0,0 +1,125 @@
+package models_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ShukinDmitriy/shortener/internal/environments"
+	"github.com/ShukinDmitriy/shortener/internal/models"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/joho/godotenv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testUserRepository проверяет регистрацию и поиск аккаунтов
+func testUserRepository(t *testing.T, repository models.UserRepository) {
+	prefix := models.GenerateShortKey()
+	user := &models.User{
+		ID:           prefix + "-id",
+		Email:        prefix + "@example.com",
+		PasswordHash: "hash",
+		CreatedAt:    time.Now().UTC().Truncate(time.Second),
+	}
+
+	require.NoError(t, repository.Create(context.TODO(), user))
+
+	tests := []struct {
+		name string
+		user *models.User
+		want error
+	}{
+		{
+			name: "negative test #1",
+			user: &models.User{ID: prefix + "-other", Email: user.Email, PasswordHash: "hash", CreatedAt: time.Now()},
+			want: models.ErrUserExist,
+		},
+		{
+			name: "negative test #2",
+			user: &models.User{ID: user.ID, Email: prefix + "-other@example.com", PasswordHash: "hash", CreatedAt: time.Now()},
+			want: models.ErrUserExist,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, repository.Create(context.TODO(), tt.user), tt.want)
+		})
+	}
+
+	got, err := repository.GetByEmail(context.TODO(), user.Email)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, got.ID)
+	assert.Equal(t, user.PasswordHash, got.PasswordHash)
+	assert.True(t, user.CreatedAt.Equal(got.CreatedAt))
+
+	got, err = repository.GetByID(context.TODO(), user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, user.Email, got.Email)
+
+	_, err = repository.GetByEmail(context.TODO(), prefix+"-missing@example.com")
+	assert.ErrorIs(t, err, models.ErrNotFound)
+	_, err = repository.GetByID(context.TODO(), prefix+"-missing")
+	assert.ErrorIs(t, err, models.ErrNotFound)
+}
+
+func TestMemoryUserRepository(t *testing.T) {
+	repository := &models.MemoryUserRepository{}
+	require.NoError(t, repository.Initialize(context.TODO(), environments.Configuration{}))
+
+	testUserRepository(t, repository)
+}
+
+func TestPGUserRepository(t *testing.T) {
+	// Будем скипать тест если нет переменных в test.env
+	godotenv.Load("../../test.env")
+	databaseDSN := os.Getenv("DATABASE_DSN")
+	if databaseDSN == "" {
+		t.Skip("Skipping testing")
+	}
+
+	repository := &models.PGUserRepository{}
+	require.NoError(t, repository.Initialize(context.TODO(), environments.Configuration{DatabaseDSN: databaseDSN}))
+
+	testUserRepository(t, repository)
+}
+
+func TestMySQLUserRepository(t *testing.T) {
+	// Будем скипать тест если нет переменных в test.env
+	godotenv.Load("../../test.env")
+	databaseDSN := os.Getenv("MYSQL_DSN")
+	if databaseDSN == "" {
+		t.Skip("Skipping testing")
+	}
+
+	repository := &models.MySQLUserRepository{}
+	require.NoError(t, repository.Initialize(context.TODO(), environments.Configuration{DatabaseDSN: databaseDSN}))
+
+	testUserRepository(t, repository)
+}
+
+func TestSQLiteUserRepository(t *testing.T) {
+	databaseDSN := models.SQLiteScheme + filepath.Join(t.TempDir(), "shortener.db")
+
+	// Ссылки и аккаунты хранятся в одном файле
+	require.NoError(t, (&models.SQLiteURLRepository{}).Initialize(context.TODO(), environments.Configuration{DatabaseDSN: databaseDSN}))
+
+	repository := &models.SQLiteUserRepository{}
+	require.NoError(t, repository.Initialize(context.TODO(), environments.Configuration{DatabaseDSN: databaseDSN}))
+
+	testUserRepository(t, repository)
+}
+
+func TestRedisUserRepository(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	repository := &models.RedisUserRepository{}
+	require.NoError(t, repository.Initialize(context.TODO(), environments.Configuration{
+		RedisURL: fmt.Sprintf("redis://%s/0", server.Addr()),
+	}))
+
+	testUserRepository(t, repository)
+}
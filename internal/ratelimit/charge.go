@@ -0,0 +1,49 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// Charge takes tokens for links created by an admitted request.
+// The request has already paid one token, so the first link is free.
+type Charge func(ctx context.Context, links int)
+
+// chargeContextKey ключ списания в контексте запроса
+type chargeContextKey struct{}
+
+// NewCharge charge of a request admitted by the bucket of the key
+func NewCharge(limiter Limiter, policy Policy, key string) Charge {
+	var mutex sync.Mutex
+	prepaid := 1
+
+	return func(ctx context.Context, links int) {
+		mutex.Lock()
+		paid := min(prepaid, links)
+		prepaid -= paid
+		mutex.Unlock()
+
+		if links -= paid; links <= 0 {
+			return
+		}
+
+		// Недоступное хранилище не мешает запросам, как и при проверке
+		if err := limiter.Take(ctx, policy, key, links); err != nil {
+			zap.L().Warn("rate limiter is unavailable", zap.String("policy", policy.Name), zap.Error(err))
+		}
+	}
+}
+
+// ContextWithCharge put the charge of the admitted request into the context
+func ContextWithCharge(ctx context.Context, charge Charge) context.Context {
+	return context.WithValue(ctx, chargeContextKey{}, charge)
+}
+
+// ChargeLinks charge the links created by the request, nothing happens when the request isn't limited
+func ChargeLinks(ctx context.Context, links int) {
+	if charge, ok := ctx.Value(chargeContextKey{}).(Charge); ok {
+		charge(ctx, links)
+	}
+}
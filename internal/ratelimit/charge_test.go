@@ -0,0 +1,63 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ShukinDmitriy/shortener/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChargeLinks(t *testing.T) {
+	tests := []struct {
+		name    string
+		links   []int
+		allowed bool
+	}{
+		{
+			name:    "positive test #1",
+			links:   []int{1},
+			allowed: true,
+		},
+		{
+			name:    "positive test #2",
+			links:   []int{2},
+			allowed: true,
+		},
+		{
+			name:    "negative test #1",
+			links:   []int{3},
+			allowed: false,
+		},
+		{
+			name:    "negative test #2",
+			links:   []int{1, 1, 1},
+			allowed: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := ratelimit.NewMemoryLimiter()
+			policy := ratelimit.PerMinute("shorten", 3)
+
+			// Запрос уже оплатил первую ссылку
+			decision, err := limiter.Allow(context.Background(), policy, "user:1")
+			require.NoError(t, err)
+			require.True(t, decision.Allowed)
+
+			ctx := ratelimit.ContextWithCharge(context.Background(), ratelimit.NewCharge(limiter, policy, "user:1"))
+			for _, links := range tt.links {
+				ratelimit.ChargeLinks(ctx, links)
+			}
+
+			decision, err = limiter.Allow(context.Background(), policy, "user:1")
+			require.NoError(t, err)
+			assert.Equal(t, tt.allowed, decision.Allowed)
+		})
+	}
+
+	// Без списания в контексте ничего не происходит
+	ratelimit.ChargeLinks(context.Background(), 10)
+}
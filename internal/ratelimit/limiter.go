@@ -0,0 +1,80 @@
+// Package ratelimit token bucket rate limiting of clients
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Policy token bucket of a group of endpoints.
+// A client may spend Burst requests at once, the bucket refills by Rate requests per second.
+type Policy struct {
+	Name  string
+	Rate  float64
+	Burst int
+}
+
+// PerMinute policy allowing limit requests per minute with the same burst
+func PerMinute(name string, limit int) Policy {
+	return Policy{
+		Name:  name,
+		Rate:  float64(limit) / 60,
+		Burst: limit,
+	}
+}
+
+// Enabled policy with a positive rate limits requests, otherwise everything is allowed
+func (p Policy) Enabled() bool {
+	return p.Rate > 0 && p.Burst > 0
+}
+
+// Decision result of taking a token
+type Decision struct {
+	Allowed bool
+	// сколько запросов еще можно сделать сразу
+	Remaining int
+	// через сколько появится следующий токен, если запрос отклонен
+	RetryAfter time.Duration
+}
+
+// Limiter storage of token buckets, Allow takes a token from the bucket of the key.
+// Take takes n tokens without a check: the bucket may go into debt, and the client
+// is refused until it refills, e.g. for links created by an admitted batch request.
+type Limiter interface {
+	Allow(ctx context.Context, policy Policy, key string) (Decision, error)
+	Take(ctx context.Context, policy Policy, key string, n int) error
+}
+
+// RetryAfterSeconds value of the Retry-After header, at least one second
+func (d Decision) RetryAfterSeconds() int {
+	return max(1, int(math.Ceil(d.RetryAfter.Seconds())))
+}
+
+// refill пополняет ведро, в котором было tokens токенов elapsed назад
+func refill(policy Policy, tokens float64, elapsed time.Duration) float64 {
+	return math.Min(float64(policy.Burst), tokens+elapsed.Seconds()*policy.Rate)
+}
+
+// fullIn через сколько ведро с tokens токенами наполнится, у ведра в долг это дольше, чем у пустого
+func fullIn(policy Policy, tokens float64) time.Duration {
+	return time.Duration((float64(policy.Burst) - tokens) / policy.Rate * float64(time.Second))
+}
+
+// take забирает токен из ведра, в котором было tokens токенов elapsed назад
+func take(policy Policy, tokens float64, elapsed time.Duration) (Decision, float64) {
+	tokens = refill(policy, tokens, elapsed)
+
+	if tokens < 1 {
+		return Decision{
+			RetryAfter: time.Duration((1 - tokens) / policy.Rate * float64(time.Second)),
+		}, tokens
+	}
+
+	tokens--
+
+	return Decision{
+		Allowed:   true,
+		Remaining: int(tokens),
+	}, tokens
+}
@@ -0,0 +1,93 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// memorySweepInterval как часто забываются полные ведра
+const memorySweepInterval = time.Minute
+
+// memoryBucket ведро клиента
+type memoryBucket struct {
+	tokens    float64
+	updatedAt time.Time
+	// когда ведро наполнится и его можно забыть
+	fullAt time.Time
+}
+
+// MemoryLimiter token buckets of a single instance
+type MemoryLimiter struct {
+	mutex   sync.Mutex
+	buckets map[string]*memoryBucket
+	sweptAt time.Time
+}
+
+// NewMemoryLimiter limiter's constructor
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		buckets: make(map[string]*memoryBucket),
+	}
+}
+
+// Allow take a token from the bucket of the key
+func (l *MemoryLimiter) Allow(_ context.Context, policy Policy, key string) (Decision, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	bucket := l.bucket(now, policy, key)
+
+	decision, tokens := take(policy, bucket.tokens, now.Sub(bucket.updatedAt))
+	bucket.update(now, policy, tokens)
+
+	return decision, nil
+}
+
+// Take take n tokens from the bucket of the key, the bucket may go into debt
+func (l *MemoryLimiter) Take(_ context.Context, policy Policy, key string, n int) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	bucket := l.bucket(now, policy, key)
+
+	bucket.update(now, policy, refill(policy, bucket.tokens, now.Sub(bucket.updatedAt))-float64(n))
+
+	return nil
+}
+
+// bucket ведро ключа, новое ведро полное
+func (l *MemoryLimiter) bucket(now time.Time, policy Policy, key string) *memoryBucket {
+	l.sweep(now)
+
+	bucketKey := policy.Name + ":" + key
+	bucket, ok := l.buckets[bucketKey]
+	if !ok {
+		bucket = &memoryBucket{tokens: float64(policy.Burst), updatedAt: now}
+		l.buckets[bucketKey] = bucket
+	}
+
+	return bucket
+}
+
+func (b *memoryBucket) update(now time.Time, policy Policy, tokens float64) {
+	b.tokens = tokens
+	b.updatedAt = now
+	b.fullAt = now.Add(fullIn(policy, tokens))
+}
+
+// sweep удаляет ведра, которые успели наполниться, такие же создаются заново
+func (l *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(l.sweptAt) < memorySweepInterval {
+		return
+	}
+	l.sweptAt = now
+
+	for key, bucket := range l.buckets {
+		if !now.Before(bucket.fullAt) {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ShukinDmitriy/shortener/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryLimiter_Allow(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  ratelimit.Policy
+		calls   int
+		allowed bool
+	}{
+		{
+			name:    "positive test #1",
+			policy:  ratelimit.PerMinute("shorten", 3),
+			calls:   3,
+			allowed: true,
+		},
+		{
+			name:    "negative test #1",
+			policy:  ratelimit.PerMinute("shorten", 3),
+			calls:   4,
+			allowed: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := ratelimit.NewMemoryLimiter()
+
+			var decision ratelimit.Decision
+			var err error
+			for i := 0; i < tt.calls; i++ {
+				decision, err = limiter.Allow(context.Background(), tt.policy, "ip:127.0.0.1")
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.allowed, decision.Allowed)
+			if tt.allowed {
+				assert.Equal(t, 3-tt.calls, decision.Remaining)
+				return
+			}
+
+			// Токен появляется раз в 20 секунд
+			assert.InDelta(t, 20*time.Second, decision.RetryAfter, float64(time.Second))
+			assert.Equal(t, 20, decision.RetryAfterSeconds())
+
+			// У другого клиента и у другой политики свои ведра
+			other, err := limiter.Allow(context.Background(), tt.policy, "ip:127.0.0.2")
+			require.NoError(t, err)
+			assert.True(t, other.Allowed)
+
+			other, err = limiter.Allow(context.Background(), ratelimit.PerMinute("redirect", 3), "ip:127.0.0.1")
+			require.NoError(t, err)
+			assert.True(t, other.Allowed)
+		})
+	}
+}
+
+func TestMemoryLimiter_Refill(t *testing.T) {
+	limiter := ratelimit.NewMemoryLimiter()
+	policy := ratelimit.Policy{Name: "redirect", Rate: 100, Burst: 1}
+
+	decision, err := limiter.Allow(context.Background(), policy, "user:1")
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+
+	decision, err = limiter.Allow(context.Background(), policy, "user:1")
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, 1, decision.RetryAfterSeconds())
+
+	time.Sleep(20 * time.Millisecond)
+
+	decision, err = limiter.Allow(context.Background(), policy, "user:1")
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+}
+
+func TestMemoryLimiter_Take(t *testing.T) {
+	limiter := ratelimit.NewMemoryLimiter()
+	policy := ratelimit.PerMinute("shorten", 3)
+
+	decision, err := limiter.Allow(context.Background(), policy, "user:1")
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+
+	// Ведро уходит в долг на 7 токенов, следующий появится через 8 * 20 секунд
+	require.NoError(t, limiter.Take(context.Background(), policy, "user:1", 9))
+
+	decision, err = limiter.Allow(context.Background(), policy, "user:1")
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	assert.InDelta(t, 160*time.Second, decision.RetryAfter, float64(time.Second))
+}
+
+func TestPerMinute(t *testing.T) {
+	assert.True(t, ratelimit.PerMinute("shorten", 60).Enabled())
+	assert.Equal(t, 1.0, ratelimit.PerMinute("shorten", 60).Rate)
+	assert.False(t, ratelimit.PerMinute("shorten", -1).Enabled())
+}
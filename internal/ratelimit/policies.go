@@ -0,0 +1,38 @@
+package ratelimit
+
+import (
+	"context"
+
+	"github.com/ShukinDmitriy/shortener/internal/environments"
+)
+
+// Policies of the endpoint groups, HTTP and gRPC requests of a client share the buckets
+type Policies struct {
+	// создание ссылок через /api/shorten*
+	Shorten Policy
+	// создание ссылки через POST /
+	Create Policy
+	// переход по короткой ссылке
+	Redirect Policy
+	// регистрация и вход, защищает пароли от перебора
+	Auth Policy
+}
+
+// NewPolicies policies from the configuration, limits are set in requests per minute
+func NewPolicies(configuration environments.Configuration) Policies {
+	return Policies{
+		Shorten:  PerMinute("shorten", configuration.RateLimitShorten),
+		Create:   PerMinute("create", configuration.RateLimitCreate),
+		Redirect: PerMinute("redirect", configuration.RateLimitRedirect),
+		Auth:     PerMinute("auth", configuration.RateLimitAuth),
+	}
+}
+
+// NewLimiter buckets shared through Redis when it's configured, otherwise in-process
+func NewLimiter(ctx context.Context, configuration environments.Configuration) (Limiter, error) {
+	if configuration.RateLimitRedisURL == "" {
+		return NewMemoryLimiter(), nil
+	}
+
+	return NewRedisLimiter(ctx, configuration.RateLimitRedisURL)
+}
@@ -0,0 +1,102 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisKeyPrefix префикс ключей ведер в Redis
+const redisKeyPrefix = "ratelimit:"
+
+// redisTakeScript атомарно пополняет ведро и забирает токены.
+// Без проверки (ARGV[5] = 1) забирает ARGV[4] токенов, и ведро может уйти в долг.
+// Время передается клиентом в миллисекундах, ведро живет, пока не наполнится.
+var redisTakeScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local n = tonumber(ARGV[4])
+local force = ARGV[5] == '1'
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) / 1000 * rate)
+local allowed = 0
+local wait = 0
+if force or tokens >= n then
+	tokens = tokens - n
+	allowed = 1
+else
+	wait = math.ceil((n - tokens) / rate * 1000)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil((burst - tokens) / rate * 1000) + 1000)
+return {allowed, math.max(0, math.floor(tokens)), wait}
+`)
+
+// RedisLimiter token buckets shared by all instances through Redis
+type RedisLimiter struct {
+	client *redis.Client
+}
+
+// NewRedisLimiter connect to Redis by URL
+func NewRedisLimiter(ctx context.Context, redisURL string) (*RedisLimiter, error) {
+	options, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, err
+	}
+
+	client := redis.NewClient(options)
+	if err = client.Ping(ctx).Err(); err != nil {
+		return nil, err
+	}
+
+	return &RedisLimiter{client: client}, nil
+}
+
+// Allow take a token from the shared bucket of the key
+func (l *RedisLimiter) Allow(ctx context.Context, policy Policy, key string) (Decision, error) {
+	return l.take(ctx, policy, key, 1, false)
+}
+
+// Take take n tokens from the shared bucket of the key, the bucket may go into debt
+func (l *RedisLimiter) Take(ctx context.Context, policy Policy, key string, n int) error {
+	_, err := l.take(ctx, policy, key, n, true)
+	return err
+}
+
+func (l *RedisLimiter) take(ctx context.Context, policy Policy, key string, n int, force bool) (Decision, error) {
+	forceArg := "0"
+	if force {
+		forceArg = "1"
+	}
+
+	result, err := redisTakeScript.Run(ctx, l.client,
+		[]string{redisKeyPrefix + policy.Name + ":" + key},
+		policy.Rate,
+		policy.Burst,
+		time.Now().UnixMilli(),
+		n,
+		forceArg,
+	).Int64Slice()
+	if err != nil {
+		return Decision{}, err
+	}
+	if len(result) != 3 {
+		return Decision{}, fmt.Errorf("unexpected rate limit script result %v", result)
+	}
+
+	return Decision{
+		Allowed:    result[0] == 1,
+		Remaining:  int(result[1]),
+		RetryAfter: time.Duration(result[2]) * time.Millisecond,
+	}, nil
+}
+
+// Close the connection
+func (l *RedisLimiter) Close() error {
+	return l.client.Close()
+}
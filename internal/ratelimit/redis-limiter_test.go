@@ -0,0 +1,76 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ShukinDmitriy/shortener/internal/ratelimit"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisLimiter_Allow(t *testing.T) {
+	server := miniredis.RunT(t)
+	policy := ratelimit.PerMinute("shorten", 2)
+
+	// Два экземпляра приложения делят одно ведро
+	first, err := ratelimit.NewRedisLimiter(context.Background(), "redis://"+server.Addr())
+	require.NoError(t, err)
+	defer first.Close()
+	second, err := ratelimit.NewRedisLimiter(context.Background(), "redis://"+server.Addr())
+	require.NoError(t, err)
+	defer second.Close()
+
+	decision, err := first.Allow(context.Background(), policy, "user:1")
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, 1, decision.Remaining)
+
+	decision, err = second.Allow(context.Background(), policy, "user:1")
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, 0, decision.Remaining)
+
+	decision, err = first.Allow(context.Background(), policy, "user:1")
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, 30, decision.RetryAfterSeconds())
+
+	decision, err = second.Allow(context.Background(), policy, "user:2")
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+
+	// Ведро удаляется, когда успевает наполниться
+	assert.Positive(t, server.TTL("ratelimit:shorten:user:1"))
+}
+
+func TestRedisLimiter_Take(t *testing.T) {
+	server := miniredis.RunT(t)
+	policy := ratelimit.PerMinute("shorten", 3)
+
+	limiter, err := ratelimit.NewRedisLimiter(context.Background(), "redis://"+server.Addr())
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	decision, err := limiter.Allow(context.Background(), policy, "user:1")
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+
+	// Ведро уходит в долг на 7 токенов, следующий появится через 8 * 20 секунд
+	require.NoError(t, limiter.Take(context.Background(), policy, "user:1", 9))
+
+	decision, err = limiter.Allow(context.Background(), policy, "user:1")
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, 160, decision.RetryAfterSeconds())
+
+	// Ведро с долгом хранится, пока не наполнится
+	assert.Greater(t, server.TTL("ratelimit:shorten:user:1"), 3*time.Minute)
+}
+
+func TestNewRedisLimiter(t *testing.T) {
+	_, err := ratelimit.NewRedisLimiter(context.Background(), "not a url")
+	assert.Error(t, err)
+}
@@ -0,0 +1,100 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ShukinDmitriy/shortener/internal/environments"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// ConfigureAutocert prepare the manager to obtain certificates for the host of BaseAddr.
+// The directory URL may point to a test ACME server trusted through ACMECAFile.
+func ConfigureAutocert(manager *autocert.Manager, configuration environments.Configuration) error {
+	host, err := AutocertHost(configuration.BaseAddr)
+	if err != nil {
+		return err
+	}
+
+	directoryURL := configuration.ACMEDirectoryURL
+	if directoryURL == "" {
+		directoryURL = acme.LetsEncryptURL
+	}
+
+	httpClient := &http.Client{Timeout: 30 * time.Second}
+	if configuration.ACMECAFile != "" {
+		pool, err := loadCertPool(configuration.ACMECAFile)
+		if err != nil {
+			return err
+		}
+
+		httpClient.Transport = &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{
+				RootCAs:    pool,
+				MinVersion: tls.VersionTLS12,
+			},
+		}
+	}
+
+	manager.Prompt = autocert.AcceptTOS
+	manager.Cache = autocert.DirCache(configuration.AutocertCacheDir)
+	manager.HostPolicy = autocert.HostWhitelist(host)
+	manager.Client = &acme.Client{
+		DirectoryURL: directoryURL,
+		HTTPClient:   httpClient,
+	}
+
+	return nil
+}
+
+// AutocertHost host for which a certificate is requested, it's taken from the base address
+func AutocertHost(baseAddr string) (string, error) {
+	u, err := url.Parse(baseAddr)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrTLSConfig, err)
+	}
+
+	host := u.Hostname()
+	if host == "" {
+		return "", fmt.Errorf("%w: base address %q has no host", ErrTLSConfig, baseAddr)
+	}
+
+	// ACME не выдаёт сертификаты на IP-адреса
+	if net.ParseIP(host) != nil {
+		return "", fmt.Errorf("%w: ACME requires a domain name, got %s", ErrTLSConfig, host)
+	}
+
+	return host, nil
+}
+
+// RedirectHandler permanently redirect plain HTTP requests to the HTTPS server listening on httpsAddr
+func RedirectHandler(httpsAddr string) http.Handler {
+	_, port, _ := net.SplitHostPort(httpsAddr)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+
+		// Стандартный порт не указываем в адресе
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+
+		target := url.URL{
+			Scheme:   "https",
+			Host:     host,
+			Path:     r.URL.Path,
+			RawQuery: r.URL.RawQuery,
+		}
+
+		http.Redirect(w, r, target.String(), http.StatusMovedPermanently)
+	})
+}
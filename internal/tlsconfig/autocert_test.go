@@ -0,0 +1,157 @@
+package tlsconfig_test
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ShukinDmitriy/shortener/internal/environments"
+	"github.com/ShukinDmitriy/shortener/internal/tlsconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+func TestAutocertHost(t *testing.T) {
+	tests := []struct {
+		name     string
+		baseAddr string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "positive test #1",
+			baseAddr: "https://short.example.com",
+			want:     "short.example.com",
+		},
+		{
+			name:     "positive test #2",
+			baseAddr: "https://short.example.com:8443/r",
+			want:     "short.example.com",
+		},
+		{
+			name:     "negative test #1",
+			baseAddr: "http://127.0.0.1:8080",
+			wantErr:  true,
+		},
+		{
+			name:     "negative test #2",
+			baseAddr: "short.example.com",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tlsconfig.AutocertHost(tt.baseAddr)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, tlsconfig.ErrTLSConfig)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConfigureAutocert(t *testing.T) {
+	dir := t.TempDir()
+
+	// Заглушка каталога ACME в духе Pebble, доступная только по TLS с собственным CA
+	var directory *httptest.Server
+	directory = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/dir" {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"newNonce":   directory.URL + "/nonce-plz",
+			"newAccount": directory.URL + "/sign-me-up",
+			"newOrder":   directory.URL + "/order-plz",
+			"revokeCert": directory.URL + "/revoke-cert",
+			"keyChange":  directory.URL + "/rollover-account-key",
+		})
+	}))
+	defer directory.Close()
+
+	ca := filepath.Join(dir, "pebble.minica.pem")
+	require.NoError(t, os.WriteFile(ca, pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: directory.Certificate().Raw,
+	}), 0o600))
+
+	manager := &autocert.Manager{}
+	err := tlsconfig.ConfigureAutocert(manager, environments.Configuration{
+		BaseAddr:         "https://short.example.com",
+		ACMEDirectoryURL: directory.URL + "/dir",
+		ACMECAFile:       ca,
+		AutocertCacheDir: dir,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, directory.URL+"/dir", manager.Client.DirectoryURL)
+	assert.Equal(t, autocert.DirCache(dir), manager.Cache)
+	assert.NoError(t, manager.HostPolicy(context.Background(), "short.example.com"))
+	assert.Error(t, manager.HostPolicy(context.Background(), "evil.example.com"))
+
+	// Каталог доступен, так как его CA передан в настройках
+	discovered, err := manager.Client.Discover(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, directory.URL+"/order-plz", discovered.OrderURL)
+
+	// Без CA сертификат тестового каталога не принимается
+	untrusted := &autocert.Manager{}
+	require.NoError(t, tlsconfig.ConfigureAutocert(untrusted, environments.Configuration{
+		BaseAddr:         "https://short.example.com",
+		ACMEDirectoryURL: directory.URL + "/dir",
+		AutocertCacheDir: dir,
+	}))
+	_, err = untrusted.Client.Discover(context.Background())
+	assert.Error(t, err)
+
+	err = tlsconfig.ConfigureAutocert(&autocert.Manager{}, environments.Configuration{
+		BaseAddr:   "https://short.example.com",
+		ACMECAFile: dir + "/missing.pem",
+	})
+	assert.ErrorIs(t, err, tlsconfig.ErrTLSConfig)
+}
+
+func TestRedirectHandler(t *testing.T) {
+	tests := []struct {
+		name      string
+		httpsAddr string
+		target    string
+		want      string
+	}{
+		{
+			name:      "positive test #1",
+			httpsAddr: ":443",
+			target:    "http://short.example.com/abc?x=1",
+			want:      "https://short.example.com/abc?x=1",
+		},
+		{
+			name:      "positive test #2",
+			httpsAddr: ":8443",
+			target:    "http://short.example.com:8080/api/shorten",
+			want:      "https://short.example.com:8443/api/shorten",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+
+			tlsconfig.RedirectHandler(tt.httpsAddr).ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusMovedPermanently, rec.Code)
+			assert.Equal(t, tt.want, rec.Header().Get("Location"))
+		})
+	}
+}
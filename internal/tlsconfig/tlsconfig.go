@@ -0,0 +1,84 @@
+// Package tlsconfig TLS settings for the HTTP and gRPC servers
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ShukinDmitriy/shortener/internal/environments"
+	"google.golang.org/grpc/credentials"
+)
+
+// ErrTLSConfig certificate, key or CA bundle can't be used
+var ErrTLSConfig = errors.New("invalid TLS configuration")
+
+// NewServerConfig build TLS config of a server.
+// When clientCAFile is set clients must present a certificate signed by one of its CAs.
+func NewServerConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, fmt.Errorf("%w: certificate and key are required", ErrTLSConfig)
+	}
+
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrTLSConfig, err)
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if clientCAFile != "" {
+		pool, err := loadCertPool(clientCAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return config, nil
+}
+
+// GRPCServerCredentials transport credentials of the gRPC server, nil when TLS isn't configured
+func GRPCServerCredentials(configuration environments.Configuration) (credentials.TransportCredentials, error) {
+	if configuration.GrpcTLSCertFile == "" && configuration.GrpcTLSKeyFile == "" {
+		// Проверка клиентов невозможна без собственного сертификата сервера
+		if configuration.GrpcClientCAFile != "" {
+			return nil, fmt.Errorf("%w: client CA requires gRPC certificate and key", ErrTLSConfig)
+		}
+
+		return nil, nil
+	}
+
+	config, err := NewServerConfig(
+		configuration.GrpcTLSCertFile,
+		configuration.GrpcTLSKeyFile,
+		configuration.GrpcClientCAFile,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return credentials.NewTLS(config), nil
+}
+
+// loadCertPool читает PEM-файл с одним или несколькими сертификатами CA
+func loadCertPool(filename string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrTLSConfig, err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("%w: no certificates in %s", ErrTLSConfig, filename)
+	}
+
+	return pool, nil
+}
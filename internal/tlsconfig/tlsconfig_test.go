@@ -0,0 +1,246 @@
+package tlsconfig_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ShukinDmitriy/shortener/internal/environments"
+	"github.com/ShukinDmitriy/shortener/internal/tlsconfig"
+	pb "github.com/ShukinDmitriy/shortener/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// authority тестовый CA
+type authority struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+	file        string
+}
+
+// newAuthority создает самоподписанный CA и сохраняет его сертификат в файл
+func newAuthority(t *testing.T, dir string, name string) *authority {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	file := filepath.Join(dir, name+".pem")
+	require.NoError(t, os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+
+	return &authority{certificate: certificate, key: key, file: file}
+}
+
+// issue выпускает сертификат и сохраняет его с ключом в файлы
+func (a *authority) issue(t *testing.T, dir string, name string, usage x509.ExtKeyUsage) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, a.certificate, &key.PublicKey, a.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+
+	return certFile, keyFile
+}
+
+// dial подключается к серверу с TLS поверх bufconn
+func dial(t *testing.T, serverCreds credentials.TransportCredentials, clientConfig *tls.Config) pb.URLClient {
+	listener := bufconn.Listen(1024 * 1024)
+
+	server := grpc.NewServer(grpc.Creds(serverCreds))
+	pb.RegisterURLServer(server, &pb.UnimplementedURLServer{})
+
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			log.Fatal(err)
+		}
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough://bufnet",
+		grpc.WithTransportCredentials(credentials.NewTLS(clientConfig)),
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	return pb.NewURLClient(conn)
+}
+
+func TestGRPCServerCredentials(t *testing.T) {
+	dir := t.TempDir()
+	ca := newAuthority(t, dir, "ca")
+	otherCA := newAuthority(t, dir, "other-ca")
+	serverCert, serverKey := ca.issue(t, dir, "localhost", x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := ca.issue(t, dir, "internal-client", x509.ExtKeyUsageClientAuth)
+	otherCert, otherKey := otherCA.issue(t, dir, "external-client", x509.ExtKeyUsageClientAuth)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.certificate)
+
+	loadPair := func(certFile, keyFile string) []tls.Certificate {
+		certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+		require.NoError(t, err)
+		return []tls.Certificate{certificate}
+	}
+
+	tests := []struct {
+		name               string
+		clientCAFile       string
+		clientCertificates []tls.Certificate
+		// Unimplemented означает, что рукопожатие прошло и запрос дошел до сервиса
+		code codes.Code
+	}{
+		{
+			name: "positive test #1",
+			code: codes.Unimplemented,
+		},
+		{
+			name:               "positive test #2",
+			clientCAFile:       ca.file,
+			clientCertificates: loadPair(clientCert, clientKey),
+			code:               codes.Unimplemented,
+		},
+		{
+			name:         "negative test #1",
+			clientCAFile: ca.file,
+			code:         codes.Unavailable,
+		},
+		{
+			name:               "negative test #2",
+			clientCAFile:       ca.file,
+			clientCertificates: loadPair(otherCert, otherKey),
+			code:               codes.Unavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			creds, err := tlsconfig.GRPCServerCredentials(environments.Configuration{
+				GrpcTLSCertFile:  serverCert,
+				GrpcTLSKeyFile:   serverKey,
+				GrpcClientCAFile: tt.clientCAFile,
+			})
+			require.NoError(t, err)
+			require.NotNil(t, creds)
+
+			client := dial(t, creds, &tls.Config{
+				RootCAs:      roots,
+				ServerName:   "localhost",
+				Certificates: tt.clientCertificates,
+				MinVersion:   tls.VersionTLS12,
+			})
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			_, err = client.Redirect(ctx, &pb.RedirectRequest{ShortUrl: "short1"})
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+}
+
+func TestGRPCServerCredentials_Config(t *testing.T) {
+	dir := t.TempDir()
+	ca := newAuthority(t, dir, "ca")
+	serverCert, serverKey := ca.issue(t, dir, "localhost", x509.ExtKeyUsageServerAuth)
+
+	tests := []struct {
+		name          string
+		configuration environments.Configuration
+		wantNil       bool
+		wantErr       bool
+	}{
+		{
+			name:          "positive test #1",
+			configuration: environments.Configuration{},
+			wantNil:       true,
+		},
+		{
+			name: "negative test #1",
+			configuration: environments.Configuration{
+				GrpcClientCAFile: ca.file,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative test #2",
+			configuration: environments.Configuration{
+				GrpcTLSCertFile: serverCert,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative test #3",
+			configuration: environments.Configuration{
+				GrpcTLSCertFile:  serverCert,
+				GrpcTLSKeyFile:   serverKey,
+				GrpcClientCAFile: serverKey,
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			creds, err := tlsconfig.GRPCServerCredentials(tt.configuration)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, tlsconfig.ErrTLSConfig)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantNil, creds == nil)
+		})
+	}
+}
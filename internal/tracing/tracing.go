@@ -0,0 +1,120 @@
+// Package tracing OpenTelemetry tracing of the application
+package tracing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ShukinDmitriy/shortener/internal/environments"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ServiceName имя сервиса в трассировках
+const ServiceName = "shortener"
+
+// instrumentationName имя библиотеки инструментирования
+const instrumentationName = "github.com/ShukinDmitriy/shortener"
+
+// ShutdownFunc flushes and stops the exporter
+type ShutdownFunc func(ctx context.Context) error
+
+// Initialize sets the global tracer provider and W3C trace context propagator.
+// Spans are exported to the OTLP endpoint if it is set, otherwise to the trace file
+// or stdout when tracing is enabled. Without an exporter only the context is propagated.
+func Initialize(ctx context.Context, configuration environments.Configuration) (ShutdownFunc, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	exporter, closer, err := newExporter(ctx, configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	if exporter == nil {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(ServiceName),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			err = errors.Join(err, closer.Close())
+		}
+
+		return err
+	}, nil
+}
+
+// newExporter выбирает экспортер спанов по конфигурации
+func newExporter(ctx context.Context, configuration environments.Configuration) (sdktrace.SpanExporter, io.Closer, error) {
+	if configuration.OTLPEndpoint != "" {
+		var options []otlptracegrpc.Option
+
+		// Адрес без схемы считаем адресом коллектора без TLS
+		if strings.Contains(configuration.OTLPEndpoint, "://") {
+			options = append(options, otlptracegrpc.WithEndpointURL(configuration.OTLPEndpoint))
+		} else {
+			options = append(options,
+				otlptracegrpc.WithEndpoint(configuration.OTLPEndpoint),
+				otlptracegrpc.WithInsecure(),
+			)
+		}
+
+		exporter, err := otlptracegrpc.New(ctx, options...)
+
+		return exporter, nil, err
+	}
+
+	if configuration.TraceFile != "" {
+		file, err := os.OpenFile(configuration.TraceFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o666)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, nil, err
+		}
+
+		return exporter, file, nil
+	}
+
+	if configuration.TracingEnabled {
+		exporter, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+
+		return exporter, nil, err
+	}
+
+	return nil, nil, nil
+}
+
+// Tracer returns the application tracer
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
@@ -0,0 +1,78 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ShukinDmitriy/shortener/internal/environments"
+	"github.com/ShukinDmitriy/shortener/internal/tracing"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestInitialize(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "traces.json")
+
+	shutdown, err := tracing.Initialize(context.Background(), environments.Configuration{
+		TraceFile: filename,
+	})
+	assert.NoError(t, err)
+
+	_, span := tracing.Tracer().Start(context.Background(), "test span")
+	span.End()
+	assert.NoError(t, shutdown(context.Background()))
+
+	data, err := os.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "test span")
+}
+
+func TestPGTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	tests := []struct {
+		name       string
+		sql        string
+		err        error
+		wantName   string
+		wantStatus codes.Code
+	}{
+		{
+			name:       "positive test #1",
+			sql:        "SELECT original_url FROM public.url WHERE short_key = $1;",
+			wantName:   "postgres SELECT",
+			wantStatus: codes.Unset,
+		},
+		{
+			name:       "negative test #1",
+			sql:        "\n\tinsert into public.url (short_key) values ($1);",
+			err:        errors.New("duplicate key"),
+			wantName:   "postgres INSERT",
+			wantStatus: codes.Error,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracer := tracing.PGTracer{}
+			ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: tt.sql})
+			tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("SELECT 1"), Err: tt.err})
+
+			spans := recorder.Ended()
+			span := spans[len(spans)-1]
+			assert.Equal(t, tt.wantName, span.Name())
+			assert.Equal(t, trace.SpanKindClient, span.SpanKind())
+			assert.Equal(t, tt.wantStatus, span.Status().Code)
+		})
+	}
+}
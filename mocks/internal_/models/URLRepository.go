@@ -9,6 +9,8 @@ import (
 	mock "github.com/stretchr/testify/mock"
 
 	models "github.com/ShukinDmitriy/shortener/internal/models"
+
+	time "time"
 )
 
 // URLRepository is an autogenerated mock type for the URLRepository type
@@ -71,29 +73,86 @@ func (_c *URLRepository_Delete_Call) RunAndReturn(run func(context.Context, []mo
 	return _c
 }
 
-// Get provides a mock function with given fields: shortKey
-func (_m *URLRepository) Get(shortKey string) (models.Event, bool) {
-	ret := _m.Called(shortKey)
+// DeleteExpired provides a mock function with given fields: ctx, now
+func (_m *URLRepository) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	ret := _m.Called(ctx, now)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteExpired")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) (int, error)); ok {
+		return rf(ctx, now)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) int); ok {
+		r0 = rf(ctx, now)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, now)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// URLRepository_DeleteExpired_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteExpired'
+type URLRepository_DeleteExpired_Call struct {
+	*mock.Call
+}
+
+// DeleteExpired is a helper method to define mock.On call
+//   - ctx context.Context
+//   - now time.Time
+func (_e *URLRepository_Expecter) DeleteExpired(ctx interface{}, now interface{}) *URLRepository_DeleteExpired_Call {
+	return &URLRepository_DeleteExpired_Call{Call: _e.mock.On("DeleteExpired", ctx, now)}
+}
+
+func (_c *URLRepository_DeleteExpired_Call) Run(run func(ctx context.Context, now time.Time)) *URLRepository_DeleteExpired_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(time.Time))
+	})
+	return _c
+}
+
+func (_c *URLRepository_DeleteExpired_Call) Return(_a0 int, _a1 error) *URLRepository_DeleteExpired_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *URLRepository_DeleteExpired_Call) RunAndReturn(run func(context.Context, time.Time) (int, error)) *URLRepository_DeleteExpired_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Get provides a mock function with given fields: ctx, shortKey
+func (_m *URLRepository) Get(ctx context.Context, shortKey string) (models.Event, error) {
+	ret := _m.Called(ctx, shortKey)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Get")
 	}
 
 	var r0 models.Event
-	var r1 bool
-	if rf, ok := ret.Get(0).(func(string) (models.Event, bool)); ok {
-		return rf(shortKey)
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (models.Event, error)); ok {
+		return rf(ctx, shortKey)
 	}
-	if rf, ok := ret.Get(0).(func(string) models.Event); ok {
-		r0 = rf(shortKey)
+	if rf, ok := ret.Get(0).(func(context.Context, string) models.Event); ok {
+		r0 = rf(ctx, shortKey)
 	} else {
 		r0 = ret.Get(0).(models.Event)
 	}
 
-	if rf, ok := ret.Get(1).(func(string) bool); ok {
-		r1 = rf(shortKey)
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, shortKey)
 	} else {
-		r1 = ret.Get(1).(bool)
+		r1 = ret.Error(1)
 	}
 
 	return r0, r1
@@ -105,30 +164,90 @@ type URLRepository_Get_Call struct {
 }
 
 // Get is a helper method to define mock.On call
+//   - ctx context.Context
+//   - shortKey string
+func (_e *URLRepository_Expecter) Get(ctx interface{}, shortKey interface{}) *URLRepository_Get_Call {
+	return &URLRepository_Get_Call{Call: _e.mock.On("Get", ctx, shortKey)}
+}
+
+func (_c *URLRepository_Get_Call) Run(run func(ctx context.Context, shortKey string)) *URLRepository_Get_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *URLRepository_Get_Call) Return(_a0 models.Event, _a1 error) *URLRepository_Get_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *URLRepository_Get_Call) RunAndReturn(run func(context.Context, string) (models.Event, error)) *URLRepository_Get_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetClickStats provides a mock function with given fields: ctx, shortKey
+func (_m *URLRepository) GetClickStats(ctx context.Context, shortKey string) (*models.ClickStats, error) {
+	ret := _m.Called(ctx, shortKey)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetClickStats")
+	}
+
+	var r0 *models.ClickStats
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*models.ClickStats, error)); ok {
+		return rf(ctx, shortKey)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.ClickStats); ok {
+		r0 = rf(ctx, shortKey)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.ClickStats)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, shortKey)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// URLRepository_GetClickStats_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetClickStats'
+type URLRepository_GetClickStats_Call struct {
+	*mock.Call
+}
+
+// GetClickStats is a helper method to define mock.On call
+//   - ctx context.Context
 //   - shortKey string
-func (_e *URLRepository_Expecter) Get(shortKey interface{}) *URLRepository_Get_Call {
-	return &URLRepository_Get_Call{Call: _e.mock.On("Get", shortKey)}
+func (_e *URLRepository_Expecter) GetClickStats(ctx interface{}, shortKey interface{}) *URLRepository_GetClickStats_Call {
+	return &URLRepository_GetClickStats_Call{Call: _e.mock.On("GetClickStats", ctx, shortKey)}
 }
 
-func (_c *URLRepository_Get_Call) Run(run func(shortKey string)) *URLRepository_Get_Call {
+func (_c *URLRepository_GetClickStats_Call) Run(run func(ctx context.Context, shortKey string)) *URLRepository_GetClickStats_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(string))
+		run(args[0].(context.Context), args[1].(string))
 	})
 	return _c
 }
 
-func (_c *URLRepository_Get_Call) Return(_a0 models.Event, _a1 bool) *URLRepository_Get_Call {
+func (_c *URLRepository_GetClickStats_Call) Return(_a0 *models.ClickStats, _a1 error) *URLRepository_GetClickStats_Call {
 	_c.Call.Return(_a0, _a1)
 	return _c
 }
 
-func (_c *URLRepository_Get_Call) RunAndReturn(run func(string) (models.Event, bool)) *URLRepository_Get_Call {
+func (_c *URLRepository_GetClickStats_Call) RunAndReturn(run func(context.Context, string) (*models.ClickStats, error)) *URLRepository_GetClickStats_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
 // GetEventsByUserID provides a mock function with given fields: ctx, userID
-func (_m *URLRepository) GetEventsByUserID(ctx context.Context, userID string) []*models.Event {
+func (_m *URLRepository) GetEventsByUserID(ctx context.Context, userID string) ([]*models.Event, error) {
 	ret := _m.Called(ctx, userID)
 
 	if len(ret) == 0 {
@@ -136,6 +255,10 @@ func (_m *URLRepository) GetEventsByUserID(ctx context.Context, userID string) [
 	}
 
 	var r0 []*models.Event
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]*models.Event, error)); ok {
+		return rf(ctx, userID)
+	}
 	if rf, ok := ret.Get(0).(func(context.Context, string) []*models.Event); ok {
 		r0 = rf(ctx, userID)
 	} else {
@@ -144,7 +267,13 @@ func (_m *URLRepository) GetEventsByUserID(ctx context.Context, userID string) [
 		}
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // URLRepository_GetEventsByUserID_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetEventsByUserID'
@@ -166,39 +295,39 @@ func (_c *URLRepository_GetEventsByUserID_Call) Run(run func(ctx context.Context
 	return _c
 }
 
-func (_c *URLRepository_GetEventsByUserID_Call) Return(_a0 []*models.Event) *URLRepository_GetEventsByUserID_Call {
-	_c.Call.Return(_a0)
+func (_c *URLRepository_GetEventsByUserID_Call) Return(_a0 []*models.Event, _a1 error) *URLRepository_GetEventsByUserID_Call {
+	_c.Call.Return(_a0, _a1)
 	return _c
 }
 
-func (_c *URLRepository_GetEventsByUserID_Call) RunAndReturn(run func(context.Context, string) []*models.Event) *URLRepository_GetEventsByUserID_Call {
+func (_c *URLRepository_GetEventsByUserID_Call) RunAndReturn(run func(context.Context, string) ([]*models.Event, error)) *URLRepository_GetEventsByUserID_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// GetShortKeyByOriginalURL provides a mock function with given fields: originalURL
-func (_m *URLRepository) GetShortKeyByOriginalURL(originalURL string) (string, bool) {
-	ret := _m.Called(originalURL)
+// GetShortKeyByOriginalURL provides a mock function with given fields: ctx, originalURL
+func (_m *URLRepository) GetShortKeyByOriginalURL(ctx context.Context, originalURL string) (string, error) {
+	ret := _m.Called(ctx, originalURL)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetShortKeyByOriginalURL")
 	}
 
 	var r0 string
-	var r1 bool
-	if rf, ok := ret.Get(0).(func(string) (string, bool)); ok {
-		return rf(originalURL)
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (string, error)); ok {
+		return rf(ctx, originalURL)
 	}
-	if rf, ok := ret.Get(0).(func(string) string); ok {
-		r0 = rf(originalURL)
+	if rf, ok := ret.Get(0).(func(context.Context, string) string); ok {
+		r0 = rf(ctx, originalURL)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
-	if rf, ok := ret.Get(1).(func(string) bool); ok {
-		r1 = rf(originalURL)
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, originalURL)
 	} else {
-		r1 = ret.Get(1).(bool)
+		r1 = ret.Error(1)
 	}
 
 	return r0, r1
@@ -210,24 +339,25 @@ type URLRepository_GetShortKeyByOriginalURL_Call struct {
 }
 
 // GetShortKeyByOriginalURL is a helper method to define mock.On call
+//   - ctx context.Context
 //   - originalURL string
-func (_e *URLRepository_Expecter) GetShortKeyByOriginalURL(originalURL interface{}) *URLRepository_GetShortKeyByOriginalURL_Call {
-	return &URLRepository_GetShortKeyByOriginalURL_Call{Call: _e.mock.On("GetShortKeyByOriginalURL", originalURL)}
+func (_e *URLRepository_Expecter) GetShortKeyByOriginalURL(ctx interface{}, originalURL interface{}) *URLRepository_GetShortKeyByOriginalURL_Call {
+	return &URLRepository_GetShortKeyByOriginalURL_Call{Call: _e.mock.On("GetShortKeyByOriginalURL", ctx, originalURL)}
 }
 
-func (_c *URLRepository_GetShortKeyByOriginalURL_Call) Run(run func(originalURL string)) *URLRepository_GetShortKeyByOriginalURL_Call {
+func (_c *URLRepository_GetShortKeyByOriginalURL_Call) Run(run func(ctx context.Context, originalURL string)) *URLRepository_GetShortKeyByOriginalURL_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(string))
+		run(args[0].(context.Context), args[1].(string))
 	})
 	return _c
 }
 
-func (_c *URLRepository_GetShortKeyByOriginalURL_Call) Return(_a0 string, _a1 bool) *URLRepository_GetShortKeyByOriginalURL_Call {
+func (_c *URLRepository_GetShortKeyByOriginalURL_Call) Return(_a0 string, _a1 error) *URLRepository_GetShortKeyByOriginalURL_Call {
 	_c.Call.Return(_a0, _a1)
 	return _c
 }
 
-func (_c *URLRepository_GetShortKeyByOriginalURL_Call) RunAndReturn(run func(string) (string, bool)) *URLRepository_GetShortKeyByOriginalURL_Call {
+func (_c *URLRepository_GetShortKeyByOriginalURL_Call) RunAndReturn(run func(context.Context, string) (string, error)) *URLRepository_GetShortKeyByOriginalURL_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -295,17 +425,17 @@ func (_c *URLRepository_GetStats_Call) RunAndReturn(run func(context.Context) (i
 	return _c
 }
 
-// Initialize provides a mock function with given fields: configuration
-func (_m *URLRepository) Initialize(configuration environments.Configuration) error {
-	ret := _m.Called(configuration)
+// Initialize provides a mock function with given fields: ctx, configuration
+func (_m *URLRepository) Initialize(ctx context.Context, configuration environments.Configuration) error {
+	ret := _m.Called(ctx, configuration)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Initialize")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(environments.Configuration) error); ok {
-		r0 = rf(configuration)
+	if rf, ok := ret.Get(0).(func(context.Context, environments.Configuration) error); ok {
+		r0 = rf(ctx, configuration)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -319,14 +449,15 @@ type URLRepository_Initialize_Call struct {
 }
 
 // Initialize is a helper method to define mock.On call
+//   - ctx context.Context
 //   - configuration environments.Configuration
-func (_e *URLRepository_Expecter) Initialize(configuration interface{}) *URLRepository_Initialize_Call {
-	return &URLRepository_Initialize_Call{Call: _e.mock.On("Initialize", configuration)}
+func (_e *URLRepository_Expecter) Initialize(ctx interface{}, configuration interface{}) *URLRepository_Initialize_Call {
+	return &URLRepository_Initialize_Call{Call: _e.mock.On("Initialize", ctx, configuration)}
 }
 
-func (_c *URLRepository_Initialize_Call) Run(run func(configuration environments.Configuration)) *URLRepository_Initialize_Call {
+func (_c *URLRepository_Initialize_Call) Run(run func(ctx context.Context, configuration environments.Configuration)) *URLRepository_Initialize_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(environments.Configuration))
+		run(args[0].(context.Context), args[1].(environments.Configuration))
 	})
 	return _c
 }
@@ -336,7 +467,149 @@ func (_c *URLRepository_Initialize_Call) Return(_a0 error) *URLRepository_Initia
 	return _c
 }
 
-func (_c *URLRepository_Initialize_Call) RunAndReturn(run func(environments.Configuration) error) *URLRepository_Initialize_Call {
+func (_c *URLRepository_Initialize_Call) RunAndReturn(run func(context.Context, environments.Configuration) error) *URLRepository_Initialize_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// IterateEvents provides a mock function with given fields: ctx, fn
+func (_m *URLRepository) IterateEvents(ctx context.Context, fn models.EventIterator) error {
+	ret := _m.Called(ctx, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IterateEvents")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.EventIterator) error); ok {
+		r0 = rf(ctx, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// URLRepository_IterateEvents_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'IterateEvents'
+type URLRepository_IterateEvents_Call struct {
+	*mock.Call
+}
+
+// IterateEvents is a helper method to define mock.On call
+//   - ctx context.Context
+//   - fn models.EventIterator
+func (_e *URLRepository_Expecter) IterateEvents(ctx interface{}, fn interface{}) *URLRepository_IterateEvents_Call {
+	return &URLRepository_IterateEvents_Call{Call: _e.mock.On("IterateEvents", ctx, fn)}
+}
+
+func (_c *URLRepository_IterateEvents_Call) Run(run func(ctx context.Context, fn models.EventIterator)) *URLRepository_IterateEvents_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(models.EventIterator))
+	})
+	return _c
+}
+
+func (_c *URLRepository_IterateEvents_Call) Return(_a0 error) *URLRepository_IterateEvents_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *URLRepository_IterateEvents_Call) RunAndReturn(run func(context.Context, models.EventIterator) error) *URLRepository_IterateEvents_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// IterateEventsByUserID provides a mock function with given fields: ctx, userID, fn
+func (_m *URLRepository) IterateEventsByUserID(ctx context.Context, userID string, fn models.EventIterator) error {
+	ret := _m.Called(ctx, userID, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IterateEventsByUserID")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.EventIterator) error); ok {
+		r0 = rf(ctx, userID, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// URLRepository_IterateEventsByUserID_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'IterateEventsByUserID'
+type URLRepository_IterateEventsByUserID_Call struct {
+	*mock.Call
+}
+
+// IterateEventsByUserID is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userID string
+//   - fn models.EventIterator
+func (_e *URLRepository_Expecter) IterateEventsByUserID(ctx interface{}, userID interface{}, fn interface{}) *URLRepository_IterateEventsByUserID_Call {
+	return &URLRepository_IterateEventsByUserID_Call{Call: _e.mock.On("IterateEventsByUserID", ctx, userID, fn)}
+}
+
+func (_c *URLRepository_IterateEventsByUserID_Call) Run(run func(ctx context.Context, userID string, fn models.EventIterator)) *URLRepository_IterateEventsByUserID_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(models.EventIterator))
+	})
+	return _c
+}
+
+func (_c *URLRepository_IterateEventsByUserID_Call) Return(_a0 error) *URLRepository_IterateEventsByUserID_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *URLRepository_IterateEventsByUserID_Call) RunAndReturn(run func(context.Context, string, models.EventIterator) error) *URLRepository_IterateEventsByUserID_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// IterateShortKeys provides a mock function with given fields: ctx, fn
+func (_m *URLRepository) IterateShortKeys(ctx context.Context, fn models.ShortKeyIterator) error {
+	ret := _m.Called(ctx, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IterateShortKeys")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.ShortKeyIterator) error); ok {
+		r0 = rf(ctx, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// URLRepository_IterateShortKeys_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'IterateShortKeys'
+type URLRepository_IterateShortKeys_Call struct {
+	*mock.Call
+}
+
+// IterateShortKeys is a helper method to define mock.On call
+//   - ctx context.Context
+//   - fn models.ShortKeyIterator
+func (_e *URLRepository_Expecter) IterateShortKeys(ctx interface{}, fn interface{}) *URLRepository_IterateShortKeys_Call {
+	return &URLRepository_IterateShortKeys_Call{Call: _e.mock.On("IterateShortKeys", ctx, fn)}
+}
+
+func (_c *URLRepository_IterateShortKeys_Call) Run(run func(ctx context.Context, fn models.ShortKeyIterator)) *URLRepository_IterateShortKeys_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(models.ShortKeyIterator))
+	})
+	return _c
+}
+
+func (_c *URLRepository_IterateShortKeys_Call) Return(_a0 error) *URLRepository_IterateShortKeys_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *URLRepository_IterateShortKeys_Call) RunAndReturn(run func(context.Context, models.ShortKeyIterator) error) *URLRepository_IterateShortKeys_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -388,6 +661,53 @@ func (_c *URLRepository_Save_Call) RunAndReturn(run func(context.Context, []*mod
 	return _c
 }
 
+// SaveClicks provides a mock function with given fields: ctx, clicks
+func (_m *URLRepository) SaveClicks(ctx context.Context, clicks []*models.Click) error {
+	ret := _m.Called(ctx, clicks)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveClicks")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []*models.Click) error); ok {
+		r0 = rf(ctx, clicks)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// URLRepository_SaveClicks_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SaveClicks'
+type URLRepository_SaveClicks_Call struct {
+	*mock.Call
+}
+
+// SaveClicks is a helper method to define mock.On call
+//   - ctx context.Context
+//   - clicks []*models.Click
+func (_e *URLRepository_Expecter) SaveClicks(ctx interface{}, clicks interface{}) *URLRepository_SaveClicks_Call {
+	return &URLRepository_SaveClicks_Call{Call: _e.mock.On("SaveClicks", ctx, clicks)}
+}
+
+func (_c *URLRepository_SaveClicks_Call) Run(run func(ctx context.Context, clicks []*models.Click)) *URLRepository_SaveClicks_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].([]*models.Click))
+	})
+	return _c
+}
+
+func (_c *URLRepository_SaveClicks_Call) Return(_a0 error) *URLRepository_SaveClicks_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *URLRepository_SaveClicks_Call) RunAndReturn(run func(context.Context, []*models.Click) error) *URLRepository_SaveClicks_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewURLRepository creates a new instance of URLRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewURLRepository(t interface {
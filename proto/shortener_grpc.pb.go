@@ -19,12 +19,16 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	URL_Create_FullMethodName      = "/shortener.URL/Create"
-	URL_CreateBatch_FullMethodName = "/shortener.URL/CreateBatch"
-	URL_Redirect_FullMethodName    = "/shortener.URL/Redirect"
-	URL_GetUserURLs_FullMethodName = "/shortener.URL/GetUserURLs"
-	URL_DeleteBatch_FullMethodName = "/shortener.URL/DeleteBatch"
-	URL_GetStats_FullMethodName    = "/shortener.URL/GetStats"
+	URL_Create_FullMethodName         = "/shortener.URL/Create"
+	URL_CreateBatch_FullMethodName    = "/shortener.URL/CreateBatch"
+	URL_Redirect_FullMethodName       = "/shortener.URL/Redirect"
+	URL_GetUserURLs_FullMethodName    = "/shortener.URL/GetUserURLs"
+	URL_DeleteBatch_FullMethodName    = "/shortener.URL/DeleteBatch"
+	URL_GetStats_FullMethodName       = "/shortener.URL/GetStats"
+	URL_GetURLStats_FullMethodName    = "/shortener.URL/GetURLStats"
+	URL_StreamUserURLs_FullMethodName = "/shortener.URL/StreamUserURLs"
+	URL_ExportAll_FullMethodName      = "/shortener.URL/ExportAll"
+	URL_ImportURLs_FullMethodName     = "/shortener.URL/ImportURLs"
 )
 
 // URLClient is the client API for URL service.
@@ -37,6 +41,11 @@ type URLClient interface {
 	GetUserURLs(ctx context.Context, in *GetUserURLsRequest, opts ...grpc.CallOption) (*GetUserURLsResponse, error)
 	DeleteBatch(ctx context.Context, in *DeleteBatchRequest, opts ...grpc.CallOption) (*DeleteBatchResponse, error)
 	GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*GetStatsResponse, error)
+	GetURLStats(ctx context.Context, in *GetURLStatsRequest, opts ...grpc.CallOption) (*GetURLStatsResponse, error)
+	StreamUserURLs(ctx context.Context, in *StreamUserURLsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StreamUserURLsResponse], error)
+	ExportAll(ctx context.Context, in *ExportAllRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ExportAllResponse], error)
+	// results are streamed back as soon as rows are saved or rejected, not in the order of rows
+	ImportURLs(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ImportURLsRequest, ImportURLsResponse], error)
 }
 
 type uRLClient struct {
@@ -107,6 +116,67 @@ func (c *uRLClient) GetStats(ctx context.Context, in *GetStatsRequest, opts ...g
 	return out, nil
 }
 
+func (c *uRLClient) GetURLStats(ctx context.Context, in *GetURLStatsRequest, opts ...grpc.CallOption) (*GetURLStatsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetURLStatsResponse)
+	err := c.cc.Invoke(ctx, URL_GetURLStats_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *uRLClient) StreamUserURLs(ctx context.Context, in *StreamUserURLsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StreamUserURLsResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &URL_ServiceDesc.Streams[0], URL_StreamUserURLs_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamUserURLsRequest, StreamUserURLsResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type URL_StreamUserURLsClient = grpc.ServerStreamingClient[StreamUserURLsResponse]
+
+func (c *uRLClient) ExportAll(ctx context.Context, in *ExportAllRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ExportAllResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &URL_ServiceDesc.Streams[1], URL_ExportAll_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ExportAllRequest, ExportAllResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type URL_ExportAllClient = grpc.ServerStreamingClient[ExportAllResponse]
+
+func (c *uRLClient) ImportURLs(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ImportURLsRequest, ImportURLsResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &URL_ServiceDesc.Streams[2], URL_ImportURLs_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ImportURLsRequest, ImportURLsResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type URL_ImportURLsClient = grpc.BidiStreamingClient[ImportURLsRequest, ImportURLsResponse]
+
 // URLServer is the server API for URL service.
 // All implementations must embed UnimplementedURLServer
 // for forward compatibility.
@@ -117,6 +187,11 @@ type URLServer interface {
 	GetUserURLs(context.Context, *GetUserURLsRequest) (*GetUserURLsResponse, error)
 	DeleteBatch(context.Context, *DeleteBatchRequest) (*DeleteBatchResponse, error)
 	GetStats(context.Context, *GetStatsRequest) (*GetStatsResponse, error)
+	GetURLStats(context.Context, *GetURLStatsRequest) (*GetURLStatsResponse, error)
+	StreamUserURLs(*StreamUserURLsRequest, grpc.ServerStreamingServer[StreamUserURLsResponse]) error
+	ExportAll(*ExportAllRequest, grpc.ServerStreamingServer[ExportAllResponse]) error
+	// results are streamed back as soon as rows are saved or rejected, not in the order of rows
+	ImportURLs(grpc.BidiStreamingServer[ImportURLsRequest, ImportURLsResponse]) error
 	mustEmbedUnimplementedURLServer()
 }
 
@@ -145,6 +220,18 @@ func (UnimplementedURLServer) DeleteBatch(context.Context, *DeleteBatchRequest)
 func (UnimplementedURLServer) GetStats(context.Context, *GetStatsRequest) (*GetStatsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetStats not implemented")
 }
+func (UnimplementedURLServer) GetURLStats(context.Context, *GetURLStatsRequest) (*GetURLStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetURLStats not implemented")
+}
+func (UnimplementedURLServer) StreamUserURLs(*StreamUserURLsRequest, grpc.ServerStreamingServer[StreamUserURLsResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamUserURLs not implemented")
+}
+func (UnimplementedURLServer) ExportAll(*ExportAllRequest, grpc.ServerStreamingServer[ExportAllResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method ExportAll not implemented")
+}
+func (UnimplementedURLServer) ImportURLs(grpc.BidiStreamingServer[ImportURLsRequest, ImportURLsResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method ImportURLs not implemented")
+}
 func (UnimplementedURLServer) mustEmbedUnimplementedURLServer() {}
 func (UnimplementedURLServer) testEmbeddedByValue()             {}
 
@@ -274,6 +361,53 @@ func _URL_GetStats_Handler(srv interface{}, ctx context.Context, dec func(interf
 	return interceptor(ctx, in, info, handler)
 }
 
+func _URL_GetURLStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetURLStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(URLServer).GetURLStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: URL_GetURLStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(URLServer).GetURLStats(ctx, req.(*GetURLStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _URL_StreamUserURLs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamUserURLsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(URLServer).StreamUserURLs(m, &grpc.GenericServerStream[StreamUserURLsRequest, StreamUserURLsResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type URL_StreamUserURLsServer = grpc.ServerStreamingServer[StreamUserURLsResponse]
+
+func _URL_ExportAll_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ExportAllRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(URLServer).ExportAll(m, &grpc.GenericServerStream[ExportAllRequest, ExportAllResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type URL_ExportAllServer = grpc.ServerStreamingServer[ExportAllResponse]
+
+func _URL_ImportURLs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(URLServer).ImportURLs(&grpc.GenericServerStream[ImportURLsRequest, ImportURLsResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type URL_ImportURLsServer = grpc.BidiStreamingServer[ImportURLsRequest, ImportURLsResponse]
+
 // URL_ServiceDesc is the grpc.ServiceDesc for URL service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -305,7 +439,28 @@ var URL_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetStats",
 			Handler:    _URL_GetStats_Handler,
 		},
+		{
+			MethodName: "GetURLStats",
+			Handler:    _URL_GetURLStats_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamUserURLs",
+			Handler:       _URL_StreamUserURLs_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ExportAll",
+			Handler:       _URL_ExportAll_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ImportURLs",
+			Handler:       _URL_ImportURLs_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/shortener.proto",
 }